@@ -0,0 +1,512 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package binding
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/btcsuite/btcutil"
+	"github.com/cosmos/cosmos-sdk/codec"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ontio/ontology-go-sdk"
+	common2 "github.com/ontio/ontology/common"
+	utils2 "github.com/ontio/ontology/smartcontract/service/native/utils"
+	"github.com/polynetwork/cosmos-poly-module/btcx"
+	"github.com/polynetwork/cosmos-poly-module/lockproxy"
+	"github.com/polynetwork/poly-go-sdk"
+	btcx_abi "github.com/polynetwork/poly-io-test/chains/eth/abi/btcx"
+	lockproxy_abi "github.com/polynetwork/poly-io-test/chains/eth/abi/lockproxy"
+	"github.com/polynetwork/poly-io-test/config"
+	"github.com/polynetwork/poly-io-test/plan"
+	pcom "github.com/polynetwork/poly/common"
+	"github.com/polynetwork/poly/native/service/governance/side_chain_manager"
+	"github.com/polynetwork/poly/native/service/utils"
+	"github.com/tendermint/tendermint/rpc/client/http"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	KindProxy  = "proxy"
+	KindAsset  = "asset"
+	KindBtcx   = "btcx"
+	KindRedeem = "redeem"
+)
+
+const (
+	StatusOK         = "ok"
+	StatusMissing    = "missing"
+	StatusStale      = "stale"
+	StatusAsymmetric = "asymmetric"
+	StatusError      = "error"
+)
+
+// Binding is one expected binding and what is found on chain
+type Binding struct {
+	Chain     string   `json:"chain"`
+	Contract  string   `json:"contract"`
+	Kind      string   `json:"kind"`
+	Asset     string   `json:"asset,omitempty"`
+	ToChainId uint64   `json:"to_chain_id"`
+	Expected  string   `json:"expected"`
+	Actual    string   `json:"actual"`
+	Status    string   `json:"status"`
+	Err       string   `json:"error,omitempty"`
+	Fix       *plan.Tx `json:"fix,omitempty"`
+}
+
+// asset is how one asset is represented on every chain
+type asset struct {
+	name   string
+	hashes map[uint64][]byte
+}
+
+type Checker struct {
+	EthCli *ethclient.Client
+	OntSdk *ontology_go_sdk.OntologySdk
+	CMCli  *http.HTTP
+	CMCdc  *codec.Codec
+	Poly   *poly_go_sdk.PolySdk
+}
+
+func NewChecker(ethCli *ethclient.Client, ontSdk *ontology_go_sdk.OntologySdk, cmCli *http.HTTP, cmCdc *codec.Codec,
+	poly *poly_go_sdk.PolySdk) *Checker {
+	return &Checker{
+		EthCli: ethCli,
+		OntSdk: ontSdk,
+		CMCli:  cmCli,
+		CMCdc:  cmCdc,
+		Poly:   poly,
+	}
+}
+
+func ontAddr(val string) []byte {
+	addr, err := common2.AddressFromHexString(val)
+	if err != nil {
+		return nil
+	}
+	return addr[:]
+}
+
+func cmProxy() []byte {
+	raw, _ := hex.DecodeString(config.DefConfig.CMLockProxy)
+	return raw
+}
+
+// expectedAssets builds the asset matrix from config
+func expectedAssets() []*asset {
+	cm := config.DefConfig.CMCrossChainId
+	return []*asset{
+		{"eth", map[uint64][]byte{
+			config.ETH_CHAIN_ID: make([]byte, 20),
+			config.ONT_CHAIN_ID: ontAddr(config.DefConfig.OntEth),
+			cm:                  []byte(config.CM_ETHX),
+		}},
+		{"erc20", map[uint64][]byte{
+			config.ETH_CHAIN_ID: common.HexToAddress(config.DefConfig.EthErc20).Bytes(),
+			config.ONT_CHAIN_ID: ontAddr(config.DefConfig.OntErc20),
+			cm:                  []byte(config.CM_ERC20),
+		}},
+		{"ont", map[uint64][]byte{
+			config.ETH_CHAIN_ID: common.HexToAddress(config.DefConfig.EthOntx).Bytes(),
+			config.ONT_CHAIN_ID: utils2.OntContractAddress[:],
+			cm:                  []byte(config.CM_ONT),
+		}},
+		{"ong", map[uint64][]byte{
+			config.ETH_CHAIN_ID: common.HexToAddress(config.DefConfig.EthOngx).Bytes(),
+			config.ONT_CHAIN_ID: utils2.OngContractAddress[:],
+			cm:                  []byte(config.CM_ONG),
+		}},
+		{"oep4", map[uint64][]byte{
+			config.ETH_CHAIN_ID: common.HexToAddress(config.DefConfig.EthOep4).Bytes(),
+			config.ONT_CHAIN_ID: ontAddr(config.DefConfig.OntOep4),
+			cm:                  []byte(config.CM_OEP4),
+		}},
+	}
+}
+
+func expectedBtcx() map[uint64][]byte {
+	redeem, _ := hex.DecodeString(config.DefConfig.BtcRedeem)
+	return map[uint64][]byte{
+		config.BTC_CHAIN_ID:              btcutil.Hash160(redeem),
+		config.ETH_CHAIN_ID:              common.HexToAddress(config.DefConfig.BtceContractAddress).Bytes(),
+		config.ONT_CHAIN_ID:              ontAddr(config.DefConfig.BtcoContractAddress),
+		config.DefConfig.CMCrossChainId: []byte(config.CM_BTCX),
+	}
+}
+
+func expectedProxies() map[uint64][]byte {
+	return map[uint64][]byte{
+		config.ETH_CHAIN_ID:              common.HexToAddress(config.DefConfig.EthLockProxy).Bytes(),
+		config.ONT_CHAIN_ID:              ontAddr(config.DefConfig.OntLockProxy),
+		config.DefConfig.CMCrossChainId: cmProxy(),
+	}
+}
+
+func chainName(id uint64) string {
+	switch id {
+	case config.BTC_CHAIN_ID:
+		return "bitcoin"
+	case config.ETH_CHAIN_ID:
+		return "ethereum"
+	case config.ONT_CHAIN_ID:
+		return "ontology"
+	case config.DefConfig.CMCrossChainId:
+		return "cosmos"
+	}
+	return fmt.Sprintf("chain-%d", id)
+}
+
+func (c *Checker) newBinding(chainId uint64, contract, kind, asset string, toChainId uint64, expected, actual []byte,
+	err error) *Binding {
+	b := &Binding{
+		Chain:     chainName(chainId),
+		Contract:  contract,
+		Kind:      kind,
+		Asset:     asset,
+		ToChainId: toChainId,
+		Expected:  hex.EncodeToString(expected),
+		Actual:    hex.EncodeToString(actual),
+	}
+	switch {
+	case err != nil:
+		b.Status, b.Err = StatusError, err.Error()
+	case len(actual) == 0:
+		b.Status = StatusMissing
+	case !bytes.Equal(expected, actual):
+		b.Status = StatusStale
+	default:
+		b.Status = StatusOK
+	}
+	return b
+}
+
+// CheckAll reads every binding from every chain and compares them with config
+func (c *Checker) CheckAll() []*Binding {
+	res := make([]*Binding, 0)
+	res = append(res, c.checkEth()...)
+	res = append(res, c.checkOnt()...)
+	res = append(res, c.checkCosmos()...)
+	res = append(res, c.checkRedeem()...)
+	markAsymmetric(res)
+	// keep the order of checking and sort by target chain in each group
+	group := make(map[string]int)
+	for _, b := range res {
+		k := b.Chain + "/" + b.Kind + "/" + b.Asset
+		if _, ok := group[k]; !ok {
+			group[k] = len(group)
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		gi, gj := group[res[i].Chain+"/"+res[i].Kind+"/"+res[i].Asset], group[res[j].Chain+"/"+res[j].Kind+"/"+res[j].Asset]
+		if gi != gj {
+			return gi < gj
+		}
+		return res[i].ToChainId < res[j].ToChainId
+	})
+	return res
+}
+
+func (c *Checker) checkEth() []*Binding {
+	res := make([]*Binding, 0)
+	proxyAddr := common.HexToAddress(config.DefConfig.EthLockProxy)
+	proxy, perr := lockproxy_abi.NewLockProxy(proxyAddr, c.EthCli)
+	for toChainId, expected := range expectedProxies() {
+		if toChainId == config.ETH_CHAIN_ID {
+			continue
+		}
+		actual, err := []byte(nil), perr
+		if err == nil {
+			actual, err = proxy.ProxyHashMap(nil, toChainId)
+		}
+		b := c.newBinding(config.ETH_CHAIN_ID, proxyAddr.Hex(), KindProxy, "", toChainId, expected, actual, err)
+		b.Fix = &plan.Tx{Chain: b.Chain, Type: plan.TyInvoke, Contract: b.Contract, Method: "bindProxyHash",
+			Args: []plan.Arg{plan.NewArg("toChainId", toChainId), plan.NewArg("targetProxyHash", expected)}}
+		res = append(res, b)
+	}
+	for _, a := range expectedAssets() {
+		from := common.BytesToAddress(a.hashes[config.ETH_CHAIN_ID])
+		for toChainId, expected := range a.hashes {
+			if toChainId == config.ETH_CHAIN_ID {
+				continue
+			}
+			actual, err := []byte(nil), perr
+			if err == nil {
+				actual, err = proxy.AssetHashMap(nil, from, toChainId)
+			}
+			b := c.newBinding(config.ETH_CHAIN_ID, proxyAddr.Hex(), KindAsset, a.name, toChainId, expected, actual, err)
+			b.Fix = &plan.Tx{Chain: b.Chain, Type: plan.TyInvoke, Contract: b.Contract, Method: "bindAssetHash",
+				Args: []plan.Arg{plan.NewArg("fromAssetHash", from.Hex()), plan.NewArg("toChainId", toChainId),
+					plan.NewArg("toAssetHash", expected)}}
+			res = append(res, b)
+		}
+	}
+
+	btcxAddr := common.HexToAddress(config.DefConfig.BtceContractAddress)
+	btce, berr := btcx_abi.NewBTCX(btcxAddr, c.EthCli)
+	for toChainId, expected := range expectedBtcx() {
+		if toChainId == config.ETH_CHAIN_ID {
+			continue
+		}
+		actual, err := []byte(nil), berr
+		if err == nil {
+			actual, err = btce.BondAssetHashes(nil, toChainId)
+		}
+		b := c.newBinding(config.ETH_CHAIN_ID, btcxAddr.Hex(), KindBtcx, "btc", toChainId, expected, actual, err)
+		b.Fix = &plan.Tx{Chain: b.Chain, Type: plan.TyInvoke, Contract: b.Contract, Method: "bindAssetHash",
+			Args: []plan.Arg{plan.NewArg("chainId", toChainId), plan.NewArg("contractAddr", expected)}}
+		res = append(res, b)
+	}
+	return res
+}
+
+func (c *Checker) ontPreExec(contract string, method string, args ...interface{}) ([]byte, error) {
+	addr, err := common2.AddressFromHexString(contract)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %v", contract, err)
+	}
+	res, err := c.OntSdk.NeoVM.PreExecInvokeNeoVMContract(addr, []interface{}{method, args})
+	if err != nil {
+		return nil, fmt.Errorf("failed to pre-execute %s: %v", method, err)
+	}
+	return res.Result.ToByteArray()
+}
+
+func (c *Checker) checkOnt() []*Binding {
+	res := make([]*Binding, 0)
+	proxy := config.DefConfig.OntLockProxy
+	for toChainId, expected := range expectedProxies() {
+		if toChainId == config.ONT_CHAIN_ID {
+			continue
+		}
+		actual, err := c.ontPreExec(proxy, "getProxyHash", toChainId)
+		b := c.newBinding(config.ONT_CHAIN_ID, proxy, KindProxy, "", toChainId, expected, actual, err)
+		b.Fix = &plan.Tx{Chain: b.Chain, Type: plan.TyInvoke, Contract: proxy, Method: "bindProxyHash",
+			Args: []plan.Arg{plan.NewArg("toChainId", toChainId), plan.NewArg("targetProxyHash", expected)}}
+		res = append(res, b)
+	}
+	for _, a := range expectedAssets() {
+		from := a.hashes[config.ONT_CHAIN_ID]
+		for toChainId, expected := range a.hashes {
+			if toChainId == config.ONT_CHAIN_ID {
+				continue
+			}
+			actual, err := c.ontPreExec(proxy, "getAssetHash", from, toChainId)
+			b := c.newBinding(config.ONT_CHAIN_ID, proxy, KindAsset, a.name, toChainId, expected, actual, err)
+			b.Fix = &plan.Tx{Chain: b.Chain, Type: plan.TyInvoke, Contract: proxy, Method: "bindAssetHash",
+				Args: []plan.Arg{plan.NewArg("fromAssetHash", from), plan.NewArg("toChainId", toChainId),
+					plan.NewArg("toAssetHash", expected)}}
+			res = append(res, b)
+		}
+	}
+
+	btco := config.DefConfig.BtcoContractAddress
+	for toChainId, expected := range expectedBtcx() {
+		if toChainId == config.ONT_CHAIN_ID || toChainId == config.BTC_CHAIN_ID {
+			// the redeem key is set by init, not a binding
+			continue
+		}
+		actual, err := c.ontPreExec(btco, "getContractAddrWithChainId", toChainId)
+		b := c.newBinding(config.ONT_CHAIN_ID, btco, KindBtcx, "btc", toChainId, expected, actual, err)
+		b.Fix = &plan.Tx{Chain: b.Chain, Type: plan.TyInvoke, Contract: btco, Method: "bindContractAddrWithChainId",
+			Args: []plan.Arg{plan.NewArg("chainId", toChainId), plan.NewArg("contractAddr", expected)}}
+		res = append(res, b)
+	}
+	return res
+}
+
+func (c *Checker) cosmosQuery(path string, param interface{}, val interface{}) error {
+	raw, err := c.CMCdc.MarshalJSON(param)
+	if err != nil {
+		return err
+	}
+	res, err := c.CMCli.ABCIQuery(path, raw)
+	if err != nil {
+		return err
+	}
+	if !res.Response.IsOK() {
+		return fmt.Errorf("query %s failed: %s", path, res.Response.Log)
+	}
+	if len(res.Response.Value) == 0 {
+		return nil
+	}
+	return c.CMCdc.UnmarshalJSON(res.Response.Value, val)
+}
+
+type denomCrossChainInfoParam struct {
+	Denom   string
+	ChainId uint64
+}
+
+func (c *Checker) checkCosmos() []*Binding {
+	res := make([]*Binding, 0)
+	proxy := cmProxy()
+	contract := config.DefConfig.CMLockProxy
+	cm := config.DefConfig.CMCrossChainId
+	for toChainId, expected := range expectedProxies() {
+		if toChainId == cm {
+			continue
+		}
+		var actual []byte
+		err := c.cosmosQuery(fmt.Sprintf("custom/%s/%s", lockproxy.QuerierRoute, lockproxy.QueryProxyHash),
+			lockproxy.NewQueryProxyHashParam(proxy, toChainId), &actual)
+		b := c.newBinding(cm, contract, KindProxy, "", toChainId, expected, actual, err)
+		b.Fix = &plan.Tx{Chain: b.Chain, Type: plan.TyInvoke, Contract: lockproxy.ModuleName, Method: "MsgBindProxyHash",
+			Args: []plan.Arg{plan.NewArg("toChainId", toChainId), plan.NewArg("toChainProxyHash", expected)}}
+		res = append(res, b)
+	}
+	for _, a := range expectedAssets() {
+		denom := string(a.hashes[cm])
+		for toChainId, expected := range a.hashes {
+			if toChainId == cm {
+				continue
+			}
+			var actual []byte
+			err := c.cosmosQuery(fmt.Sprintf("custom/%s/%s", lockproxy.QuerierRoute, lockproxy.QueryAssetHash),
+				lockproxy.NewQueryAssetHashParam(proxy, denom, toChainId), &actual)
+			b := c.newBinding(cm, contract, KindAsset, a.name, toChainId, expected, actual, err)
+			b.Fix = &plan.Tx{Chain: b.Chain, Type: plan.TyInvoke, Contract: lockproxy.ModuleName, Method: "MsgBindAssetHash",
+				Args: []plan.Arg{plan.NewArg("sourceAssetDenom", denom), plan.NewArg("toChainId", toChainId),
+					plan.NewArg("toAssetHash", expected)}}
+			res = append(res, b)
+		}
+	}
+
+	for toChainId, expected := range expectedBtcx() {
+		if toChainId == cm {
+			continue
+		}
+		info := &btcx.DenomCrossChainInfo{}
+		var actual []byte
+		err := c.cosmosQuery(fmt.Sprintf("custom/%s/denom_cc_info", btcx.QuerierRoute),
+			denomCrossChainInfoParam{config.CM_BTCX, toChainId}, info)
+		if err == nil && info.ToAssetHash != "" {
+			actual, err = hex.DecodeString(info.ToAssetHash)
+		}
+		b := c.newBinding(cm, config.CM_BTCX, KindBtcx, "btc", toChainId, expected, actual, err)
+		b.Fix = &plan.Tx{Chain: b.Chain, Type: plan.TyInvoke, Contract: btcx.ModuleName, Method: "MsgBindAssetHash",
+			Args: []plan.Arg{plan.NewArg("sourceAssetDenom", config.CM_BTCX), plan.NewArg("toChainId", toChainId),
+				plan.NewArg("toAssetHash", expected)}}
+		res = append(res, b)
+	}
+	return res
+}
+
+// checkRedeem checks the btcx contracts bound with the redeem on poly
+func (c *Checker) checkRedeem() []*Binding {
+	res := make([]*Binding, 0)
+	btcxes := expectedBtcx()
+	hashKey := btcxes[config.BTC_CHAIN_ID]
+	for toChainId, expected := range btcxes {
+		if toChainId == config.BTC_CHAIN_ID {
+			continue
+		}
+		var actual []byte
+		val, err := c.Poly.GetStorage(utils.SideChainManagerContractAddress.ToHexString(),
+			append(append(append([]byte(side_chain_manager.REDEEM_BIND), utils.GetUint64Bytes(config.BTC_CHAIN_ID)...),
+				utils.GetUint64Bytes(toChainId)...), hashKey...))
+		if err == nil && len(val) > 0 {
+			cb := &side_chain_manager.ContractBinded{}
+			if err = cb.Deserialization(pcom.NewZeroCopySource(val)); err == nil {
+				actual = cb.Contract
+			}
+		}
+		b := c.newBinding(toChainId, "poly", KindRedeem, "btc", toChainId, expected, actual, err)
+		b.Chain = "poly"
+		b.Contract = utils.SideChainManagerContractAddress.ToHexString()
+		b.Fix = &plan.Tx{Chain: "poly", Type: plan.TyPolyGov, Contract: b.Contract, Method: side_chain_manager.REGISTER_REDEEM,
+			Args: []plan.Arg{plan.NewArg("redeemChainId", config.BTC_CHAIN_ID), plan.NewArg("contractChainId", toChainId),
+				plan.NewArg("redeem", config.DefConfig.BtcRedeem), plan.NewArg("contractAddress", expected)},
+			Note: "signed by the vendor keys, see BtcInvoker.BindBtcxWithVendor"}
+		res = append(res, b)
+	}
+	return res
+}
+
+// markAsymmetric marks the broken bindings whose reverse direction is fine
+func markAsymmetric(res []*Binding) {
+	idx := make(map[string]*Binding)
+	key := func(chain string, kind, asset string, to string) string {
+		return strings.Join([]string{chain, kind, asset, to}, "/")
+	}
+	for _, b := range res {
+		idx[key(b.Chain, b.Kind, b.Asset, chainName(b.ToChainId))] = b
+	}
+	for _, b := range res {
+		if b.Status != StatusMissing && b.Status != StatusStale {
+			continue
+		}
+		rev, ok := idx[key(chainName(b.ToChainId), b.Kind, b.Asset, b.Chain)]
+		if ok && rev.Status == StatusOK {
+			b.Status = StatusAsymmetric
+		}
+	}
+}
+
+// Broken returns the bindings need to be fixed
+func Broken(res []*Binding) []*Binding {
+	broken := make([]*Binding, 0)
+	for _, b := range res {
+		if b.Status != StatusOK {
+			broken = append(broken, b)
+		}
+	}
+	return broken
+}
+
+func Print(w io.Writer, res []*Binding, format string) error {
+	switch format {
+	case plan.FormatJson:
+		raw, err := json.MarshalIndent(res, "", "\t")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(raw))
+		return err
+	case plan.FormatText, "":
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "CHAIN\tKIND\tASSET\tTO\tSTATUS\tEXPECTED\tACTUAL")
+		for _, b := range res {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", b.Chain, b.Kind, b.Asset, chainName(b.ToChainId), b.Status,
+				b.Expected, b.Actual)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		broken := Broken(res)
+		if len(broken) == 0 {
+			_, err := fmt.Fprintln(w, "all bindings are fine")
+			return err
+		}
+		fix := plan.NewPlan("fix bindings")
+		for _, b := range broken {
+			if b.Status == StatusError {
+				continue
+			}
+			fix.Add(b.Fix)
+		}
+		_, err := fmt.Fprintln(w, fix.Text())
+		return err
+	default:
+		return fmt.Errorf("unknown format %s", format)
+	}
+}
@@ -0,0 +1,131 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package btc
+
+import (
+	"encoding/hex"
+	"fmt"
+	types2 "github.com/cosmos/cosmos-sdk/types"
+	ecom "github.com/ethereum/go-ethereum/common"
+	"github.com/ontio/ontology/common"
+	"github.com/polynetwork/poly-io-test/config"
+	"strings"
+)
+
+// AddressCodec decodes an address on the destination chain into the bytes carried by btc
+// cross chain txs
+type AddressCodec func(addr string) ([]byte, error)
+
+const (
+	CodecEvm    = "evm"
+	CodecOnt    = "ont"
+	CodecBech32 = "bech32" // "bech32:<prefix>", or the account prefix of cosmos-sdk config
+	CodecHex    = "hex"
+)
+
+var AddressCodecs = map[string]AddressCodec{
+	CodecEvm: DecodeEvmAddress,
+	CodecOnt: DecodeOntAddress,
+	CodecHex: DecodeHexAddress,
+}
+
+// ChainAddressCodecs are the codecs of chains btc can cross to, BtcAddressCodecs in config
+// goes first. Cosmos uses bech32.
+var ChainAddressCodecs = map[uint64]string{
+	config.ETH_CHAIN_ID: CodecEvm,
+	config.ONT_CHAIN_ID: CodecOnt,
+}
+
+// DecodeEvmAddress decodes a hex address of 20 bytes, validating the EIP55 checksum if in mixed case
+func DecodeEvmAddress(addr string) ([]byte, error) {
+	raw := strings.TrimPrefix(addr, "0x")
+	if len(raw) != 2*ecom.AddressLength {
+		return nil, fmt.Errorf("evm address %s is not %d bytes", addr, ecom.AddressLength)
+	}
+	b, err := hex.DecodeString(raw)
+	if err != nil {
+		return nil, fmt.Errorf("evm address %s is not hex: %v", addr, err)
+	}
+	if raw != strings.ToLower(raw) && raw != strings.ToUpper(raw) && ecom.BytesToAddress(b).Hex()[2:] != raw {
+		return nil, fmt.Errorf("evm address %s has wrong checksum", addr)
+	}
+	return b, nil
+}
+
+// DecodeOntAddress decodes a base58 ontology address
+func DecodeOntAddress(addr string) ([]byte, error) {
+	a, err := common.AddressFromBase58(addr)
+	if err != nil {
+		return nil, fmt.Errorf("wrong ontology address %s: %v", addr, err)
+	}
+	return a[:], nil
+}
+
+// Bech32Codec decodes bech32 addresses with prefix, or the account prefix of cosmos-sdk config
+// if prefix is empty
+func Bech32Codec(prefix string) AddressCodec {
+	return func(addr string) ([]byte, error) {
+		p := prefix
+		if p == "" {
+			p = types2.GetConfig().GetBech32AccountAddrPrefix()
+		}
+		b, err := types2.GetFromBech32(addr, p)
+		if err != nil {
+			return nil, fmt.Errorf("wrong bech32 address %s: %v", addr, err)
+		}
+		return b, nil
+	}
+}
+
+// DecodeHexAddress decodes raw hex bytes, with or without 0x
+func DecodeHexAddress(addr string) ([]byte, error) {
+	b, err := hex.DecodeString(strings.TrimPrefix(addr, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("address %s is not hex: %v", addr, err)
+	}
+	if len(b) == 0 {
+		return nil, fmt.Errorf("empty address")
+	}
+	return b, nil
+}
+
+// GetAddressCodec returns the codec named name
+func GetAddressCodec(name string) (AddressCodec, error) {
+	if name == CodecBech32 || strings.HasPrefix(name, CodecBech32+":") {
+		return Bech32Codec(strings.TrimPrefix(strings.TrimPrefix(name, CodecBech32), ":")), nil
+	}
+	c, ok := AddressCodecs[name]
+	if !ok {
+		return nil, fmt.Errorf("address codec %s not supported", name)
+	}
+	return c, nil
+}
+
+// AddressCodecOf returns the codec of addresses on chain toChainId
+func AddressCodecOf(toChainId uint64) (AddressCodec, error) {
+	name, ok := config.DefConfig.BtcAddressCodecs[toChainId]
+	if !ok {
+		name, ok = ChainAddressCodecs[toChainId]
+	}
+	if !ok && toChainId != 0 && toChainId == config.DefConfig.CMCrossChainId {
+		name, ok = CodecBech32, true
+	}
+	if !ok {
+		return nil, fmt.Errorf("no address codec for chain-id %d", toChainId)
+	}
+	return GetAddressCodec(name)
+}
@@ -17,6 +17,7 @@
 package btc
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
@@ -28,7 +29,6 @@ import (
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcutil"
-	"math/rand"
 )
 
 type BuildCrossChainTxParam struct {
@@ -41,6 +41,11 @@ type BuildCrossChainTxParam struct {
 	Privk        *btcec.PrivateKey
 	NetParam     *chaincfg.Params
 	Data         []byte
+
+	PrevPkScripts [][]byte // pk script of each input, PrevPkScript is used for all if empty
+	PrevAmounts   []int64  // satoshi of each input, needed to sign witness inputs
+	ToP2wsh       bool     // lock to the P2WSH of redeem instead of P2SH
+	Replaceable   bool     // signal BIP125 replacement
 }
 
 type Builder struct {
@@ -52,20 +57,33 @@ type Builder struct {
 	IsSigned     bool
 	RedeemScript []byte
 	Privks       map[string]*btcec.PrivateKey
+
+	PrevPkScripts [][]byte
+	PrevAmounts   []int64
 }
 
 func NewBuilder(param *BuildCrossChainTxParam) (b *Builder, err error) {
 	b = &Builder{}
 	mtx, err := getUnsignedCrossChainTx(param.Inputs, param.Changes, param.Redeem, param.ToMultiValue,
-		param.Locktime, param.NetParam, param.Data)
+		param.Locktime, param.NetParam, param.Data, param.ToP2wsh)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to get raw tx: %v", err)
 	}
 
+	if param.Replaceable {
+		for _, in := range mtx.TxIn {
+			in.Sequence = ReplaceableSequence
+		}
+	}
 	b.Tx = mtx
 	b.PrivKey = param.Privk
 	b.PrevPkScript = param.PrevPkScript
 	b.NetParam = param.NetParam
+	b.PrevPkScripts = param.PrevPkScripts
+	b.PrevAmounts = param.PrevAmounts
+	if len(b.PrevPkScripts) != 0 && len(b.PrevPkScripts) != len(mtx.TxIn) {
+		return nil, fmt.Errorf("%d prev pk scripts for %d inputs", len(b.PrevPkScripts), len(mtx.TxIn))
+	}
 
 	return b, nil
 }
@@ -77,18 +95,93 @@ func (builder *Builder) LookUpKey(addr btcutil.Address) (*btcec.PrivateKey, bool
 	return builder.PrivKey, true, nil
 }
 
-// locking
+func (builder *Builder) prevPkScript(i int) []byte {
+	if len(builder.PrevPkScripts) == 0 {
+		return builder.PrevPkScript
+	}
+	return builder.PrevPkScripts[i]
+}
+
+func (builder *Builder) prevAmount(i int) (int64, error) {
+	if i >= len(builder.PrevAmounts) || builder.PrevAmounts[i] <= 0 {
+		return 0, fmt.Errorf("no amount for witness input No.%d", i)
+	}
+	return builder.PrevAmounts[i], nil
+}
+
+// nestedRedeem returns the P2WPKH script of the private key wrapped by the P2SH script
+func (builder *Builder) nestedRedeem(pkScript []byte) ([]byte, error) {
+	addr, err := btcutil.NewAddressWitnessPubKeyHash(
+		btcutil.Hash160(builder.PrivKey.PubKey().SerializeCompressed()), builder.NetParam)
+	if err != nil {
+		return nil, err
+	}
+	redeem, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return nil, err
+	}
+	p2sh, err := btcutil.NewAddressScriptHash(redeem, builder.NetParam)
+	if err != nil {
+		return nil, err
+	}
+	expect, err := txscript.PayToAddrScript(p2sh)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(expect, pkScript) {
+		return nil, errors.New("P2SH input is not the P2SH-P2WPKH of private key")
+	}
+	return redeem, nil
+}
+
+// locking, inputs could be P2PKH, P2WPKH or P2SH-P2WPKH
 func (builder *Builder) BuildSignedTx() error {
-	for i, _ := range builder.Tx.TxIn {
-		sig, err := txscript.SignTxOutput(builder.NetParam, builder.Tx, i, builder.PrevPkScript,
-			txscript.SigHashAll, txscript.KeyClosure(builder.LookUpKey), nil, nil)
-		if err != nil {
-			return fmt.Errorf("Failed to sign tx's No.%d input: %v", i, err)
-		}
-		if err != nil {
-			return fmt.Errorf("Failed to get witness: %v", err)
+	if builder.PrivKey == nil {
+		return errors.New("Private key not ready")
+	}
+	hashes := txscript.NewTxSigHashes(builder.Tx)
+	for i := range builder.Tx.TxIn {
+		pkScript := builder.prevPkScript(i)
+		switch txscript.GetScriptClass(pkScript) {
+		case txscript.WitnessV0PubKeyHashTy:
+			amt, err := builder.prevAmount(i)
+			if err != nil {
+				return err
+			}
+			wit, err := txscript.WitnessSignature(builder.Tx, hashes, i, amt, pkScript, txscript.SigHashAll,
+				builder.PrivKey, true)
+			if err != nil {
+				return fmt.Errorf("Failed to get witness of No.%d input: %v", i, err)
+			}
+			builder.Tx.TxIn[i].Witness = wit
+		case txscript.ScriptHashTy:
+			redeem, err := builder.nestedRedeem(pkScript)
+			if err != nil {
+				return fmt.Errorf("Failed to sign tx's No.%d input: %v", i, err)
+			}
+			amt, err := builder.prevAmount(i)
+			if err != nil {
+				return err
+			}
+			wit, err := txscript.WitnessSignature(builder.Tx, hashes, i, amt, redeem, txscript.SigHashAll,
+				builder.PrivKey, true)
+			if err != nil {
+				return fmt.Errorf("Failed to get witness of No.%d input: %v", i, err)
+			}
+			sig, err := txscript.NewScriptBuilder().AddData(redeem).Script()
+			if err != nil {
+				return fmt.Errorf("Failed to build sig script of No.%d input: %v", i, err)
+			}
+			builder.Tx.TxIn[i].SignatureScript = sig
+			builder.Tx.TxIn[i].Witness = wit
+		default:
+			sig, err := txscript.SignTxOutput(builder.NetParam, builder.Tx, i, pkScript,
+				txscript.SigHashAll, txscript.KeyClosure(builder.LookUpKey), nil, nil)
+			if err != nil {
+				return fmt.Errorf("Failed to sign tx's No.%d input: %v", i, err)
+			}
+			builder.Tx.TxIn[i].SignatureScript = sig
 		}
-		builder.Tx.TxIn[i].SignatureScript = sig
 	}
 	builder.IsSigned = true
 	return nil
@@ -96,7 +189,7 @@ func (builder *Builder) BuildSignedTx() error {
 
 // need to make a multisig-output tx
 func getUnsignedCrossChainTx(txIns []btcjson.TransactionInput, changes map[string]float64, redeem string,
-	value float64, locktime *int64, netParam *chaincfg.Params, data []byte) (*wire.MsgTx, error) {
+	value float64, locktime *int64, netParam *chaincfg.Params, data []byte, toP2wsh bool) (*wire.MsgTx, error) {
 	if locktime != nil && (*locktime < 0 || *locktime > int64(wire.MaxTxInSequenceNum)) {
 		return nil, fmt.Errorf("getRawTxToMultiAddr, locktime %d out of range", *locktime)
 	}
@@ -128,20 +221,15 @@ func getUnsignedCrossChainTx(txIns []btcjson.TransactionInput, changes map[strin
 
 	rb, _ := hex.DecodeString(redeem)
 	var addr btcutil.Address
-	r := rand.Intn(2)
-	switch r {
-	case 0:
-		addr, err = btcutil.NewAddressScriptHash(rb, netParam)
-		if err != nil {
-			return nil, err
-		}
-	case 1:
+	if toP2wsh {
 		hasher := sha256.New()
 		hasher.Write(rb)
 		addr, err = btcutil.NewAddressWitnessScriptHash(hasher.Sum(nil), netParam)
-		if err != nil {
-			return nil, err
-		}
+	} else {
+		addr, err = btcutil.NewAddressScriptHash(rb, netParam)
+	}
+	if err != nil {
+		return nil, err
 	}
 
 	script, err := txscript.PayToAddrScript(addr)
@@ -178,6 +266,7 @@ func getUnsignedCrossChainTx(txIns []btcjson.TransactionInput, changes map[strin
 		switch addr.(type) {
 		case *btcutil.AddressPubKeyHash:
 		case *btcutil.AddressScriptHash:
+		case *btcutil.AddressWitnessPubKeyHash:
 		default:
 			return nil, fmt.Errorf("getRawTxToMultiAddr, type of addr is not found")
 		}
@@ -38,8 +38,10 @@ type BtcInvoker struct {
 	RChain      *poly_go_sdk.PolySdk
 	RChainAcc   *poly_go_sdk.Account
 	FromChainId int32
-	BtcCli      *RestCli
+	BtcCli      BtcClient
 	Signer      *BtcSigner
+
+	Utxos *UtxoManager
 }
 
 func NewBtcInvoker(rpc, wallet, pwd, btcRpc, btcUser, btcPwd, privk string) (*BtcInvoker, error) {
@@ -59,6 +61,17 @@ func NewBtcInvoker(rpc, wallet, pwd, btcRpc, btcUser, btcPwd, privk string) (*Bt
 	if err != nil {
 		return nil, err
 	}
+	selector, err := GetSelector(config.DefConfig.BtcUtxoSelector)
+	if err != nil {
+		return nil, err
+	}
+	addr, err := invoker.Signer.AddressOf(config.DefConfig.BtcSignerInputType, config.BtcNet)
+	if err != nil {
+		return nil, err
+	}
+	if invoker.Utxos, err = NewUtxoManager(invoker.BtcCli, addr, selector); err != nil {
+		return nil, err
+	}
 	return invoker, nil
 }
 
@@ -93,41 +106,60 @@ func (invoker *BtcInvoker) GenerateVendor(n, require int) (*Vendor, error) {
 	}, nil
 }
 
-func (invoker *BtcInvoker) BindBtcxWithVendor(btcx string, btcxChainId uint64, vendor *Vendor) (common.Uint256, error) {
-	ver := uint64(0)
-	val, _ := invoker.RChain.GetStorage(utils.SideChainManagerContractAddress.ToHexString(),
+// ContractBinded returns the btcx on chain btcxChainId bound with the redeem of hashKey on poly,
+// nil if not bound
+func (invoker *BtcInvoker) ContractBinded(hashKey []byte, btcxChainId uint64) (*side_chain_manager.ContractBinded,
+	error) {
+	val, err := invoker.RChain.GetStorage(utils.SideChainManagerContractAddress.ToHexString(),
 		append(append(append([]byte(side_chain_manager.REDEEM_BIND),
 			utils.GetUint64Bytes(1)...),
-			utils.GetUint64Bytes(btcxChainId)...), vendor.HashKey...))
-	if val != nil && len(val) != 0 {
-		c := &side_chain_manager.ContractBinded{}
-		err := c.Deserialization(common.NewZeroCopySource(val))
-		if err != nil {
-			return common.UINT256_EMPTY, err
-		}
-		ver = c.Ver + 1
+			utils.GetUint64Bytes(btcxChainId)...), hashKey...))
+	if err != nil {
+		return nil, err
+	}
+	if len(val) == 0 {
+		return nil, nil
+	}
+	c := &side_chain_manager.ContractBinded{}
+	if err = c.Deserialization(common.NewZeroCopySource(val)); err != nil {
+		return nil, err
 	}
+	return c, nil
+}
 
+// BtcxBytes returns the bytes of btcx address on chain btcxChainId registered on poly
+func BtcxBytes(btcx string, btcxChainId uint64) ([]byte, error) {
 	btcx = strings.Replace(btcx, "0x", "", 1)
-	var btcxBytes []byte
 	switch btcxChainId {
 	case config.ONT_CHAIN_ID: //ONT ChainId: 3
 		addr, err := common2.AddressFromHexString(btcx)
 		if err != nil {
-			return common.UINT256_EMPTY, err
+			return nil, err
 		}
-		btcxBytes = addr[:]
+		return addr[:], nil
 	case 2: //ETH ChainID: 2
-		addr, err := hex.DecodeString(btcx)
-		if err != nil {
-			return common.UINT256_EMPTY, err
-		}
-		btcxBytes = addr[:]
+		return hex.DecodeString(btcx)
 	case config.DefConfig.CMCrossChainId:
-		btcxBytes = []byte(btcx)
+		return []byte(btcx), nil
 	//todo：Add Cosmos + Others, need to refactor to registration mode
 	default:
-		return common.UINT256_EMPTY, fmt.Errorf("chain-id %d not supported", btcxChainId)
+		return nil, fmt.Errorf("chain-id %d not supported", btcxChainId)
+	}
+}
+
+func (invoker *BtcInvoker) BindBtcxWithVendor(btcx string, btcxChainId uint64, vendor *Vendor) (common.Uint256, error) {
+	ver := uint64(0)
+	c, err := invoker.ContractBinded(vendor.HashKey, btcxChainId)
+	if err != nil {
+		return common.UINT256_EMPTY, err
+	}
+	if c != nil {
+		ver = c.Ver + 1
+	}
+
+	btcxBytes, err := BtcxBytes(btcx, btcxChainId)
+	if err != nil {
+		return common.UINT256_EMPTY, err
 	}
 
 	hash := btcutil.Hash160(
@@ -189,7 +221,11 @@ func (invoker *BtcInvoker) GetAccInfo() (string, error) {
 	if err != nil {
 		return "", err
 	}
-	utxos, err := invoker.BtcCli.ListUnspent(1, n, invoker.Signer.Address)
+	addr, err := invoker.Signer.AddressOf(config.DefConfig.BtcSignerInputType, config.BtcNet)
+	if err != nil {
+		return "", err
+	}
+	utxos, err := invoker.BtcCli.ListUnspent(1, n, addr.EncodeAddress())
 	if err != nil {
 		return "", err
 	}
@@ -197,7 +233,7 @@ func (invoker *BtcInvoker) GetAccInfo() (string, error) {
 	for _, u := range utxos {
 		sum += u.Amount
 	}
-	return fmt.Sprintf("BITCOIN: acc: %s, asset: [ btc: %d ]", invoker.Signer.Address, sum), nil
+	return fmt.Sprintf("BITCOIN: acc: %s, asset: [ btc: %d ]", addr.EncodeAddress(), sum), nil
 }
 
 type Vendor struct {
@@ -239,6 +275,29 @@ func NewVendorFromConfig() (*Vendor, error) {
 	return v, nil
 }
 
+// NewVendorFromMembers returns the vendor of redeem with private keys of members, such as the
+// ones from LoadVendorMembers
+func NewVendorFromMembers(redeem []byte, members []*VendorMember) (*Vendor, error) {
+	v := &Vendor{
+		PrivateKeys: make([]*btcutil.WIF, len(members)),
+		AddressSet:  make([]*btcutil.AddressPubKey, len(members)),
+		Redeem:      redeem,
+		HashKey:     btcutil.Hash160(redeem),
+	}
+	for i, m := range members {
+		wif, err := btcutil.NewWIF(m.Privk, config.BtcNet, true)
+		if err != nil {
+			return nil, err
+		}
+		v.PrivateKeys[i], v.AddressSet[i] = wif, m.Addr
+	}
+	v.P2shAddr, _ = btcutil.NewAddressScriptHash(redeem, config.BtcNet)
+	hasher := sha256.New()
+	hasher.Write(redeem)
+	v.P2wshAddr, _ = btcutil.NewAddressWitnessScriptHash(hasher.Sum(nil), config.BtcNet)
+	return v, nil
+}
+
 func (v *Vendor) EncryptPrivateKeys(file, pwd string) ([]string, error) {
 	pathSet := make([]string, len(v.PrivateKeys))
 	for i, priv := range v.PrivateKeys {
@@ -0,0 +1,461 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package btc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/polynetwork/poly-io-test/config"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+var testNet = &chaincfg.RegressionNetParams
+
+func testSigner(t *testing.T, seed string) *BtcSigner {
+	privk, _ := btcec.PrivKeyFromBytes(btcec.S256(), chainhash.HashB([]byte(seed)))
+	wif, err := btcutil.NewWIF(privk, testNet, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := NewBtcSigner(wif.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+func encodeTx(t *testing.T, tx *wire.MsgTx) string {
+	var buf bytes.Buffer
+	if err := tx.BtcEncode(&buf, wire.ProtocolVersion, wire.LatestEncoding); err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(buf.Bytes())
+}
+
+// buildCross builds the signed cross chain tx spending utxos of signer like sendBtcCross
+func buildCross(t *testing.T, signer *BtcSigner, addr btcutil.Address, utxos []*Utxo, amount, fee int64,
+	toP2wsh, replaceable bool) *wire.MsgTx {
+	vendor, _ := TestVendor(t, testNet)
+	param := &BuildCrossChainTxParam{
+		Redeem:       hex.EncodeToString(vendor.Redeem),
+		Data:         []byte{0xcc, 0x01},
+		NetParam:     testNet,
+		Privk:        signer.WIF.PrivKey,
+		ToMultiValue: float64(amount) / btcutil.SatoshiPerBitcoin,
+		Changes:      map[string]float64{},
+		ToP2wsh:      toP2wsh,
+		Replaceable:  replaceable,
+	}
+	sum := int64(0)
+	for _, u := range utxos {
+		pkScript, _ := hex.DecodeString(u.ScriptPubKey)
+		param.Inputs = append(param.Inputs, btcjson.TransactionInput{Txid: u.Txid, Vout: u.Vout})
+		param.PrevPkScripts = append(param.PrevPkScripts, pkScript)
+		param.PrevAmounts = append(param.PrevAmounts, u.Amount)
+		sum += u.Amount
+	}
+	param.Changes[addr.EncodeAddress()] = float64(sum-amount-fee) / btcutil.SatoshiPerBitcoin
+	b, err := NewBuilder(param)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = b.BuildSignedTx(); err != nil || !b.IsSigned {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	return b.Tx
+}
+
+func fundedSigner(t *testing.T, ty string, amounts ...int64) (*FakeCli, *BtcSigner, btcutil.Address) {
+	cli := NewFakeCli(testNet)
+	signer := testSigner(t, "signer")
+	addr, err := signer.AddressOf(ty, testNet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = cli.Fund(addr.EncodeAddress(), amounts...); err != nil {
+		t.Fatal(err)
+	}
+	return cli, signer, addr
+}
+
+func TestBuildSignedTx(t *testing.T) {
+	for _, ty := range []string{InputP2pkh, InputP2wpkh, InputP2shP2wpkh} {
+		for _, toP2wsh := range []bool{false, true} {
+			cli, signer, addr := fundedSigner(t, ty, 300000, 400000)
+			utxos, err := cli.ListUnspent(1, 1000, addr.EncodeAddress())
+			if err != nil || len(utxos) != 2 {
+				t.Fatalf("%s: wrong utxos %v: %v", ty, utxos, err)
+			}
+			tx := buildCross(t, signer, addr, utxos, 500000, 10000, toP2wsh, false)
+			if _, err = cli.SendRawTx(encodeTx(t, tx)); err != nil {
+				t.Fatalf("%s, p2wsh %v: tx rejected: %v", ty, toP2wsh, err)
+			}
+		}
+	}
+}
+
+func TestBuildSignedTxWrongKey(t *testing.T) {
+	cli, _, addr := fundedSigner(t, InputP2wpkh, 300000)
+	utxos, _ := cli.ListUnspent(1, 1000, addr.EncodeAddress())
+	tx := buildCross(t, testSigner(t, "other"), addr, utxos, 200000, 10000, false, false)
+	if _, err := cli.SendRawTx(encodeTx(t, tx)); err == nil ||
+		!strings.Contains(err.Error(), "script-verify") {
+		t.Fatalf("tx signed by wrong key should be rejected: %v", err)
+	}
+}
+
+func TestFakeCliMinRelayFee(t *testing.T) {
+	cli, signer, addr := fundedSigner(t, InputP2pkh, 300000)
+	utxos, _ := cli.ListUnspent(1, 1000, addr.EncodeAddress())
+	tx := buildCross(t, signer, addr, utxos, 200000, 10, false, false)
+	if _, err := cli.SendRawTx(encodeTx(t, tx)); err == nil ||
+		!strings.Contains(err.Error(), "min relay fee not met") {
+		t.Fatalf("tx under min relay fee should be rejected: %v", err)
+	}
+}
+
+func TestFakeCliBlocks(t *testing.T) {
+	cli, signer, addr := fundedSigner(t, InputP2wpkh, 300000)
+	utxos, _ := cli.ListUnspent(1, 1000, addr.EncodeAddress())
+	tx := buildCross(t, signer, addr, utxos, 200000, 10000, false, false)
+	txid, err := cli.SendRawTx(encodeTx(t, tx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = cli.GetProof([]string{txid}); err == nil {
+		t.Fatal("proof of tx in mempool should fail")
+	}
+	if n, _ := cli.GetMempoolInfo(); n != 1 {
+		t.Fatalf("%d txs in mempool, expected 1", n)
+	}
+	hashes, err := cli.GenerateToAddr(1, addr.EncodeAddress())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := cli.GetMempoolInfo(); n != 0 {
+		t.Fatalf("%d txs in mempool after mined", n)
+	}
+	height, _, _ := cli.GetCurrentHeightAndHash()
+	if h, err := cli.GetBlockHeight(hashes[0]); err != nil || h != height || h != 2 {
+		t.Fatalf("wrong height %d of mined block, tip %d: %v", h, height, err)
+	}
+	txs, _, err := cli.GetTxsInBlock(hashes[0])
+	if err != nil || len(txs) != 2 || txs[1].TxHash().String() != txid {
+		t.Fatalf("wrong txs in block: %v", err)
+	}
+	if _, err = cli.GetProof([]string{txid}); err != nil {
+		t.Fatalf("failed to get proof: %v", err)
+	}
+	// change is listed with one confirmation, the coinbase is not mature
+	utxos, _ = cli.ListUnspent(1, 1000, addr.EncodeAddress())
+	if len(utxos) != 1 || utxos[0].Txid != txid || utxos[0].Amount != 90000 {
+		t.Fatalf("wrong utxos after mined: %v", utxos)
+	}
+}
+
+func testUtxos(amounts ...int64) []*Utxo {
+	utxos := make([]*Utxo, len(amounts))
+	for i, amt := range amounts {
+		utxos[i] = &Utxo{Txid: strings.Repeat("0", 63) + string('a'+byte(i)), Amount: amt}
+	}
+	return utxos
+}
+
+func TestSelectors(t *testing.T) {
+	utxos := testUtxos(1000, 5000, 3000, 20000, 7000)
+	selected, sum, err := SelectLargestFirst(utxos, 24000)
+	if err != nil || len(selected) != 2 || sum != 27000 {
+		t.Fatalf("largest first selected %v, sum %d: %v", selected, sum, err)
+	}
+	selected, sum, err = SelectBranchAndBound(utxos, 9000)
+	if err != nil || sum != 9000 {
+		t.Fatalf("bnb should find the exact match, selected %v, sum %d: %v", selected, sum, err)
+	}
+	if _, _, err = SelectRandom(utxos, 36000); err != nil {
+		t.Fatalf("random should spend all: %v", err)
+	}
+	for name, s := range Selectors {
+		if _, _, err = s(utxos, 36001); err == nil {
+			t.Fatalf("selector %s should fail for not enough utxos", name)
+		}
+	}
+}
+
+func TestUtxoManager(t *testing.T) {
+	cli, signer, addr := fundedSigner(t, InputP2wpkh, 300000, 200000)
+	mgr, err := NewUtxoManager(cli, addr, SelectLargestFirst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _, err := mgr.Reserve(250000)
+	if err != nil || len(first) != 1 || first[0].Amount != 300000 {
+		t.Fatalf("wrong reserved %v: %v", first, err)
+	}
+	second, _, err := mgr.Reserve(150000)
+	if err != nil || len(second) != 1 || second[0].Amount != 200000 {
+		t.Fatalf("reserved utxos should not be selected again, got %v: %v", second, err)
+	}
+	if _, _, err = mgr.Reserve(1000); err == nil {
+		t.Fatal("all utxos are reserved")
+	}
+	mgr.Release(second)
+
+	tx := buildCross(t, signer, addr, first, 200000, 10000, false, false)
+	if _, err = cli.SendRawTx(encodeTx(t, tx)); err != nil {
+		t.Fatal(err)
+	}
+	mgr.Spent(tx)
+	// the unconfirmed change can be spent
+	selected, sum, err := mgr.Reserve(250000)
+	if err != nil || len(selected) != 2 || sum != 290000 {
+		t.Fatalf("wrong reserved %v, sum %d: %v", selected, sum, err)
+	}
+	mgr.Release(selected)
+
+	if _, err = cli.GenerateToAddr(1, addr.EncodeAddress()); err != nil {
+		t.Fatal(err)
+	}
+	selected, sum, err = mgr.Reserve(250000)
+	if err != nil || len(selected) != 2 || sum != 290000 {
+		t.Fatalf("wrong reserved after mined %v, sum %d: %v", selected, sum, err)
+	}
+	mgr.Release(selected)
+
+	// change spent before confirmed is dropped once the spending tx is mined with it
+	tx = buildCross(t, signer, addr, []*Utxo{second[0]}, 100000, 10000, false, false)
+	if _, err = cli.SendRawTx(encodeTx(t, tx)); err != nil {
+		t.Fatal(err)
+	}
+	mgr.Spent(tx)
+	var change []*Utxo
+	for _, u := range mgr.unconfirmed {
+		change = append(change, u)
+	}
+	if len(change) != 1 || change[0].Txid != tx.TxHash().String() {
+		t.Fatalf("wrong unconfirmed change %v", change)
+	}
+	spending := buildCross(t, signer, addr, change, 50000, 10000, false, false)
+	if _, err = cli.SendRawTx(encodeTx(t, spending)); err != nil {
+		t.Fatal(err)
+	}
+	mgr.Spent(spending)
+	if _, err = cli.GenerateToAddr(1, addr.EncodeAddress()); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = mgr.Reserve(1000); err != nil {
+		t.Fatal(err)
+	}
+	op := outpoint(change[0].Txid, change[0].Vout)
+	if _, ok := mgr.unconfirmed[op]; ok {
+		t.Fatal("spent change should be dropped from unconfirmed")
+	}
+	if _, ok := mgr.reserved[op]; ok {
+		t.Fatal("spent change should be dropped from reserved")
+	}
+}
+
+func TestBumpFee(t *testing.T) {
+	cli, signer, addr := fundedSigner(t, InputP2shP2wpkh, 300000)
+	utxos, _ := cli.ListUnspent(1, 1000, addr.EncodeAddress())
+
+	final := buildCross(t, signer, addr, utxos, 200000, 1000, false, false)
+	if _, err := cli.SendRawTx(encodeTx(t, final)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := BumpFee(cli, signer.WIF.PrivKey, testNet, final.TxHash().String(), 10); err == nil {
+		t.Fatal("tx not signaling BIP125 should not be bumped")
+	}
+
+	cli, signer, addr = fundedSigner(t, InputP2shP2wpkh, 300000)
+	utxos, _ = cli.ListUnspent(1, 1000, addr.EncodeAddress())
+	tx := buildCross(t, signer, addr, utxos, 200000, 1000, false, true)
+	txid, err := cli.SendRawTx(encodeTx(t, tx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bumped, err := BumpFee(cli, signer.WIF.PrivKey, testNet, txid, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fee := 300000 - bumped.TxOut[0].Value - bumped.TxOut[2].Value; fee < VSize(bumped)*20 {
+		t.Fatalf("fee %d of replacement under 20 sat/vbyte", fee)
+	}
+	nid, err := cli.SendRawTx(encodeTx(t, bumped))
+	if err != nil {
+		t.Fatalf("replacement rejected: %v", err)
+	}
+	mempool := cli.Mempool()
+	if len(mempool) != 1 || mempool[0].TxHash().String() != nid {
+		t.Fatal("replaced tx should be evicted")
+	}
+	if _, err = cli.GetTx(txid); err == nil {
+		t.Fatal("replaced tx should be gone")
+	}
+	if _, err = cli.GenerateToAddr(1, addr.EncodeAddress()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = BumpFee(cli, signer.WIF.PrivKey, testNet, nid, 30); err != ErrConfirmed {
+		t.Fatalf("confirmed tx should not be bumped, but got: %v", err)
+	}
+}
+
+func TestVendorSigning(t *testing.T) {
+	vendor, _ := TestVendor(t, testNet)
+	redeem := vendor.Redeem
+	dir, err := ioutil.TempDir("", "vendor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := path.Join(dir, "btcprivk")
+	if _, err = vendor.EncryptPrivateKeys(file, "pwd"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = LoadVendorMembers(file, "wrong", redeem, testNet); err == nil {
+		t.Fatal("should fail with wrong password")
+	}
+	members, err := LoadVendorMembers(file, "pwd", redeem, testNet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range members {
+		if !bytes.Equal(m.Privk.Serialize(), vendor.PrivateKeys[i].PrivKey.Serialize()) {
+			t.Fatalf("No.%d member key not decrypted", i+1)
+		}
+	}
+
+	// unlock tx made by poly spending a P2SH and a P2WSH utxo, with pk scripts in signature scripts
+	cli := NewFakeCli(testNet)
+	shScript, wshScript, _ := MultisigPkScripts(redeem, testNet)
+	tx := wire.NewMsgTx(wire.TxVersion)
+	amts := []uint64{50000, 60000}
+	for i, pks := range [][]byte{shScript, wshScript} {
+		_, addrs, _, _ := txscript.ExtractPkScriptAddrs(pks, testNet)
+		fund, err := cli.Fund(addrs[0].EncodeAddress(), int64(amts[i]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		hash := fund.TxHash()
+		tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&hash, 0), pks, nil))
+	}
+	tx.AddTxOut(wire.NewTxOut(100000, shScript))
+
+	sigs := make([][][]byte, 2)
+	for i, m := range members[:2] {
+		if sigs[i], err = SignUnlock(tx, amts, redeem, m.Privk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// add signatures like poly does when enough
+	signed := tx.Copy()
+	builder := txscript.NewScriptBuilder().AddOp(txscript.OP_FALSE).AddData(sigs[0][0]).AddData(sigs[1][0])
+	signed.TxIn[0].SignatureScript, _ = builder.AddData(redeem).Script()
+	signed.TxIn[1].SignatureScript = nil
+	signed.TxIn[1].Witness = wire.TxWitness{nil, sigs[0][1], sigs[1][1], redeem}
+	if _, err = cli.SendRawTx(encodeTx(t, signed)); err != nil {
+		t.Fatalf("signatures of vendor not accepted: %v", err)
+	}
+}
+
+func TestSweepMultisig(t *testing.T) {
+	vendor, members := TestVendor(t, testNet)
+	redeem := vendor.Redeem
+	cli := NewFakeCli(testNet)
+	utxos := make([]*Utxo, 0)
+	shScript, wshScript, _ := MultisigPkScripts(redeem, testNet)
+	for _, pks := range [][]byte{shScript, wshScript} {
+		_, addrs, _, _ := txscript.ExtractPkScriptAddrs(pks, testNet)
+		if _, err := cli.Fund(addrs[0].EncodeAddress(), 40000, 50000); err != nil {
+			t.Fatal(err)
+		}
+		res, err := cli.ListUnspent(1, 100, addrs[0].EncodeAddress())
+		if err != nil {
+			t.Fatal(err)
+		}
+		utxos = append(utxos, res...)
+	}
+	to, _ := btcutil.NewAddressWitnessScriptHash(chainhash.HashB([]byte("new redeem")), testNet)
+
+	// members out of order and more than required
+	data := []byte{0xcc, 2}
+	tx, err := SweepMultisig(utxos, redeem, []*VendorMember{members[2], members[0], members[1]}, to, data, 2,
+		testNet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tx.TxOut) != 2 || 180000-tx.TxOut[0].Value < VSize(tx)*2-4 {
+		t.Fatalf("wrong output or fee: %d", 180000-tx.TxOut[0].Value)
+	}
+	if pushes, err := txscript.PushedData(tx.TxOut[1].PkScript); err != nil || len(pushes) != 1 ||
+		!bytes.Equal(pushes[0], data) {
+		t.Fatalf("wrong null data output: %x", tx.TxOut[1].PkScript)
+	}
+	if _, err = cli.SendRawTx(encodeTx(t, tx)); err != nil {
+		t.Fatalf("sweep not accepted: %v", err)
+	}
+	if _, err = SweepMultisig(utxos, redeem, members[:1], to, nil, 2, testNet); err == nil {
+		t.Fatal("should fail with too few members")
+	}
+}
+
+func TestAddressCodecs(t *testing.T) {
+	KeepConfig(t)
+	for _, c := range []struct {
+		chainId uint64
+		addr    string
+		ok      bool
+	}{
+		{config.ETH_CHAIN_ID, "0x344cFc3B8635f72F14200aAf2168d9f75df86FD3", true},
+		{config.ETH_CHAIN_ID, "344cfc3b8635f72f14200aaf2168d9f75df86fd3", true},
+		{config.ETH_CHAIN_ID, "0x344CFc3B8635f72F14200aAf2168d9f75df86FD3", false},
+		{config.ETH_CHAIN_ID, "0x344cfc3b8635f72f14200aaf2168d9f75df86f", false},
+		{config.ONT_CHAIN_ID, "AdzZ2VKufdJWeB8t9a8biXoHbbMe2kZeyH", true},
+		{config.ONT_CHAIN_ID, "AdzZ2VKufdJWeB8t9a8biXoHbbMe2kZeyh", false},
+		{100, "cosmos1qypqxpq9qcrsszg2pvxq6rs0zqg3yyc5lzv7xu", false},
+		{101, "0x0102", true},
+		{101, "0xzz", false},
+		{102, "AdzZ2VKufdJWeB8t9a8biXoHbbMe2kZeyH", false},
+	} {
+		config.DefConfig.BtcAddressCodecs = map[uint64]string{100: "bech32:swth", 101: CodecHex}
+		data, err := BuildData(c.chainId, 0, c.addr)
+		if c.ok != (err == nil) {
+			t.Fatalf("%s on chain %d: ok %v but err %v", c.addr, c.chainId, c.ok, err)
+		}
+		if c.ok && data[0] != 0xcc {
+			t.Fatalf("wrong flag of data %x", data)
+		}
+	}
+
+	b, err := Bech32Codec("cosmos")("cosmos1qypqxpq9qcrsszg2pvxq6rs0zqg3yyc5lzv7xu")
+	if err != nil || len(b) != 20 {
+		t.Fatalf("failed to decode bech32: %v", err)
+	}
+	if _, err = GetAddressCodec("base64"); err == nil {
+		t.Fatal("unknown codec should fail")
+	}
+}
@@ -0,0 +1,48 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package btc
+
+import (
+	"github.com/btcsuite/btcd/wire"
+)
+
+// BtcClient is the bitcoin node used by invoker, monitors and tools. RestCli talks to
+// btcd or bitcoind by JSON-RPC, and FakeCli keeps a chain in memory for unit tests.
+type BtcClient interface {
+	GetTxsInBlock(hash string) ([]*wire.MsgTx, string, error)
+	GetCurrentHeightAndHash() (int32, string, error)
+	GetBlockHeight(hash string) (int32, error)
+	GetBlockCount() (int64, error)
+	GetHeader(h int32) (*wire.BlockHeader, error)
+	GetProof(txids []string) (string, error)
+	GetMempoolInfo() (int32, error)
+	GetRawTransaction(txid string) (string, error)
+	GetTx(txid string) (*wire.MsgTx, error)
+	GetTxConfs(txid string) (int64, error)
+	GetTxOutVal(txid string, idx uint32) (int64, error)
+	ListUnspent(minConfs, maxConfs int64, addr string) ([]*Utxo, error)
+	ImportAddress(addr string) error
+	EstimateSmartFee(confTarget int) (uint64, error)
+	BroadcastTx(tx string) (string, error)
+	SendRawTx(rawTx string) (string, error)
+	GenerateToAddr(n int, addr string) ([]string, error)
+}
+
+var (
+	_ BtcClient = (*RestCli)(nil)
+	_ BtcClient = (*FakeCli)(nil)
+)
@@ -0,0 +1,530 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package btc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/bloom"
+	"sort"
+	"sync"
+	"time"
+)
+
+type fakeCoin struct {
+	out      *wire.TxOut
+	height   int32 // -1 if in mempool
+	coinbase bool
+}
+
+// FakeCli is a bitcoin node in memory for unit tests. It keeps a chain, a mempool and the utxo
+// set, verifies scripts and fees of txs sent like a regtest node with BIP125 replacement, and
+// mines blocks only when asked. Everything is deterministic for the same calls.
+type FakeCli struct {
+	lock     *sync.Mutex
+	NetParam *chaincfg.Params
+	// returned by EstimateSmartFee, which fails like a fresh regtest node if 0
+	FeeRate uint64
+
+	blocks  []*wire.MsgBlock
+	heights map[chainhash.Hash]int32
+	txs     map[chainhash.Hash]*wire.MsgTx
+	txBlock map[chainhash.Hash]int32 // height of confirmed txs
+	mempool []*wire.MsgTx
+	coins   map[wire.OutPoint]*fakeCoin
+	spentBy map[wire.OutPoint]chainhash.Hash // spent by mempool txs
+	funded  uint32
+}
+
+func NewFakeCli(netParam *chaincfg.Params) *FakeCli {
+	genesis := *netParam.GenesisBlock
+	cli := &FakeCli{
+		lock:     &sync.Mutex{},
+		NetParam: netParam,
+		blocks:   []*wire.MsgBlock{&genesis},
+		heights:  map[chainhash.Hash]int32{genesis.BlockHash(): 0},
+		txs:      make(map[chainhash.Hash]*wire.MsgTx),
+		txBlock:  make(map[chainhash.Hash]int32),
+		coins:    make(map[wire.OutPoint]*fakeCoin),
+		spentBy:  make(map[wire.OutPoint]chainhash.Hash),
+	}
+	return cli
+}
+
+func (cli *FakeCli) tip() int32 {
+	return int32(len(cli.blocks) - 1)
+}
+
+func (cli *FakeCli) addrScript(addr string) ([]byte, error) {
+	a, err := btcutil.DecodeAddress(addr, cli.NetParam)
+	if err != nil {
+		return nil, err
+	}
+	return txscript.PayToAddrScript(a)
+}
+
+// mine appends a block with a coinbase paying pkScript and txs, which must be valid already
+func (cli *FakeCli) mine(pkScript []byte, txs []*wire.MsgTx) *wire.MsgBlock {
+	height := cli.tip() + 1
+	prev := cli.blocks[height-1]
+	fees := int64(0)
+	for _, tx := range txs {
+		// fund txs spend nothing known and would make the coinbase negative
+		if fee := cli.fee(tx); fee > 0 {
+			fees += fee
+		}
+	}
+
+	coinbase := wire.NewMsgTx(wire.TxVersion)
+	sig, _ := txscript.NewScriptBuilder().AddInt64(int64(height)).AddInt64(0).Script()
+	coinbase.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{}, wire.MaxPrevOutIndex), sig, nil))
+	coinbase.AddTxOut(wire.NewTxOut(blockchain.CalcBlockSubsidy(height, cli.NetParam)+fees, pkScript))
+
+	block := wire.NewMsgBlock(wire.NewBlockHeader(0x20000000, &chainhash.Hash{}, &chainhash.Hash{},
+		cli.NetParam.PowLimitBits, 0))
+	block.Header.PrevBlock = prev.BlockHash()
+	block.Header.Timestamp = prev.Header.Timestamp.Add(10 * time.Minute)
+	utxs := []*btcutil.Tx{btcutil.NewTx(coinbase)}
+	_ = block.AddTransaction(coinbase)
+	for _, tx := range txs {
+		_ = block.AddTransaction(tx)
+		utxs = append(utxs, btcutil.NewTx(tx))
+	}
+	store := blockchain.BuildMerkleTreeStore(utxs, false)
+	block.Header.MerkleRoot = *store[len(store)-1]
+
+	cli.blocks = append(cli.blocks, block)
+	cli.heights[block.BlockHash()] = height
+	for i, tx := range block.Transactions {
+		txid := tx.TxHash()
+		cli.txs[txid] = tx
+		cli.txBlock[txid] = height
+		for _, in := range tx.TxIn {
+			delete(cli.coins, in.PreviousOutPoint)
+			delete(cli.spentBy, in.PreviousOutPoint)
+		}
+		for j, out := range tx.TxOut {
+			cli.coins[*wire.NewOutPoint(&txid, uint32(j))] = &fakeCoin{out: out, height: height, coinbase: i == 0}
+		}
+	}
+	return block
+}
+
+// fee returns the fee of tx whose inputs are all known
+func (cli *FakeCli) fee(tx *wire.MsgTx) int64 {
+	fee := int64(0)
+	for _, in := range tx.TxIn {
+		if prev, ok := cli.txs[in.PreviousOutPoint.Hash]; ok {
+			fee += prev.TxOut[in.PreviousOutPoint.Index].Value
+		} else if c, ok := cli.coins[in.PreviousOutPoint]; ok {
+			fee += c.out.Value
+		}
+	}
+	for _, out := range tx.TxOut {
+		fee -= out.Value
+	}
+	return fee
+}
+
+// evict removes tx and its descendants from mempool
+func (cli *FakeCli) evict(txid chainhash.Hash) {
+	tx, ok := cli.txs[txid]
+	if !ok {
+		return
+	}
+	for i := range tx.TxOut {
+		op := *wire.NewOutPoint(&txid, uint32(i))
+		if child, ok := cli.spentBy[op]; ok {
+			cli.evict(child)
+		}
+		delete(cli.coins, op)
+	}
+	for _, in := range tx.TxIn {
+		delete(cli.spentBy, in.PreviousOutPoint)
+	}
+	delete(cli.txs, txid)
+	for i, m := range cli.mempool {
+		if m.TxHash() == txid {
+			cli.mempool = append(cli.mempool[:i], cli.mempool[i+1:]...)
+			break
+		}
+	}
+}
+
+// Fund confirms a new tx paying amounts to addr in a new block, and returns it
+func (cli *FakeCli) Fund(addr string, amounts ...int64) (*wire.MsgTx, error) {
+	cli.lock.Lock()
+	defer cli.lock.Unlock()
+
+	pkScript, err := cli.addrScript(addr)
+	if err != nil {
+		return nil, err
+	}
+	cli.funded++
+	seed := make([]byte, 4)
+	binary.LittleEndian.PutUint32(seed, cli.funded)
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{}, 0), seed, nil))
+	tx.TxIn[0].PreviousOutPoint.Hash = chainhash.DoubleHashH(append([]byte("fund"), seed...))
+	for _, amt := range amounts {
+		tx.AddTxOut(wire.NewTxOut(amt, pkScript))
+	}
+	cli.mine(pkScript, []*wire.MsgTx{tx})
+	return tx, nil
+}
+
+// Mempool returns txs in mempool in the order accepted
+func (cli *FakeCli) Mempool() []*wire.MsgTx {
+	cli.lock.Lock()
+	defer cli.lock.Unlock()
+	return append([]*wire.MsgTx{}, cli.mempool...)
+}
+
+func (cli *FakeCli) GetTxsInBlock(hash string) ([]*wire.MsgTx, string, error) {
+	cli.lock.Lock()
+	defer cli.lock.Unlock()
+
+	h, err := chainhash.NewHashFromStr(hash)
+	if err != nil {
+		return nil, "", err
+	}
+	height, ok := cli.heights[*h]
+	if !ok {
+		return nil, "", errors.New("response shows failure: Block not found")
+	}
+	block := cli.blocks[height]
+	return block.Transactions, block.Header.PrevBlock.String(), nil
+}
+
+func (cli *FakeCli) GetCurrentHeightAndHash() (int32, string, error) {
+	cli.lock.Lock()
+	defer cli.lock.Unlock()
+	return cli.tip(), cli.blocks[cli.tip()].BlockHash().String(), nil
+}
+
+func (cli *FakeCli) GetBlockHeight(hash string) (int32, error) {
+	cli.lock.Lock()
+	defer cli.lock.Unlock()
+
+	h, err := chainhash.NewHashFromStr(hash)
+	if err != nil {
+		return -1, err
+	}
+	height, ok := cli.heights[*h]
+	if !ok {
+		return -1, errors.New("[GetBlockHeight] response shows failure: Block not found")
+	}
+	return height, nil
+}
+
+func (cli *FakeCli) GetBlockCount() (int64, error) {
+	cli.lock.Lock()
+	defer cli.lock.Unlock()
+	return int64(cli.tip()), nil
+}
+
+func (cli *FakeCli) GetHeader(h int32) (*wire.BlockHeader, error) {
+	cli.lock.Lock()
+	defer cli.lock.Unlock()
+
+	if h < 0 || h > cli.tip() {
+		return nil, errors.New("response shows failure: Block height out of range")
+	}
+	hdr := cli.blocks[h].Header
+	return &hdr, nil
+}
+
+// GetProof returns the merkle block proving txids like gettxoutproof
+func (cli *FakeCli) GetProof(txids []string) (string, error) {
+	cli.lock.Lock()
+	defer cli.lock.Unlock()
+
+	if len(txids) == 0 {
+		return "", errors.New("response shows failure: Parameter 'txids' cannot be empty")
+	}
+	filter := bloom.NewFilter(uint32(len(txids)), 0, 0.000001, wire.BloomUpdateNone)
+	var block *wire.MsgBlock
+	for _, txid := range txids {
+		h, err := chainhash.NewHashFromStr(txid)
+		if err != nil {
+			return "", err
+		}
+		filter.AddHash(h)
+		if block != nil {
+			continue
+		}
+		for _, b := range cli.blocks {
+			for _, tx := range b.Transactions {
+				if tx.TxHash() == *h {
+					block = b
+				}
+			}
+		}
+	}
+	if block == nil {
+		return "", errors.New("Transaction not yet in block")
+	}
+	mb, _ := bloom.NewMerkleBlock(btcutil.NewBlock(block), filter)
+	var buf bytes.Buffer
+	if err := mb.BtcEncode(&buf, wire.ProtocolVersion, wire.LatestEncoding); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf.Bytes()), nil
+}
+
+func (cli *FakeCli) GetMempoolInfo() (int32, error) {
+	cli.lock.Lock()
+	defer cli.lock.Unlock()
+	return int32(len(cli.mempool)), nil
+}
+
+func (cli *FakeCli) GetRawTransaction(txid string) (string, error) {
+	tx, err := cli.GetTx(txid)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err = tx.BtcEncode(&buf, wire.ProtocolVersion, wire.LatestEncoding); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf.Bytes()), nil
+}
+
+func (cli *FakeCli) GetTx(txid string) (*wire.MsgTx, error) {
+	cli.lock.Lock()
+	defer cli.lock.Unlock()
+
+	h, err := chainhash.NewHashFromStr(txid)
+	if err != nil {
+		return nil, err
+	}
+	tx, ok := cli.txs[*h]
+	if !ok {
+		return nil, errors.New("[GetRawTransaction] response shows failure: No such mempool or " +
+			"blockchain transaction")
+	}
+	return tx.Copy(), nil
+}
+
+func (cli *FakeCli) GetTxConfs(txid string) (int64, error) {
+	cli.lock.Lock()
+	defer cli.lock.Unlock()
+
+	h, err := chainhash.NewHashFromStr(txid)
+	if err != nil {
+		return -1, err
+	}
+	if _, ok := cli.txs[*h]; !ok {
+		return -1, errors.New("[GetTxConfs] response shows failure: No such mempool or blockchain transaction")
+	}
+	height, ok := cli.txBlock[*h]
+	if !ok {
+		return 0, nil
+	}
+	return int64(cli.tip()-height) + 1, nil
+}
+
+func (cli *FakeCli) GetTxOutVal(txid string, idx uint32) (int64, error) {
+	tx, err := cli.GetTx(txid)
+	if err != nil {
+		return -1, err
+	}
+	if int(idx) >= len(tx.TxOut) {
+		return -1, fmt.Errorf("[GetTxOutVal] no output %d in %s", idx, txid)
+	}
+	return tx.TxOut[idx].Value, nil
+}
+
+// ListUnspent lists mature outputs to addr not spent by chain or mempool, ordered by height
+// and outpoint
+func (cli *FakeCli) ListUnspent(minConfs, maxConfs int64, addr string) ([]*Utxo, error) {
+	cli.lock.Lock()
+	defer cli.lock.Unlock()
+
+	pkScript, err := cli.addrScript(addr)
+	if err != nil {
+		return nil, fmt.Errorf("[ListUnspent] response shows failure: %v", err)
+	}
+	utxos := make([]*Utxo, 0)
+	for op, c := range cli.coins {
+		if !bytes.Equal(c.out.PkScript, pkScript) {
+			continue
+		}
+		if _, ok := cli.spentBy[op]; ok {
+			continue
+		}
+		confs := int64(0)
+		if c.height >= 0 {
+			confs = int64(cli.tip()-c.height) + 1
+		}
+		if c.coinbase && confs < int64(cli.NetParam.CoinbaseMaturity) {
+			continue
+		}
+		if confs < minConfs || confs > maxConfs {
+			continue
+		}
+		utxos = append(utxos, &Utxo{
+			Txid:         op.Hash.String(),
+			Vout:         op.Index,
+			ScriptPubKey: hex.EncodeToString(c.out.PkScript),
+			Amount:       c.out.Value,
+			Confs:        confs,
+		})
+	}
+	sort.Slice(utxos, func(i, j int) bool {
+		if utxos[i].Confs != utxos[j].Confs {
+			return utxos[i].Confs > utxos[j].Confs
+		}
+		if utxos[i].Txid != utxos[j].Txid {
+			return utxos[i].Txid < utxos[j].Txid
+		}
+		return utxos[i].Vout < utxos[j].Vout
+	})
+	return utxos, nil
+}
+
+func (cli *FakeCli) ImportAddress(addr string) error {
+	return nil
+}
+
+func (cli *FakeCli) EstimateSmartFee(confTarget int) (uint64, error) {
+	if cli.FeeRate == 0 {
+		return 0, errors.New("[EstimateSmartFee] no fee rate: [Insufficient data or no feerate found]")
+	}
+	return cli.FeeRate, nil
+}
+
+func (cli *FakeCli) BroadcastTx(tx string) (string, error) {
+	return cli.SendRawTx(tx)
+}
+
+// SendRawTx accepts tx into mempool if its inputs are unspent or replaceable, its scripts pass
+// and it pays the min relay fee
+func (cli *FakeCli) SendRawTx(rawTx string) (string, error) {
+	cli.lock.Lock()
+	defer cli.lock.Unlock()
+
+	raw, err := hex.DecodeString(rawTx)
+	if err != nil {
+		return "", fmt.Errorf("[SendRawTx] response shows failure: TX decode failed")
+	}
+	tx := wire.NewMsgTx(wire.TxVersion)
+	if err = tx.BtcDecode(bytes.NewBuffer(raw), wire.ProtocolVersion, wire.LatestEncoding); err != nil {
+		return "", fmt.Errorf("[SendRawTx] response shows failure: TX decode failed")
+	}
+	txid := tx.TxHash()
+	if _, ok := cli.txs[txid]; ok {
+		return "", fmt.Errorf("[SendRawTx] response shows failure: txn-already-known")
+	}
+
+	conflicts := make(map[chainhash.Hash]bool)
+	sigHashes := txscript.NewTxSigHashes(tx)
+	in := int64(0)
+	for i, ti := range tx.TxIn {
+		c, ok := cli.coins[ti.PreviousOutPoint]
+		if !ok {
+			return "", fmt.Errorf("[SendRawTx] response shows failure: bad-txns-inputs-missingorspent")
+		}
+		if c.coinbase && int64(cli.tip()-c.height)+1 < int64(cli.NetParam.CoinbaseMaturity) {
+			return "", fmt.Errorf("[SendRawTx] response shows failure: bad-txns-premature-spend-of-coinbase")
+		}
+		if other, ok := cli.spentBy[ti.PreviousOutPoint]; ok {
+			conflicts[other] = true
+		}
+		vm, err := txscript.NewEngine(c.out.PkScript, tx, i, txscript.StandardVerifyFlags, nil, sigHashes,
+			c.out.Value)
+		if err == nil {
+			err = vm.Execute()
+		}
+		if err != nil {
+			return "", fmt.Errorf("[SendRawTx] response shows failure: mandatory-script-verify-flag-failed "+
+				"(input %d: %v)", i, err)
+		}
+		in += c.out.Value
+	}
+	out := int64(0)
+	for _, o := range tx.TxOut {
+		out += o.Value
+	}
+	if in < out {
+		return "", fmt.Errorf("[SendRawTx] response shows failure: bad-txns-in-belowout")
+	}
+	fee, size := in-out, VSize(tx)
+	if min := size * int64(MinRelayFeeRate); fee < min {
+		return "", fmt.Errorf("[SendRawTx] response shows failure: min relay fee not met, %d < %d", fee, min)
+	}
+
+	replaced := int64(0)
+	for other := range conflicts {
+		if !IsReplaceable(cli.txs[other]) {
+			return "", fmt.Errorf("[SendRawTx] response shows failure: txn-mempool-conflict")
+		}
+		replaced += cli.fee(cli.txs[other])
+	}
+	if len(conflicts) > 0 && fee < replaced+size*int64(MinRelayFeeRate) {
+		return "", fmt.Errorf("[SendRawTx] response shows failure: insufficient fee, rejecting replacement %s",
+			txid.String())
+	}
+	// evict in a fixed order
+	evicted := make([]chainhash.Hash, 0, len(conflicts))
+	for other := range conflicts {
+		evicted = append(evicted, other)
+	}
+	sort.Slice(evicted, func(i, j int) bool {
+		return evicted[i].String() < evicted[j].String()
+	})
+	for _, other := range evicted {
+		cli.evict(other)
+	}
+
+	cli.txs[txid] = tx
+	cli.mempool = append(cli.mempool, tx)
+	for _, ti := range tx.TxIn {
+		cli.spentBy[ti.PreviousOutPoint] = txid
+	}
+	for i, o := range tx.TxOut {
+		cli.coins[*wire.NewOutPoint(&txid, uint32(i))] = &fakeCoin{out: o, height: -1}
+	}
+	return txid.String(), nil
+}
+
+// GenerateToAddr mines n blocks paying coinbase to addr, the first one takes all txs in mempool
+func (cli *FakeCli) GenerateToAddr(n int, addr string) ([]string, error) {
+	cli.lock.Lock()
+	defer cli.lock.Unlock()
+
+	pkScript, err := cli.addrScript(addr)
+	if err != nil {
+		return nil, fmt.Errorf("[GenerateToAddr] response shows failure: %v", err)
+	}
+	hashes := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		txs := cli.mempool
+		cli.mempool = nil
+		hashes = append(hashes, cli.mine(pkScript, txs).BlockHash().String())
+	}
+	return hashes, nil
+}
@@ -0,0 +1,70 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package btc
+
+import (
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	"github.com/polynetwork/poly-io-test/config"
+	"testing"
+)
+
+// TestVendor returns the 2-of-3 vendor on netParam and its members for tests with FakeCli. The keys
+// are the same on every call.
+func TestVendor(t testing.TB, netParam *chaincfg.Params) (*Vendor, []*VendorMember) {
+	v := &Vendor{
+		PrivateKeys: make([]*btcutil.WIF, 3),
+		AddressSet:  make([]*btcutil.AddressPubKey, 3),
+	}
+	members := make([]*VendorMember, 3)
+	for i := range members {
+		privk, _ := btcec.PrivKeyFromBytes(btcec.S256(), chainhash.HashB([]byte{byte(i)}))
+		wif, err := btcutil.NewWIF(privk, netParam, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr, err := btcutil.NewAddressPubKey(privk.PubKey().SerializeCompressed(), netParam)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v.PrivateKeys[i], v.AddressSet[i] = wif, addr
+		members[i] = &VendorMember{Privk: privk, Addr: addr}
+	}
+	redeem, err := txscript.MultiSigScript(v.AddressSet, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.Redeem, v.HashKey = redeem, btcutil.Hash160(redeem)
+	if v.P2shAddr, err = btcutil.NewAddressScriptHash(redeem, netParam); err != nil {
+		t.Fatal(err)
+	}
+	if v.P2wshAddr, err = btcutil.NewAddressWitnessScriptHash(chainhash.HashB(redeem), netParam); err != nil {
+		t.Fatal(err)
+	}
+	return v, members
+}
+
+// KeepConfig restores config.DefConfig and config.BtcNet changed by test t when it finishes
+func KeepConfig(t testing.TB) {
+	conf, net := *config.DefConfig, config.BtcNet
+	t.Cleanup(func() {
+		*config.DefConfig, config.BtcNet = conf, net
+	})
+}
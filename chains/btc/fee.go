@@ -0,0 +1,131 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package btc
+
+import (
+	"errors"
+	"fmt"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/polynetwork/poly-io-test/log"
+)
+
+const (
+	// fee rates are in satoshi per vbyte
+	MinRelayFeeRate uint64 = 1
+	DefaultFeeRate  uint64 = 2
+	FeeConfTarget          = 6
+	DustLimit       int64  = 546
+	// sequence signaling BIP125 replacement
+	ReplaceableSequence = wire.MaxTxInSequenceNum - 2
+)
+
+// ErrConfirmed is returned by BumpFee for a tx already in a block, which can not be replaced
+var ErrConfirmed = errors.New("tx is already confirmed")
+
+// FeeRate returns rate if positive, otherwise the rate estimated by node, or DefaultFeeRate
+// if node fails to estimate
+func FeeRate(cli BtcClient, rate uint64) uint64 {
+	if rate > 0 {
+		return rate
+	}
+	rate, err := cli.EstimateSmartFee(FeeConfTarget)
+	if err != nil {
+		log.Warnf("FeeRate, using %d sat/vbyte for failed to estimate: %v", DefaultFeeRate, err)
+		return DefaultFeeRate
+	}
+	return rate
+}
+
+// VSize returns the virtual size of tx in vbytes
+func VSize(tx *wire.MsgTx) int64 {
+	weight := tx.SerializeSizeStripped()*3 + tx.SerializeSize()
+	return int64((weight + 3) / 4)
+}
+
+func IsReplaceable(tx *wire.MsgTx) bool {
+	for _, in := range tx.TxIn {
+		if in.Sequence < wire.MaxTxInSequenceNum-1 {
+			return true
+		}
+	}
+	return false
+}
+
+// BumpFee rebuilds the replaceable tx txid to pay fee at feeRate, taking the extra fee
+// from its change output which is the last one after the lock and nulldata outputs
+func BumpFee(cli BtcClient, privk *btcec.PrivateKey, netParam *chaincfg.Params, txid string,
+	feeRate uint64) (*wire.MsgTx, error) {
+	mtx, err := cli.GetTx(txid)
+	if err != nil {
+		return nil, err
+	}
+	confs, err := cli.GetTxConfs(txid)
+	if err != nil {
+		return nil, err
+	}
+	if confs > 0 {
+		return nil, ErrConfirmed
+	}
+	if !IsReplaceable(mtx) {
+		return nil, fmt.Errorf("tx %s is not replaceable", txid)
+	}
+	if len(mtx.TxOut) < 3 {
+		return nil, fmt.Errorf("tx %s has no change output to pay more fee", txid)
+	}
+
+	b := &Builder{
+		NetParam:      netParam,
+		PrivKey:       privk,
+		Tx:            mtx.Copy(),
+		PrevPkScripts: make([][]byte, len(mtx.TxIn)),
+		PrevAmounts:   make([]int64, len(mtx.TxIn)),
+	}
+	oldFee := int64(0)
+	for i, in := range mtx.TxIn {
+		prev, err := cli.GetTx(in.PreviousOutPoint.Hash.String())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get prev tx of No.%d input: %v", i, err)
+		}
+		out := prev.TxOut[in.PreviousOutPoint.Index]
+		b.PrevPkScripts[i], b.PrevAmounts[i] = out.PkScript, out.Value
+		oldFee += out.Value
+	}
+	for _, out := range mtx.TxOut {
+		oldFee -= out.Value
+	}
+
+	// sign once to get the size with witness
+	if err = b.BuildSignedTx(); err != nil {
+		return nil, err
+	}
+	size := VSize(b.Tx)
+	fee := size * int64(feeRate)
+	// BIP125 requires the replacement to pay for its own relay on top of the old fee
+	if min := oldFee + size*int64(MinRelayFeeRate); fee < min {
+		fee = min
+	}
+	change := b.Tx.TxOut[len(b.Tx.TxOut)-1]
+	if change.Value -= fee - oldFee; change.Value < DustLimit {
+		return nil, fmt.Errorf("change of tx %s is not enough to pay fee %d", txid, fee)
+	}
+	if err = b.BuildSignedTx(); err != nil {
+		return nil, err
+	}
+	return b.Tx, nil
+}
@@ -0,0 +1,212 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package btc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+// MultisigPkScripts returns the P2SH and P2WSH pk scripts of redeem
+func MultisigPkScripts(redeem []byte, netParam *chaincfg.Params) ([]byte, []byte, error) {
+	p2sh, err := btcutil.NewAddressScriptHash(redeem, netParam)
+	if err != nil {
+		return nil, nil, err
+	}
+	hash := sha256.Sum256(redeem)
+	p2wsh, err := btcutil.NewAddressWitnessScriptHash(hash[:], netParam)
+	if err != nil {
+		return nil, nil, err
+	}
+	shScript, err := txscript.PayToAddrScript(p2sh)
+	if err != nil {
+		return nil, nil, err
+	}
+	wshScript, err := txscript.PayToAddrScript(p2wsh)
+	if err != nil {
+		return nil, nil, err
+	}
+	return shScript, wshScript, nil
+}
+
+// VerifyUnlock checks that tx unlocks btc from the multisig of redeem: every input spends its
+// P2SH or P2WSH, the outputs to address to sum to amount minus a fee no more than maxFee, and
+// all other outputs are change back to the multisig. The fee is not bounded if maxFee is not
+// positive, and the payment is not checked if to is empty.
+func VerifyUnlock(cli BtcClient, tx *wire.MsgTx, redeem []byte, to string, amount, maxFee int64,
+	netParam *chaincfg.Params) error {
+	p2sh, p2wsh, err := MultisigPkScripts(redeem, netParam)
+	if err != nil {
+		return fmt.Errorf("failed to get pk scripts of redeem: %v", err)
+	}
+	isMultisig := func(pkScript []byte) bool {
+		return bytes.Equal(pkScript, p2sh) || bytes.Equal(pkScript, p2wsh)
+	}
+	for i, in := range tx.TxIn {
+		prev, err := cli.GetTx(in.PreviousOutPoint.Hash.String())
+		if err != nil {
+			return fmt.Errorf("failed to get prev tx of No.%d input: %v", i, err)
+		}
+		if int(in.PreviousOutPoint.Index) >= len(prev.TxOut) {
+			return fmt.Errorf("No.%d input spends a missing output %s", i, in.PreviousOutPoint.String())
+		}
+		if !isMultisig(prev.TxOut[in.PreviousOutPoint.Index].PkScript) {
+			return fmt.Errorf("No.%d input %s not spent from multisig", i, in.PreviousOutPoint.String())
+		}
+	}
+
+	var toScript []byte
+	if to != "" {
+		addr, err := btcutil.DecodeAddress(to, netParam)
+		if err != nil {
+			return fmt.Errorf("failed to decode address %s: %v", to, err)
+		}
+		if toScript, err = txscript.PayToAddrScript(addr); err != nil {
+			return fmt.Errorf("failed to get pk script of %s: %v", to, err)
+		}
+	}
+	paid := int64(0)
+	for i, out := range tx.TxOut {
+		switch {
+		case toScript != nil && bytes.Equal(out.PkScript, toScript):
+			paid += out.Value
+		case !isMultisig(out.PkScript):
+			return fmt.Errorf("No.%d output of %d satoshi pays neither %s nor multisig", i, out.Value, to)
+		}
+	}
+	if toScript == nil {
+		return nil
+	}
+	if paid <= 0 || paid > amount {
+		return fmt.Errorf("paid %d satoshi to %s for %d locked", paid, to, amount)
+	}
+	if maxFee > 0 && amount-paid > maxFee {
+		return fmt.Errorf("fee %d of %d satoshi locked is more than %d", amount-paid, amount, maxFee)
+	}
+	return nil
+}
+
+// SignMultisig signs every input of tx spending the multisig of redeem with the first members
+// enough for it, the way poly adds signatures of the vendor. The signature script of every input
+// holds the pk script it spends before signing, and amts are the values spent.
+func SignMultisig(tx *wire.MsgTx, amts []uint64, redeem []byte, members []*VendorMember,
+	netParam *chaincfg.Params) error {
+	_, addrs, required, err := txscript.ExtractPkScriptAddrs(redeem, netParam)
+	if err != nil {
+		return fmt.Errorf("failed to extract addresses of redeem: %v", err)
+	}
+	// signatures must be in the order of public keys in redeem
+	signers := make([]*VendorMember, 0, required)
+	for _, a := range addrs {
+		for _, m := range members {
+			if len(signers) < required && m.Addr.EncodeAddress() == a.EncodeAddress() {
+				signers = append(signers, m)
+				break
+			}
+		}
+	}
+	if len(signers) < required {
+		return fmt.Errorf("%d members of redeem to sign, %d required", len(signers), required)
+	}
+	sigs := make([][][]byte, len(signers))
+	for i, m := range signers {
+		if sigs[i], err = SignUnlock(tx, amts, redeem, m.Privk); err != nil {
+			return err
+		}
+	}
+	for i, in := range tx.TxIn {
+		switch c := txscript.GetScriptClass(in.SignatureScript); c {
+		case txscript.ScriptHashTy:
+			builder := txscript.NewScriptBuilder().AddOp(txscript.OP_FALSE)
+			for _, s := range sigs {
+				builder.AddData(s[i])
+			}
+			if in.SignatureScript, err = builder.AddData(redeem).Script(); err != nil {
+				return err
+			}
+		case txscript.WitnessV0ScriptHashTy:
+			in.Witness = wire.TxWitness{nil}
+			for _, s := range sigs {
+				in.Witness = append(in.Witness, s[i])
+			}
+			in.Witness, in.SignatureScript = append(in.Witness, redeem), nil
+		default:
+			return fmt.Errorf("wrong type %s of No.%d input", c, i)
+		}
+	}
+	return nil
+}
+
+// SweepMultisig builds the tx moving utxos of the multisig of redeem to address to in one output
+// paying fee at feeRate, signed by members. The output is followed by a null data output of data
+// if not nil, e.g. BuildData for a cross chain tx.
+func SweepMultisig(utxos []*Utxo, redeem []byte, members []*VendorMember, to btcutil.Address, data []byte,
+	feeRate uint64, netParam *chaincfg.Params) (*wire.MsgTx, error) {
+	if len(utxos) == 0 {
+		return nil, fmt.Errorf("no utxos to sweep")
+	}
+	toScript, err := txscript.PayToAddrScript(to)
+	if err != nil {
+		return nil, err
+	}
+	build := func(fee int64) (*wire.MsgTx, error) {
+		tx := wire.NewMsgTx(wire.TxVersion)
+		amts := make([]uint64, len(utxos))
+		sum := int64(0)
+		for i, u := range utxos {
+			hash, err := chainhash.NewHashFromStr(u.Txid)
+			if err != nil {
+				return nil, err
+			}
+			pkScript, err := hex.DecodeString(u.ScriptPubKey)
+			if err != nil {
+				return nil, err
+			}
+			tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(hash, u.Vout), pkScript, nil))
+			amts[i] = uint64(u.Amount)
+			sum += u.Amount
+		}
+		if sum-fee < DustLimit {
+			return nil, fmt.Errorf("%d satoshi of %d utxos is not enough to pay fee %d", sum, len(utxos), fee)
+		}
+		tx.AddTxOut(wire.NewTxOut(sum-fee, toScript))
+		if data != nil {
+			nullData, err := txscript.NullDataScript(data)
+			if err != nil {
+				return nil, err
+			}
+			tx.AddTxOut(wire.NewTxOut(0, nullData))
+		}
+		if err := SignMultisig(tx, amts, redeem, members, netParam); err != nil {
+			return nil, err
+		}
+		return tx, nil
+	}
+	// value of output does not change the size
+	tx, err := build(0)
+	if err != nil {
+		return nil, err
+	}
+	return build(VSize(tx) * int64(feeRate))
+}
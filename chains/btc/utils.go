@@ -25,27 +25,24 @@ import (
 	"fmt"
 	"github.com/btcsuite/btcd/btcjson"
 	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcutil"
-	types2 "github.com/cosmos/cosmos-sdk/types"
 	"github.com/ontio/ontology-crypto/keypair"
 	"github.com/ontio/ontology-crypto/signature"
-	"github.com/ontio/ontology/common"
 	"github.com/polynetwork/poly-go-sdk"
-	"github.com/polynetwork/poly-io-test/config"
-	"github.com/polynetwork/poly-io-test/log"
 	"github.com/polynetwork/poly/account"
 	common2 "github.com/polynetwork/poly/common"
 	"github.com/polynetwork/poly/core/types"
 	"github.com/polynetwork/poly/native/service/cross_chain_manager/btc"
 	"io/ioutil"
+	"math"
 	"math/rand"
 	"net/http"
 	"net/url"
 	"os"
 	"sort"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
 )
@@ -72,6 +69,36 @@ func NewBtcSigner(privateKey string) (*BtcSigner, error) {
 	}, nil
 }
 
+const (
+	InputP2pkh      = "p2pkh"
+	InputP2wpkh     = "p2wpkh"
+	InputP2shP2wpkh = "p2sh-p2wpkh"
+)
+
+// AddressOf returns the address of signer holding utxos spent as inputs of type ty,
+// legacy P2PKH for empty ty
+func (signer *BtcSigner) AddressOf(ty string, netParam *chaincfg.Params) (btcutil.Address, error) {
+	pkh := btcutil.Hash160(signer.WIF.PrivKey.PubKey().SerializeCompressed())
+	switch ty {
+	case "", InputP2pkh:
+		return btcutil.NewAddressPubKeyHash(pkh, netParam)
+	case InputP2wpkh:
+		return btcutil.NewAddressWitnessPubKeyHash(pkh, netParam)
+	case InputP2shP2wpkh:
+		wpkh, err := btcutil.NewAddressWitnessPubKeyHash(pkh, netParam)
+		if err != nil {
+			return nil, err
+		}
+		redeem, err := txscript.PayToAddrScript(wpkh)
+		if err != nil {
+			return nil, err
+		}
+		return btcutil.NewAddressScriptHash(redeem, netParam)
+	default:
+		return nil, fmt.Errorf("input type %s not supported", ty)
+	}
+}
+
 func RandomInt64(min, max int64) int64 {
 	r := rand.New(rand.NewSource(time.Now().Unix()))
 	return r.Int63n(max-min) + min
@@ -567,6 +594,85 @@ func (cli *RestCli) GetTxOutVal(txid string, idx uint32) (int64, error) {
 	return mtx.TxOut[idx].Value, nil
 }
 
+func (cli *RestCli) GetTx(txid string) (*wire.MsgTx, error) {
+	rawTxStr, err := cli.GetRawTransaction(txid)
+	if err != nil {
+		return nil, err
+	}
+	rawTx, err := hex.DecodeString(rawTxStr)
+	if err != nil {
+		return nil, fmt.Errorf("[GetTx] failed to decode raw tx string: %v", err)
+	}
+	mtx := wire.NewMsgTx(wire.TxVersion)
+	if err = mtx.BtcDecode(bytes.NewBuffer(rawTx), wire.ProtocolVersion, wire.LatestEncoding); err != nil {
+		return nil, fmt.Errorf("[GetTx] failed to decode tx: %v", err)
+	}
+	return mtx, nil
+}
+
+// GetTxConfs returns the confirmations of tx txid, 0 if in mempool
+func (cli *RestCli) GetTxConfs(txid string) (int64, error) {
+	req, err := json.Marshal(Request{
+		Jsonrpc: "1.0",
+		Method:  "getrawtransaction",
+		Params:  []interface{}{txid, true},
+		Id:      1,
+	})
+	if err != nil {
+		return -1, fmt.Errorf("[GetTxConfs] failed to marshal request: %v", err)
+	}
+
+	resp, err := cli.sendPostReq(req)
+	if err != nil {
+		return -1, fmt.Errorf("[GetTxConfs] failed to send post: %v", err)
+	}
+	if resp.Error != nil {
+		return -1, fmt.Errorf("[GetTxConfs] response shows failure: %v", resp.Error.Message)
+	}
+	res, ok := resp.Result.(map[string]interface{})
+	if !ok {
+		return -1, fmt.Errorf("[GetTxConfs] wrong result: %v", resp.Result)
+	}
+	// missing when in mempool
+	confs, _ := res["confirmations"].(float64)
+	return int64(confs), nil
+}
+
+// EstimateSmartFee returns the fee rate in satoshi per vbyte to confirm in confTarget blocks
+func (cli *RestCli) EstimateSmartFee(confTarget int) (uint64, error) {
+	req, err := json.Marshal(Request{
+		Jsonrpc: "1.0",
+		Method:  "estimatesmartfee",
+		Params:  []interface{}{confTarget},
+		Id:      1,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("[EstimateSmartFee] failed to marshal request: %v", err)
+	}
+
+	resp, err := cli.sendPostReq(req)
+	if err != nil {
+		return 0, fmt.Errorf("[EstimateSmartFee] failed to send post: %v", err)
+	}
+	if resp.Error != nil {
+		return 0, fmt.Errorf("[EstimateSmartFee] response shows failure: %v", resp.Error.Message)
+	}
+	res, ok := resp.Result.(map[string]interface{})
+	if !ok {
+		return 0, fmt.Errorf("[EstimateSmartFee] wrong result: %v", resp.Result)
+	}
+	// btc per kvbyte, missing when the node has not enough data
+	rate, ok := res["feerate"].(float64)
+	if !ok {
+		return 0, fmt.Errorf("[EstimateSmartFee] no fee rate: %v", res["errors"])
+	}
+	satPerVb := uint64(math.Ceil(rate * btcutil.SatoshiPerBitcoin / 1000))
+	if satPerVb < MinRelayFeeRate {
+		satPerVb = MinRelayFeeRate
+	}
+	return satPerVb, nil
+}
+
 func (cli *RestCli) GetHeader(h int32) (*wire.BlockHeader, error) {
 	req, err := json.Marshal(Request{
 		Jsonrpc: "1.0",
@@ -646,54 +752,25 @@ func hexReverse(arr []byte) []byte {
 	return x
 }
 
+// BuildData returns the data of the btc cross chain tx to toAddr on chain toChainId, whose
+// address is decoded by AddressCodecOf
 func BuildData(toChainId uint64, ccFee int64, toAddr string) ([]byte, error) {
-	var data []byte
-	ccflag := byte(0xcc)
-	var args *btc.Args
-	switch toChainId {
-	case 2:
-		toAddr = strings.Replace(toAddr, "0x", "", 1)
-		toAddrBytes, err := hex.DecodeString(toAddr)
-		if err != nil {
-			return nil, err
-		}
-		args = &btc.Args{
-			Address:   toAddrBytes[:],
-			ToChainID: toChainId,
-			Fee:       ccFee,
-		}
-	case config.ONT_CHAIN_ID:
-		addrBytes, _ := common.AddressFromBase58(toAddr)
-		args = &btc.Args{
-			Address:   addrBytes[:],
-			ToChainID: toChainId,
-			Fee:       ccFee,
-		}
-	case config.DefConfig.CMCrossChainId:
-		addrBytes, _ := types2.AccAddressFromBech32(toAddr)
-		args = &btc.Args{
-			Address:   addrBytes[:],
-			ToChainID: toChainId,
-			Fee:       ccFee,
-		}
-	default:
-		toAddrBytes, err := hex.DecodeString(toAddr)
-		if err != nil {
-			return nil, err
-		}
-		args = &btc.Args{
-			Address:   toAddrBytes[:],
-			ToChainID: toChainId,
-			Fee:       ccFee,
-		}
-		log.Warn("not support address type, using hex.Decode")
+	codec, err := AddressCodecOf(toChainId)
+	if err != nil {
+		return nil, err
 	}
-	var buf []byte
-	sink := common2.NewZeroCopySink(buf)
+	addr, err := codec(toAddr)
+	if err != nil {
+		return nil, err
+	}
+	args := &btc.Args{
+		Address:   addr,
+		ToChainID: toChainId,
+		Fee:       ccFee,
+	}
+	sink := common2.NewZeroCopySink(nil)
 	args.Serialization(sink)
-	data = append(append(data, ccflag), sink.Bytes()...)
-
-	return data, nil
+	return append([]byte{0xcc}, sink.Bytes()...), nil
 }
 
 func encryptBtcPrivk(path, privk, pwd string) error {
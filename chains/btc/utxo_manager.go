@@ -0,0 +1,305 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package btc
+
+import (
+	"encoding/hex"
+	"fmt"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Selector picks utxos to pay value, and returns them with their sum
+type Selector func(utxos []*Utxo, value int64) ([]*Utxo, int64, error)
+
+const (
+	SelectorConfs   = "confs"
+	SelectorLargest = "largest"
+	SelectorBnb     = "bnb"
+	SelectorRandom  = "random"
+
+	// max tries of branch and bound before falling back to largest first
+	BnbMaxTries = 100000
+)
+
+var Selectors = map[string]Selector{
+	SelectorConfs:   SelectUtxos,
+	SelectorLargest: SelectLargestFirst,
+	SelectorBnb:     SelectBranchAndBound,
+	SelectorRandom:  SelectRandom,
+}
+
+// GetSelector returns the selector named name, SelectUtxos for empty name
+func GetSelector(name string) (Selector, error) {
+	if name == "" {
+		return SelectUtxos, nil
+	}
+	s, ok := Selectors[name]
+	if !ok {
+		return nil, fmt.Errorf("utxo selector %s not supported", name)
+	}
+	return s, nil
+}
+
+func accumulate(utxos []*Utxo, value int64) ([]*Utxo, int64, error) {
+	if value <= 0 {
+		return nil, -1, fmt.Errorf("value must be positive")
+	}
+	selected := make([]*Utxo, 0)
+	selectedVal := int64(0)
+	for _, u := range utxos {
+		selected = append(selected, u)
+		if selectedVal += u.Amount; selectedVal >= value {
+			return selected, selectedVal, nil
+		}
+	}
+	return nil, selectedVal, fmt.Errorf("not enough utxo for %d, all we have is %d", value, selectedVal)
+}
+
+func sortByAmount(utxos []*Utxo) []*Utxo {
+	ul := make(UtxoList, 0, len(utxos))
+	for _, v := range utxos {
+		ul = append(ul, &UtxoItem{key: v, val: v.Amount})
+	}
+	sort.Sort(sort.Reverse(ul))
+	res := make([]*Utxo, len(ul))
+	for i, v := range ul {
+		res[i] = v.key
+	}
+	return res
+}
+
+// SelectLargestFirst spends the largest utxos first, which makes the fewest inputs
+func SelectLargestFirst(utxos []*Utxo, value int64) ([]*Utxo, int64, error) {
+	return accumulate(sortByAmount(utxos), value)
+}
+
+// SelectRandom spends utxos in random order, so that concurrent senders seldom pick the same
+func SelectRandom(utxos []*Utxo, value int64) ([]*Utxo, int64, error) {
+	shuffled := make([]*Utxo, len(utxos))
+	copy(shuffled, utxos)
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	r.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	return accumulate(shuffled, value)
+}
+
+// SelectBranchAndBound searches for the utxos paying value with a change under DustLimit, so
+// no change output is needed, and falls back to largest first if not found
+func SelectBranchAndBound(utxos []*Utxo, value int64) ([]*Utxo, int64, error) {
+	if value <= 0 {
+		return nil, -1, fmt.Errorf("value must be positive")
+	}
+	sorted := sortByAmount(utxos)
+	rest := make([]int64, len(sorted)+1)
+	for i := len(sorted) - 1; i >= 0; i-- {
+		rest[i] = rest[i+1] + sorted[i].Amount
+	}
+
+	var best []int
+	bestVal, tries := int64(-1), 0
+	picked := make([]int, 0, len(sorted))
+	var search func(i int, sum int64)
+	search = func(i int, sum int64) {
+		if sum >= value {
+			if sum-value < DustLimit && (best == nil || sum < bestVal) {
+				best, bestVal = append([]int{}, picked...), sum
+			}
+			return
+		}
+		if i == len(sorted) || sum+rest[i] < value || tries >= BnbMaxTries || bestVal == value {
+			return
+		}
+		tries++
+		picked = append(picked, i)
+		search(i+1, sum+sorted[i].Amount)
+		picked = picked[:len(picked)-1]
+		search(i+1, sum)
+	}
+	search(0, 0)
+	if best == nil {
+		return accumulate(sorted, value)
+	}
+
+	selected := make([]*Utxo, len(best))
+	for i, idx := range best {
+		selected[i] = sorted[idx]
+	}
+	return selected, bestVal, nil
+}
+
+func outpoint(txid string, vout uint32) string {
+	return fmt.Sprintf("%s:%d", txid, vout)
+}
+
+// UtxoManager selects utxos of one address for concurrent senders. Selected utxos are reserved
+// until released or no longer listed as unspent by node, and change of sent txs can be spent
+// before confirmed.
+type UtxoManager struct {
+	lock     *sync.Mutex
+	cli      BtcClient
+	addr     btcutil.Address
+	pkScript []byte
+	selector Selector
+
+	reserved    map[string]string // outpoint to txid spending it, empty if not sent yet
+	unconfirmed map[string]*Utxo  // change of sent txs
+}
+
+func NewUtxoManager(cli BtcClient, addr btcutil.Address, selector Selector) (*UtxoManager, error) {
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return nil, fmt.Errorf("NewUtxoManager, failed to get pk script of %s: %v", addr.EncodeAddress(), err)
+	}
+	return &UtxoManager{
+		lock:        &sync.Mutex{},
+		cli:         cli,
+		addr:        addr,
+		pkScript:    pkScript,
+		selector:    selector,
+		reserved:    make(map[string]string),
+		unconfirmed: make(map[string]*Utxo),
+	}, nil
+}
+
+// available lists confirmed utxos and unconfirmed change not reserved, and drops the reservations
+// and unconfirmed change settled on chain or spent by txs settled or dropped
+func (m *UtxoManager) available() ([]*Utxo, error) {
+	cnt, err := m.cli.GetBlockCount()
+	if err != nil {
+		return nil, err
+	}
+	utxos, err := m.cli.ListUnspent(1, cnt, m.addr.EncodeAddress())
+	if err != nil {
+		return nil, err
+	}
+	listed := make(map[string]bool)
+	for _, u := range utxos {
+		listed[outpoint(u.Txid, u.Vout)] = true
+	}
+	for op := range m.unconfirmed {
+		if listed[op] {
+			delete(m.unconfirmed, op)
+			continue
+		}
+		// change spent by another sent tx is never listed, drop it once the spending tx is
+		// confirmed or gone from mempool
+		txid := m.reserved[op]
+		if txid == "" {
+			continue
+		}
+		if confs, err := m.cli.GetTxConfs(txid); err != nil || confs > 0 {
+			delete(m.unconfirmed, op)
+			delete(m.reserved, op)
+		}
+	}
+	// unconfirmed change is not listed until confirmed, keep it reserved
+	for op, txid := range m.reserved {
+		if _, ok := m.unconfirmed[op]; txid != "" && !ok && !listed[op] {
+			delete(m.reserved, op)
+		}
+	}
+
+	res := make([]*Utxo, 0, len(utxos)+len(m.unconfirmed))
+	for _, u := range utxos {
+		if _, ok := m.reserved[outpoint(u.Txid, u.Vout)]; !ok {
+			res = append(res, u)
+		}
+	}
+	for op, u := range m.unconfirmed {
+		if _, ok := m.reserved[op]; !ok {
+			res = append(res, u)
+		}
+	}
+	return res, nil
+}
+
+// Reserve selects and reserves utxos to pay value
+func (m *UtxoManager) Reserve(value int64) ([]*Utxo, int64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	utxos, err := m.available()
+	if err != nil {
+		return nil, -1, fmt.Errorf("Reserve, failed to list utxos: %v", err)
+	}
+	selected, sum, err := m.selector(utxos, value)
+	if err != nil {
+		return nil, -1, fmt.Errorf("Reserve, %v", err)
+	}
+	for _, u := range selected {
+		m.reserved[outpoint(u.Txid, u.Vout)] = ""
+	}
+	return selected, sum, nil
+}
+
+// Release gives back utxos reserved but not sent
+func (m *UtxoManager) Release(utxos []*Utxo) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, u := range utxos {
+		delete(m.reserved, outpoint(u.Txid, u.Vout))
+	}
+}
+
+// Spent records tx sent spending the reserved utxos, and keeps its outputs to the address as
+// unconfirmed utxos
+func (m *UtxoManager) Spent(tx *wire.MsgTx) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.spent(tx)
+}
+
+func (m *UtxoManager) spent(tx *wire.MsgTx) {
+	txid := tx.TxHash().String()
+	for _, in := range tx.TxIn {
+		m.reserved[outpoint(in.PreviousOutPoint.Hash.String(), in.PreviousOutPoint.Index)] = txid
+	}
+	for i, out := range tx.TxOut {
+		if string(out.PkScript) != string(m.pkScript) {
+			continue
+		}
+		m.unconfirmed[outpoint(txid, uint32(i))] = &Utxo{
+			Txid:         txid,
+			Vout:         uint32(i),
+			ScriptPubKey: hex.EncodeToString(out.PkScript),
+			Amount:       out.Value,
+		}
+	}
+}
+
+// Replaced records tx replacing the sent tx old, whose change is gone with it
+func (m *UtxoManager) Replaced(old string, tx *wire.MsgTx) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for op, u := range m.unconfirmed {
+		if u.Txid == old {
+			delete(m.unconfirmed, op)
+			delete(m.reserved, op)
+		}
+	}
+	m.spent(tx)
+}
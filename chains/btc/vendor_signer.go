@@ -0,0 +1,286 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package btc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/ontio/ontology-crypto/ec"
+	"github.com/polynetwork/poly-go-sdk"
+	"github.com/polynetwork/poly-io-test/config"
+	"github.com/polynetwork/poly-io-test/log"
+	"github.com/polynetwork/poly/account"
+	pcom "github.com/polynetwork/poly/common"
+	pbtc "github.com/polynetwork/poly/native/service/cross_chain_manager/btc"
+	"github.com/polynetwork/poly/native/service/utils"
+	"time"
+)
+
+// VendorMember is a member of the btc multisig signing for the vendor
+type VendorMember struct {
+	Privk *btcec.PrivateKey
+	Addr  *btcutil.AddressPubKey
+}
+
+// LoadVendorMembers decrypts the private keys of the multisig members saved by Vendor.EncryptPrivateKeys
+// into files named file_1, file_2 and so on, one for every public key in redeem
+func LoadVendorMembers(file, pwd string, redeem []byte, netParam *chaincfg.Params) ([]*VendorMember, error) {
+	_, addrs, _, err := txscript.ExtractPkScriptAddrs(redeem, netParam)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract addresses of redeem: %v", err)
+	}
+	members := make([]*VendorMember, len(addrs))
+	for i := range addrs {
+		path := fmt.Sprintf("%s_%d", file, i+1)
+		wallet, err := account.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %v", path, err)
+		}
+		acc, err := wallet.GetDefaultAccount([]byte(pwd))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt %s: %v", path, err)
+		}
+		pri, ok := acc.PrivKey().(*ec.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("wrong type of private key in %s", path)
+		}
+		privk, _ := btcec.PrivKeyFromBytes(btcec.S256(), pri.D.Bytes())
+		addr, err := btcutil.NewAddressPubKey(privk.PubKey().SerializeCompressed(), netParam)
+		if err != nil {
+			return nil, err
+		}
+		found := false
+		for _, a := range addrs {
+			found = found || a.EncodeAddress() == addr.EncodeAddress()
+		}
+		if !found {
+			return nil, fmt.Errorf("key in %s is not a member of redeem", path)
+		}
+		members[i] = &VendorMember{Privk: privk, Addr: addr}
+	}
+	return members, nil
+}
+
+// SignUnlock signs every input of the unlock tx made by poly, whose signature scripts hold
+// the pk scripts of the multisig utxos spent and amts their values, the same way as the vendor
+// signing tool.
+func SignUnlock(mtx *wire.MsgTx, amts []uint64, redeem []byte, privk *btcec.PrivateKey) ([][]byte, error) {
+	if len(amts) != len(mtx.TxIn) {
+		return nil, fmt.Errorf("%d amounts for %d inputs", len(amts), len(mtx.TxIn))
+	}
+	tx := mtx.Copy()
+	pkScripts := make([][]byte, len(tx.TxIn))
+	for i, in := range tx.TxIn {
+		pkScripts[i], in.SignatureScript = in.SignatureScript, nil
+	}
+	var sh *txscript.TxSigHashes
+	sigs := make([][]byte, len(tx.TxIn))
+	for i, pks := range pkScripts {
+		var err error
+		switch c := txscript.GetScriptClass(pks); c {
+		case txscript.MultiSigTy, txscript.ScriptHashTy:
+			sigs[i], err = txscript.RawTxInSignature(tx, i, redeem, txscript.SigHashAll, privk)
+		case txscript.WitnessV0ScriptHashTy:
+			if sh == nil {
+				sh = txscript.NewTxSigHashes(tx)
+			}
+			sigs[i], err = txscript.RawTxInWitnessSignature(tx, sh, i, int64(amts[i]), redeem,
+				txscript.SigHashAll, privk)
+		default:
+			return nil, fmt.Errorf("wrong type %s of No.%d input", c, i)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign No.%d input: %v", i, err)
+		}
+	}
+	return sigs, nil
+}
+
+// VendorSigner simulates the vendor signing tool in process: it watches poly for unlock txs made
+// for the multisig of Redeem and submits signatures of the members back to poly.
+type VendorSigner struct {
+	Poly    *poly_go_sdk.PolySdk
+	Acc     *poly_go_sdk.Account // pays for the poly txs
+	Redeem  []byte
+	Members []*VendorMember
+
+	// sign with the first Keys members only, all if 0; unlocks stay unsigned if fewer than required
+	Keys int
+	// wait before signing every unlock tx
+	Delay time.Duration
+
+	scanned uint32
+	// unlock txs failed to sign, to the values of their inputs
+	retry map[string][]uint64
+}
+
+func NewVendorSigner(poly *poly_go_sdk.PolySdk, acc *poly_go_sdk.Account, redeem []byte,
+	members []*VendorMember) *VendorSigner {
+	return &VendorSigner{
+		Poly:    poly,
+		Acc:     acc,
+		Redeem:  redeem,
+		Members: members,
+		retry:   make(map[string][]uint64),
+	}
+}
+
+// ScanFrom makes the signer start from poly height h
+func (vs *VendorSigner) ScanFrom(h uint32) {
+	if h > 0 {
+		vs.scanned = h - 1
+	}
+}
+
+func (vs *VendorSigner) signers() []*VendorMember {
+	if vs.Keys > 0 && vs.Keys < len(vs.Members) {
+		return vs.Members[:vs.Keys]
+	}
+	return vs.Members
+}
+
+// signedBy returns the members who signed the unlock tx txHash on poly already
+func (vs *VendorSigner) signedBy(txHash chainhash.Hash) (map[string][][]byte, error) {
+	raw, err := vs.Poly.GetStorage(utils.CrossChainManagerContractAddress.ToHexString(),
+		append([]byte(pbtc.MULTI_SIGN_INFO), txHash[:]...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get signatures of %s: %v", txHash.String(), err)
+	}
+	info := &pbtc.MultiSignInfo{MultiSignInfo: make(map[string][][]byte)}
+	if len(raw) > 0 {
+		if err = info.Deserialization(pcom.NewZeroCopySource(raw)); err != nil {
+			return nil, fmt.Errorf("failed to deserialize signatures of %s: %v", txHash.String(), err)
+		}
+	}
+	return info.MultiSignInfo, nil
+}
+
+// Sign submits the signatures of members not signed yet for the unlock tx in hex with input values amts
+func (vs *VendorSigner) Sign(rawTx string, amts []uint64) error {
+	raw, err := hex.DecodeString(rawTx)
+	if err != nil {
+		return err
+	}
+	mtx := wire.NewMsgTx(wire.TxVersion)
+	if err = mtx.BtcDecode(bytes.NewBuffer(raw), wire.ProtocolVersion, wire.LatestEncoding); err != nil {
+		return fmt.Errorf("failed to decode unlock tx: %v", err)
+	}
+	txHash := mtx.TxHash()
+	signed, err := vs.signedBy(txHash)
+	if err != nil {
+		return err
+	}
+	key := hex.EncodeToString(btcutil.Hash160(vs.Redeem))
+	for _, m := range vs.signers() {
+		if _, ok := signed[m.Addr.EncodeAddress()]; ok {
+			continue
+		}
+		sigs, err := SignUnlock(mtx, amts, vs.Redeem, m.Privk)
+		if err != nil {
+			return fmt.Errorf("member %s failed to sign %s: %v", m.Addr.EncodeAddress(), txHash.String(), err)
+		}
+		txid, err := vs.Poly.Native.Ccm.BtcMultiSign(config.BTC_CHAIN_ID, key, txHash[:], m.Addr.EncodeAddress(), sigs, vs.Acc)
+		if err != nil {
+			return fmt.Errorf("member %s failed to send signatures of %s: %v", m.Addr.EncodeAddress(),
+				txHash.String(), err)
+		}
+		log.Infof("vendor signer: member %s signed btc tx %s in poly tx %s", m.Addr.EncodeAddress(),
+			txHash.String(), txid.ToHexString())
+	}
+	return nil
+}
+
+func (vs *VendorSigner) scan(height uint32) error {
+	key := hex.EncodeToString(btcutil.Hash160(vs.Redeem))
+	for vs.scanned < height {
+		h := vs.scanned + 1
+		events, err := vs.Poly.GetSmartContractEventByBlock(h)
+		if err != nil {
+			return fmt.Errorf("failed to get events of poly block %d: %v", h, err)
+		}
+		for _, e := range events {
+			for _, n := range e.Notify {
+				if n.ContractAddress != utils.CrossChainManagerContractAddress.ToHexString() {
+					continue
+				}
+				states, ok := n.States.([]interface{})
+				if !ok || len(states) < 4 {
+					continue
+				}
+				if name, _ := states[0].(string); name != "makeBtcTx" {
+					continue
+				}
+				if rk, _ := states[1].(string); rk != key {
+					continue
+				}
+				rawTx, _ := states[2].(string)
+				vals, _ := states[3].([]interface{})
+				amts := make([]uint64, len(vals))
+				for i, v := range vals {
+					f, _ := v.(float64)
+					amts[i] = uint64(f)
+				}
+				time.Sleep(vs.Delay)
+				if err = vs.Sign(rawTx, amts); err != nil {
+					log.Errorf("vendor signer: unlock tx of poly tx %s, retry later: %v", e.TxHash, err)
+					vs.retry[rawTx] = amts
+				}
+			}
+		}
+		vs.scanned = h
+	}
+	return nil
+}
+
+// signFailed signs the unlock txs failed before again, and forgets the ones done
+func (vs *VendorSigner) signFailed() {
+	for rawTx, amts := range vs.retry {
+		if err := vs.Sign(rawTx, amts); err != nil {
+			log.Errorf("vendor signer: failed to sign unlock tx again: %v", err)
+			continue
+		}
+		delete(vs.retry, rawTx)
+	}
+}
+
+// Run signs unlock txs in new poly blocks, and the ones failed to sign before, every interval until
+// stop is closed
+func (vs *VendorSigner) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		vs.signFailed()
+		height, err := vs.Poly.GetCurrentBlockHeight()
+		if err != nil {
+			log.Warnf("vendor signer: failed to get poly height: %v", err)
+		} else if err = vs.scan(height); err != nil {
+			log.Warnf("vendor signer: %v", err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,186 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package btc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"sort"
+)
+
+// Wallet reshapes the utxo set of the signer address for batch tests
+type Wallet struct {
+	Cli      BtcClient
+	Signer   *BtcSigner
+	Addr     btcutil.Address
+	NetParam *chaincfg.Params
+	pkScript []byte
+}
+
+func NewWallet(cli BtcClient, signer *BtcSigner, inputType string, netParam *chaincfg.Params) (*Wallet, error) {
+	addr, err := signer.AddressOf(inputType, netParam)
+	if err != nil {
+		return nil, err
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return nil, err
+	}
+	return &Wallet{
+		Cli:      cli,
+		Signer:   signer,
+		Addr:     addr,
+		NetParam: netParam,
+		pkScript: pkScript,
+	}, nil
+}
+
+func (w *Wallet) ListUtxos() ([]*Utxo, error) {
+	cnt, err := w.Cli.GetBlockCount()
+	if err != nil {
+		return nil, err
+	}
+	return w.Cli.ListUnspent(1, cnt, w.Addr.EncodeAddress())
+}
+
+// sign signs tx spending utxos in order
+func (w *Wallet) sign(tx *wire.MsgTx, utxos []*Utxo) error {
+	b := &Builder{
+		NetParam:      w.NetParam,
+		PrivKey:       w.Signer.WIF.PrivKey,
+		Tx:            tx,
+		PrevPkScripts: make([][]byte, len(utxos)),
+		PrevAmounts:   make([]int64, len(utxos)),
+	}
+	for i, u := range utxos {
+		pkScript, err := hex.DecodeString(u.ScriptPubKey)
+		if err != nil {
+			return fmt.Errorf("failed to decode pk script of utxo %s:%d: %v", u.Txid, u.Vout, err)
+		}
+		b.PrevPkScripts[i], b.PrevAmounts[i] = pkScript, u.Amount
+	}
+	return b.BuildSignedTx()
+}
+
+func (w *Wallet) newTx(utxos []*Utxo) (*wire.MsgTx, error) {
+	tx := wire.NewMsgTx(wire.TxVersion)
+	for _, u := range utxos {
+		hash, err := chainhash.NewHashFromStr(u.Txid)
+		if err != nil {
+			return nil, fmt.Errorf("decode txid fail: %v", err)
+		}
+		tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(hash, u.Vout), nil, nil))
+	}
+	return tx, nil
+}
+
+// Split builds the tx paying n outputs of value to the wallet address, with the change back
+func (w *Wallet) Split(n int, value int64, feeRate uint64) (*wire.MsgTx, error) {
+	if n <= 0 || value < DustLimit {
+		return nil, fmt.Errorf("wrong split: %d outputs of %d satoshi", n, value)
+	}
+	utxos, err := w.ListUtxos()
+	if err != nil {
+		return nil, err
+	}
+	fee := int64(0)
+	for i := 0; i < 5; i++ {
+		selected, sum, err := SelectLargestFirst(utxos, int64(n)*value+fee)
+		if err != nil {
+			return nil, err
+		}
+		tx, err := w.newTx(selected)
+		if err != nil {
+			return nil, err
+		}
+		for j := 0; j < n; j++ {
+			tx.AddTxOut(wire.NewTxOut(value, w.pkScript))
+		}
+		if change := sum - int64(n)*value - fee; change >= DustLimit {
+			tx.AddTxOut(wire.NewTxOut(change, w.pkScript))
+		}
+		if err = w.sign(tx, selected); err != nil {
+			return nil, err
+		}
+		need := VSize(tx) * int64(feeRate)
+		if fee >= need {
+			return tx, nil
+		}
+		fee = need
+	}
+	return nil, fmt.Errorf("fee not settled at %d sat/vbyte", feeRate)
+}
+
+// Consolidate builds the tx merging at most maxInputs utxos under below, smallest first, into
+// one output. All utxos are merged if below is not positive.
+func (w *Wallet) Consolidate(below int64, maxInputs int, feeRate uint64) (*wire.MsgTx, error) {
+	utxos, err := w.ListUtxos()
+	if err != nil {
+		return nil, err
+	}
+	selected := make([]*Utxo, 0)
+	for _, u := range utxos {
+		if below <= 0 || u.Amount < below {
+			selected = append(selected, u)
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool {
+		return selected[i].Amount < selected[j].Amount
+	})
+	if maxInputs > 0 && len(selected) > maxInputs {
+		selected = selected[:maxInputs]
+	}
+	if len(selected) < 2 {
+		return nil, fmt.Errorf("only %d utxos to consolidate", len(selected))
+	}
+
+	tx, err := w.newTx(selected)
+	if err != nil {
+		return nil, err
+	}
+	sum := int64(0)
+	for _, u := range selected {
+		sum += u.Amount
+	}
+	tx.AddTxOut(wire.NewTxOut(sum, w.pkScript))
+	// value of output does not change the size
+	if err = w.sign(tx, selected); err != nil {
+		return nil, err
+	}
+	if tx.TxOut[0].Value -= VSize(tx) * int64(feeRate); tx.TxOut[0].Value < DustLimit {
+		return nil, fmt.Errorf("%d satoshi of %d utxos is not enough to pay fee", sum, len(selected))
+	}
+	if err = w.sign(tx, selected); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// Send broadcasts tx and returns its txid
+func (w *Wallet) Send(tx *wire.MsgTx) (string, error) {
+	var buf bytes.Buffer
+	if err := tx.BtcEncode(&buf, wire.ProtocolVersion, wire.LatestEncoding); err != nil {
+		return "", fmt.Errorf("failed to encode transaction: %v", err)
+	}
+	return w.Cli.SendRawTx(hex.EncodeToString(buf.Bytes()))
+}
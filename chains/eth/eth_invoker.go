@@ -26,7 +26,6 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	ontcommon "github.com/ontio/ontology/common"
-	utils2 "github.com/ontio/ontology/smartcontract/service/native/utils"
 	btcx_abi "github.com/polynetwork/poly-io-test/chains/eth/abi/btcx"
 	eccd_abi "github.com/polynetwork/poly-io-test/chains/eth/abi/eccd"
 	eccm_abi "github.com/polynetwork/poly-io-test/chains/eth/abi/eccm"
@@ -39,6 +38,7 @@ import (
 	"github.com/polynetwork/poly-io-test/config"
 	"log"
 	"math/big"
+	"strings"
 )
 
 type EInvoker struct {
@@ -180,71 +180,6 @@ func (ethInvoker *EInvoker) BindAssetHash(lockProxyAddr, fromAssetHash, toAssetH
 	return tx, nil
 }
 
-func (ethInvoker *EInvoker) BindOntAsset(lockProxy, ontOnEth, ongOnEth, oep4OnEth, oep4OnOnt string) ([]*types.Transaction, error) {
-	auth, contract, err := ethInvoker.MakeLockProxy(lockProxy)
-	if err != nil {
-		return nil, err
-	}
-	txs := make([]*types.Transaction, 0)
-
-	tx1, err := contract.BindAssetHash(auth, ethComm.HexToAddress(ontOnEth),
-		config.ONT_CHAIN_ID, utils2.OntContractAddress[:])
-	if err != nil {
-		return nil, err
-	}
-	ethInvoker.ETHUtil.WaitTransactionConfirm(tx1.Hash())
-	txs = append(txs, tx1)
-
-	auth, _ = ethInvoker.MakeSmartContractAuth()
-	tx4, err := contract.BindAssetHash(auth, ethComm.HexToAddress(ontOnEth),
-		config.DefConfig.CMCrossChainId, []byte(config.CM_ONT))
-	if err != nil {
-		return nil, err
-	}
-	ethInvoker.ETHUtil.WaitTransactionConfirm(tx4.Hash())
-	txs = append(txs, tx4)
-
-	auth, _ = ethInvoker.MakeSmartContractAuth()
-	tx2, err := contract.BindAssetHash(auth, ethComm.HexToAddress(ongOnEth),
-		config.ONT_CHAIN_ID, utils2.OngContractAddress[:])
-	if err != nil {
-		return nil, err
-	}
-	ethInvoker.ETHUtil.WaitTransactionConfirm(tx2.Hash())
-	txs = append(txs, tx2)
-
-	auth, _ = ethInvoker.MakeSmartContractAuth()
-	tx5, err := contract.BindAssetHash(auth, ethComm.HexToAddress(ongOnEth),
-		config.DefConfig.CMCrossChainId, []byte(config.CM_ONG))
-	if err != nil {
-		return nil, err
-	}
-	ethInvoker.ETHUtil.WaitTransactionConfirm(tx5.Hash())
-	txs = append(txs, tx5)
-
-	oep4, err := ontcommon.AddressFromHexString(oep4OnOnt)
-	if err != nil {
-		return nil, err
-	}
-	auth, _ = ethInvoker.MakeSmartContractAuth()
-	tx3, err := contract.BindAssetHash(auth, ethComm.HexToAddress(oep4OnEth), config.ONT_CHAIN_ID, oep4[:])
-	if err != nil {
-		return nil, err
-	}
-	ethInvoker.ETHUtil.WaitTransactionConfirm(tx3.Hash())
-	txs = append(txs, tx3)
-
-	auth, _ = ethInvoker.MakeSmartContractAuth()
-	tx6, err := contract.BindAssetHash(auth, ethComm.HexToAddress(oep4OnEth), config.DefConfig.CMCrossChainId, []byte(config.CM_OEP4))
-	if err != nil {
-		return nil, err
-	}
-	ethInvoker.ETHUtil.WaitTransactionConfirm(tx6.Hash())
-	txs = append(txs, tx6)
-
-	return txs, nil
-}
-
 func (ethInvoker *EInvoker) MakeLockProxy(lockProxyAddr string) (*bind.TransactOpts, *lockproxy_abi.LockProxy, error) {
 	auth, _ := ethInvoker.MakeSmartContractAuth()
 	contract, err := lockproxy_abi.NewLockProxy(ethComm.HexToAddress(lockProxyAddr),
@@ -341,6 +276,9 @@ func (ethInvoker *EInvoker) TransferOwnershipForECCD(eccdAddrHex, ownershipAddre
 	}
 	auth, _ := ethInvoker.MakeSmartContractAuth()
 	tx, err := eccdContract.TransferOwnership(auth, ownershipAddress)
+	if err != nil {
+		return nil, fmt.Errorf("TransferOwnershipForECCD, err: %v", err)
+	}
 	ethInvoker.ETHUtil.WaitTransactionConfirm(tx.Hash())
 	return tx, nil
 }
@@ -355,12 +293,170 @@ func (ethInvoker *EInvoker) TransferOwnershipForECCM(eccmAddrHex, ownershipAddre
 	auth, _ := ethInvoker.MakeSmartContractAuth()
 	tx, err := eccmContract.TransferOwnership(auth, ownershipAddress)
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("TransferOwnershipForECCM err: %v", err)
+	}
+	ethInvoker.ETHUtil.WaitTransactionConfirm(tx.Hash())
+	return tx, nil
+}
+
+func (ethInvoker *EInvoker) GetOwnerOfECCD(eccdAddrHex string) (ethComm.Address, error) {
+	eccdContract, err := eccd_abi.NewEthCrossChainData(ethComm.HexToAddress(eccdAddrHex), ethInvoker.ETHUtil.GetEthClient())
+	if err != nil {
+		return ethComm.Address{}, fmt.Errorf("GetOwnerOfECCD, err: %v", err)
+	}
+	owner, err := eccdContract.Owner(nil)
+	if err != nil {
+		return ethComm.Address{}, fmt.Errorf("GetOwnerOfECCD, failed to get owner: %v", err)
+	}
+	return owner, nil
+}
+
+func (ethInvoker *EInvoker) GetOwnerOfECCM(eccmAddrHex string) (ethComm.Address, error) {
+	eccmContract, err := eccm_abi.NewEthCrossChainManager(ethComm.HexToAddress(eccmAddrHex), ethInvoker.ETHUtil.GetEthClient())
+	if err != nil {
+		return ethComm.Address{}, fmt.Errorf("GetOwnerOfECCM, err: %v", err)
+	}
+	owner, err := eccmContract.Owner(nil)
+	if err != nil {
+		return ethComm.Address{}, fmt.Errorf("GetOwnerOfECCM, failed to get owner: %v", err)
+	}
+	return owner, nil
+}
+
+// PauseECCMP pauses ECCMP and the ECCM behind it, so no lock or unlock goes through
+func (ethInvoker *EInvoker) PauseECCMP(eccmpAddrHex string) (*types.Transaction, error) {
+	eccmpContract, err := eccmp_abi.NewEthCrossChainManagerProxy(ethComm.HexToAddress(eccmpAddrHex),
+		ethInvoker.ETHUtil.GetEthClient())
+	if err != nil {
+		return nil, fmt.Errorf("PauseECCMP, err: %v", err)
+	}
+	auth, _ := ethInvoker.MakeSmartContractAuth()
+	tx, err := eccmpContract.PauseEthCrossChainManager(auth)
+	if err != nil {
+		return nil, fmt.Errorf("PauseECCMP, err: %v", err)
 	}
 	ethInvoker.ETHUtil.WaitTransactionConfirm(tx.Hash())
 	return tx, nil
 }
 
+func (ethInvoker *EInvoker) UnpauseECCMP(eccmpAddrHex string) (*types.Transaction, error) {
+	eccmpContract, err := eccmp_abi.NewEthCrossChainManagerProxy(ethComm.HexToAddress(eccmpAddrHex),
+		ethInvoker.ETHUtil.GetEthClient())
+	if err != nil {
+		return nil, fmt.Errorf("UnpauseECCMP, err: %v", err)
+	}
+	auth, _ := ethInvoker.MakeSmartContractAuth()
+	tx, err := eccmpContract.UnpauseEthCrossChainManager(auth)
+	if err != nil {
+		return nil, fmt.Errorf("UnpauseECCMP, err: %v", err)
+	}
+	ethInvoker.ETHUtil.WaitTransactionConfirm(tx.Hash())
+	return tx, nil
+}
+
+// UpgradeECCM points the paused ECCMP to the new ECCM, which must be paused and
+// owned by ECCMP. ECCD is handed to the new ECCM.
+func (ethInvoker *EInvoker) UpgradeECCM(eccmpAddrHex, newEccmAddrHex string) (*types.Transaction, error) {
+	eccmpContract, err := eccmp_abi.NewEthCrossChainManagerProxy(ethComm.HexToAddress(eccmpAddrHex),
+		ethInvoker.ETHUtil.GetEthClient())
+	if err != nil {
+		return nil, fmt.Errorf("UpgradeECCM, err: %v", err)
+	}
+	auth, _ := ethInvoker.MakeSmartContractAuth()
+	tx, err := eccmpContract.UpgradeEthCrossChainManager(auth, ethComm.HexToAddress(newEccmAddrHex))
+	if err != nil {
+		return nil, fmt.Errorf("UpgradeECCM, err: %v", err)
+	}
+	ethInvoker.ETHUtil.WaitTransactionConfirm(tx.Hash())
+	return tx, nil
+}
+
+// PauseECCM pauses the ECCM owned by the invoker, and ECCD if it is not paused
+func (ethInvoker *EInvoker) PauseECCM(eccmAddrHex string) (*types.Transaction, error) {
+	eccmContract, err := eccm_abi.NewEthCrossChainManager(ethComm.HexToAddress(eccmAddrHex),
+		ethInvoker.ETHUtil.GetEthClient())
+	if err != nil {
+		return nil, fmt.Errorf("PauseECCM, err: %v", err)
+	}
+	auth, _ := ethInvoker.MakeSmartContractAuth()
+	tx, err := eccmContract.Pause(auth)
+	if err != nil {
+		return nil, fmt.Errorf("PauseECCM, err: %v", err)
+	}
+	ethInvoker.ETHUtil.WaitTransactionConfirm(tx.Hash())
+	return tx, nil
+}
+
+func (ethInvoker *EInvoker) IsECCMPPaused(eccmpAddrHex string) (bool, error) {
+	eccmpContract, err := eccmp_abi.NewEthCrossChainManagerProxy(ethComm.HexToAddress(eccmpAddrHex),
+		ethInvoker.ETHUtil.GetEthClient())
+	if err != nil {
+		return false, fmt.Errorf("IsECCMPPaused, err: %v", err)
+	}
+	paused, err := eccmpContract.Paused(nil)
+	if err != nil {
+		return false, fmt.Errorf("IsECCMPPaused, failed to get paused: %v", err)
+	}
+	return paused, nil
+}
+
+func (ethInvoker *EInvoker) IsECCMPaused(eccmAddrHex string) (bool, error) {
+	eccmContract, err := eccm_abi.NewEthCrossChainManager(ethComm.HexToAddress(eccmAddrHex),
+		ethInvoker.ETHUtil.GetEthClient())
+	if err != nil {
+		return false, fmt.Errorf("IsECCMPaused, err: %v", err)
+	}
+	paused, err := eccmContract.Paused(nil)
+	if err != nil {
+		return false, fmt.Errorf("IsECCMPaused, failed to get paused: %v", err)
+	}
+	return paused, nil
+}
+
+// GetECCMOfECCMP returns the ECCM behind ECCMP, it fails when ECCMP is paused
+func (ethInvoker *EInvoker) GetECCMOfECCMP(eccmpAddrHex string) (ethComm.Address, error) {
+	eccmpContract, err := eccmp_abi.NewEthCrossChainManagerProxy(ethComm.HexToAddress(eccmpAddrHex),
+		ethInvoker.ETHUtil.GetEthClient())
+	if err != nil {
+		return ethComm.Address{}, fmt.Errorf("GetECCMOfECCMP, err: %v", err)
+	}
+	eccm, err := eccmpContract.GetEthCrossChainManager(nil)
+	if err != nil {
+		return ethComm.Address{}, fmt.Errorf("GetECCMOfECCMP, failed to get eccm: %v", err)
+	}
+	return eccm, nil
+}
+
+// IsContractDeployed tells if there is any code at the address on the latest block
+func (ethInvoker *EInvoker) IsContractDeployed(addrHex string) (bool, error) {
+	if addrHex == "" || !ethComm.IsHexAddress(addrHex) {
+		return false, nil
+	}
+	code, err := ethInvoker.ETHUtil.GetEthClient().CodeAt(context.Background(), ethComm.HexToAddress(addrHex), nil)
+	if err != nil {
+		return false, fmt.Errorf("IsContractDeployed, failed to get code of %s: %v", addrHex, err)
+	}
+	return len(code) > 0, nil
+}
+
+// CrossChainEvent returns the cross chain event the ECCM eccm emitted in tx txHash
+func (ethInvoker *EInvoker) CrossChainEvent(eccm string, txHash ethComm.Hash) (*LockEvent, error) {
+	receipt, err := ethInvoker.ETHUtil.GetEthClient().TransactionReceipt(context.Background(), txHash)
+	if err != nil {
+		return nil, fmt.Errorf("CrossChainEvent, failed to get receipt of %s: %v", txHash.String(), err)
+	}
+	locks, _, err := ethInvoker.ETHUtil.GetSmartContractEventByBlock(eccm, receipt.BlockNumber.Uint64())
+	if err != nil {
+		return nil, fmt.Errorf("CrossChainEvent, %v", err)
+	}
+	for _, e := range locks {
+		if strings.EqualFold(e.TxHash, txHash.String()) {
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("CrossChainEvent, no cross chain event in %s", txHash.String())
+}
+
 func (ethInvoker *EInvoker) GetAccInfo() (string, error) {
 	h, err := ethInvoker.ETHUtil.GetNodeHeight()
 	if err != nil {
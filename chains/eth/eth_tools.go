@@ -88,6 +88,15 @@ type BlockRep struct {
 	Id      uint          `json:"id"`
 }
 
+type ProofRep struct {
+	JsonRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result"`
+	Error   *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+	Id uint `json:"id"`
+}
+
 func NewEthTools(url string) *ETHTools {
 	ethclient, err := ethclient.Dial(url)
 	if err != nil {
@@ -160,6 +169,33 @@ func (self *ETHTools) GetBlockHeader(height uint64) (*types.Header, error) {
 	return rsp.Result, nil
 }
 
+// GetProof returns the json of eth_getProof for key in the storage of contract at height, which
+// poly takes as the proof of cross chain txs from ethereum
+func (self *ETHTools) GetProof(contract, key string, height uint64) ([]byte, error) {
+	req := &BlockReq{
+		JsonRpc: "2.0",
+		Method:  "eth_getProof",
+		Params:  []interface{}{contract, []string{key}, fmt.Sprintf("0x%x", height)},
+		Id:      1,
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("GetProof: marshal req err: %s", err)
+	}
+	resp, err := self.restclient.SendRestRequest(data)
+	if err != nil {
+		return nil, fmt.Errorf("GetProof err: %s", err)
+	}
+	rsp := &ProofRep{}
+	if err = json.Unmarshal(resp, rsp); err != nil {
+		return nil, fmt.Errorf("GetProof, unmarshal resp err: %s", err)
+	}
+	if rsp.Error != nil {
+		return nil, fmt.Errorf("GetProof, eth_getProof err: %s", rsp.Error.Message)
+	}
+	return rsp.Result, nil
+}
+
 func (self *ETHTools) GetSmartContractEventByBlock(contractAddr string, height uint64) ([]*LockEvent, []*UnlockEvent, error) {
 	eccmAddr := common.HexToAddress(contractAddr)
 	instance, err := eccm.NewEthCrossChainManager(eccmAddr, self.ethclient)
@@ -22,12 +22,9 @@ import (
 	"github.com/ontio/ontology-go-sdk/utils"
 	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/common/log"
-	utils2 "github.com/ontio/ontology/smartcontract/service/native/utils"
 	"github.com/polynetwork/poly-io-test/config"
 	"io/ioutil"
-	"math/big"
 	"path"
-	"strings"
 	"time"
 )
 
@@ -79,19 +76,28 @@ func NewOntInvoker(rpc, avmPath, wallet, pwd string) (*OntInvoker, error) {
 //	}
 //}
 
+// GetContractAddress reads the avm of contract and tells its address and if it is deployed
+func (invoker *OntInvoker) GetContractAddress(name string) ([]byte, common.Address, bool, error) {
+	raw, err := ioutil.ReadFile(path.Join(invoker.OntAvmPath, name+".avm"))
+	if err != nil {
+		return nil, common.ADDRESS_EMPTY, false, err
+	}
+	addr, err := utils.GetContractAddress(string(raw))
+	if err != nil {
+		return nil, common.ADDRESS_EMPTY, false, err
+	}
+	val, err := invoker.OntSdk.GetSmartContract(addr.ToHexString())
+	return raw, addr, err == nil && val != nil, nil
+}
+
 func (invoker *OntInvoker) DeployContracts() ([]common.Address, error) {
 	addrs := make([]common.Address, 0)
 	for _, name := range ContractNames {
-		raw, err := ioutil.ReadFile(path.Join(invoker.OntAvmPath, name+".avm"))
+		raw, addr, deployed, err := invoker.GetContractAddress(name)
 		if err != nil {
 			return nil, err
 		}
-		addr, err := utils.GetContractAddress(string(raw))
-		if err != nil {
-			return nil, err
-		}
-		val, err := invoker.OntSdk.GetSmartContract(addr.ToHexString())
-		if err == nil && val != nil {
+		if deployed {
 			addrs = append(addrs, addr)
 			log.Warnf("contract %s already deployed", name)
 			continue
@@ -108,121 +114,6 @@ func (invoker *OntInvoker) DeployContracts() ([]common.Address, error) {
 	return addrs, nil
 }
 
-func (invoker *OntInvoker) SetupEthAsset(lockProxy, etho, erc20, erc20o string, gasPrice, gasLimit uint64) ([]common.Uint256, error) {
-	txs := make([]common.Uint256, 0)
-
-	contractAddress, err := utils.AddressFromHexString(lockProxy)
-	if err != nil {
-		log.Errorf("parse contract addr failed, err: %s", err)
-	}
-
-	// etho
-	ethoAddr, _ := common.AddressFromHexString(etho)
-	ethAddr, _ := common.HexToBytes("0000000000000000000000000000000000000000")
-	assetLimt := big.NewInt(1e18)
-
-	res, err := invoker.OntSdk.NeoVM.PreExecInvokeNeoVMContract(ethoAddr, []interface{}{"balanceOf", []interface{}{contractAddress[:]}})
-	if err != nil {
-		return nil, fmt.Errorf("failed to check the ethx balance of proxy: %v", err)
-	}
-	val, err := res.Result.ToInteger()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get value from result: %v", err)
-	}
-	tx2 := common.UINT256_EMPTY
-	if val.Uint64() == 0 {
-		tx2, err = invoker.OntSdk.NeoVM.InvokeNeoVMContract(gasPrice, gasLimit,
-			invoker.OntAcc,
-			invoker.OntAcc,
-			ethoAddr,
-			[]interface{}{"delegateToProxy", []interface{}{contractAddress[:], assetLimt}})
-		if err != nil {
-			return nil, fmt.Errorf("etho delegateToProxy error: %v", err)
-		}
-		invoker.WaitTxConfirmation(tx2)
-	}
-
-	tx1, err := invoker.OntSdk.NeoVM.InvokeNeoVMContract(gasPrice, gasLimit,
-		invoker.OntAcc,
-		invoker.OntAcc,
-		contractAddress,
-		[]interface{}{"bindAssetHash", []interface{}{ethoAddr[:], 2, ethAddr}})
-	if err != nil {
-		if !strings.Contains(err.Error(), "already") {
-			return nil, fmt.Errorf("etho bindAssetHash error: %v", err)
-		}
-		log.Warnf("etho is already binded: %v", err)
-	}
-	tx5, err := invoker.OntSdk.NeoVM.InvokeNeoVMContract(gasPrice, gasLimit,
-		invoker.OntAcc,
-		invoker.OntAcc,
-		contractAddress,
-		[]interface{}{"bindAssetHash", []interface{}{ethoAddr[:], config.DefConfig.CMCrossChainId, []byte(config.CM_ETHX)}})
-	if err != nil {
-		if !strings.Contains(err.Error(), "already") {
-			return nil, fmt.Errorf("eth on cosmos bindAssetHash error: %v", err)
-		}
-		log.Warnf("eth on cosmos is already binded: %v", err)
-	}
-
-	// erc20
-	erc20oAddr, _ := common.AddressFromHexString(erc20o)
-	erc20Addr, _ := common.HexToBytes(strings.Replace(erc20, "0x", "", 1))
-	res, err = invoker.OntSdk.NeoVM.PreExecInvokeNeoVMContract(erc20oAddr, []interface{}{"balanceOf", []interface{}{contractAddress[:]}})
-	if err != nil {
-		return nil, fmt.Errorf("failed to check the erc20 balance of proxy: %v", err)
-	}
-	val, err = res.Result.ToInteger()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get value from result: %v", err)
-	}
-	tx4 := common.UINT256_EMPTY
-	if val.Uint64() == 0 {
-		tx4, err = invoker.OntSdk.NeoVM.InvokeNeoVMContract(gasPrice, gasLimit,
-			invoker.OntAcc,
-			invoker.OntAcc,
-			erc20oAddr,
-			[]interface{}{"delegateToProxy", []interface{}{contractAddress[:], big.NewInt(1e13)}})
-		if err != nil {
-			return nil, fmt.Errorf("erc20 delegateToProxy error: %v", err)
-		}
-		invoker.WaitTxConfirmation(tx4)
-	}
-
-	tx3, err := invoker.OntSdk.NeoVM.InvokeNeoVMContract(gasPrice, gasLimit,
-		invoker.OntAcc,
-		invoker.OntAcc,
-		contractAddress,
-		[]interface{}{"bindAssetHash", []interface{}{erc20oAddr[:], 2, erc20Addr}})
-	if err != nil {
-		if !strings.Contains(err.Error(), "already") {
-			return nil, fmt.Errorf("erc20 bindAssetHash error: %v", err)
-		}
-		log.Warnf("erc20 on ethereum is already binded: %v", err)
-	}
-
-	tx6, err := invoker.OntSdk.NeoVM.InvokeNeoVMContract(gasPrice, gasLimit,
-		invoker.OntAcc,
-		invoker.OntAcc,
-		contractAddress,
-		[]interface{}{"bindAssetHash", []interface{}{erc20oAddr[:], config.DefConfig.CMCrossChainId, []byte(config.CM_ERC20)}})
-	if err != nil {
-		if !strings.Contains(err.Error(), "already") {
-			return nil, fmt.Errorf("erc20 on cosmos bindAssetHash error: %v", err)
-		}
-		log.Warnf("erc20 on cosmos is already binded: %v", err)
-	}
-
-	txs = append(txs, tx1)
-	txs = append(txs, tx2)
-	txs = append(txs, tx3)
-	txs = append(txs, tx4)
-	txs = append(txs, tx5)
-	txs = append(txs, tx6)
-
-	return txs, nil
-}
-
 func (invoker *OntInvoker) GetLockedAmt(lockProxy, src common.Address) ([]byte, error) {
 	res, err := invoker.OntSdk.NeoVM.PreExecInvokeNeoVMContract(lockProxy,
 		[]interface{}{"getLockedAmt", []interface{}{src}})
@@ -236,122 +127,6 @@ func (invoker *OntInvoker) GetLockedAmt(lockProxy, src common.Address) ([]byte,
 	return raw, nil
 }
 
-func (invoker *OntInvoker) SetupOntAsset(lockProxy, onte, onge, oep4, oep4e string, gasPrice, gasLimit uint64) ([]common.Uint256, error) {
-	txs := make([]common.Uint256, 0)
-
-	contractAddress, err := utils.AddressFromHexString(lockProxy)
-	if err != nil {
-		return nil, fmt.Errorf("parse contract addr failed, err: %s", err)
-	}
-
-	// ont
-	onteAddr, _ := common.HexToBytes(strings.Replace(onte, "0x", "", 1))
-	tx1, err := invoker.OntSdk.NeoVM.InvokeNeoVMContract(gasPrice, gasLimit,
-		invoker.OntAcc,
-		invoker.OntAcc,
-		contractAddress,
-		[]interface{}{"bindAssetHash", []interface{}{utils2.OntContractAddress, 2, onteAddr}})
-	if err != nil {
-		if !strings.Contains(err.Error(), "already") {
-			return nil, fmt.Errorf("bindAssetHash Ont error: %s", err)
-		}
-		log.Warnf("ont on ethereum is already binded: %v", err)
-	}
-	txs = append(txs, tx1)
-	tx2, err := invoker.OntSdk.NeoVM.InvokeNeoVMContract(gasPrice, gasLimit,
-		invoker.OntAcc,
-		invoker.OntAcc,
-		contractAddress,
-		[]interface{}{"bindAssetHash", []interface{}{utils2.OntContractAddress, config.DefConfig.CMCrossChainId,
-			[]byte(config.CM_ONT)}})
-	if err != nil {
-		if !strings.Contains(err.Error(), "already") {
-			return nil, fmt.Errorf("bindAssetHash Ont of cosmos error: %s", err)
-		}
-		log.Warnf("ont on cosmos is already binded: %v", err)
-	}
-	txs = append(txs, tx2)
-
-	// ong
-	ongeAddr, _ := common.HexToBytes(strings.Replace(onge, "0x", "", 1))
-	tx3, err := invoker.OntSdk.NeoVM.InvokeNeoVMContract(gasPrice, gasLimit,
-		invoker.OntAcc,
-		invoker.OntAcc,
-		contractAddress,
-		[]interface{}{"bindAssetHash", []interface{}{utils2.OngContractAddress[:], 2, ongeAddr}})
-	if err != nil {
-		if !strings.Contains(err.Error(), "already") {
-			return nil, fmt.Errorf("bindAssetHash ong error: %s", err)
-		}
-		log.Warnf("ong on ethereum is already binded: %v", err)
-	}
-	txs = append(txs, tx3)
-	tx4, err := invoker.OntSdk.NeoVM.InvokeNeoVMContract(gasPrice, gasLimit,
-		invoker.OntAcc,
-		invoker.OntAcc,
-		contractAddress,
-		[]interface{}{"bindAssetHash", []interface{}{utils2.OngContractAddress[:], config.DefConfig.CMCrossChainId,
-			[]byte(config.CM_ONG)}})
-	if err != nil {
-		if !strings.Contains(err.Error(), "already") {
-			return nil, fmt.Errorf("bindAssetHash ong of cosmos error: %s", err)
-		}
-		log.Warnf("ong on cosmos is already binded: %v", err)
-	}
-	txs = append(txs, tx4)
-
-	// oep4
-	oep4Addr, _ := common.AddressFromHexString(oep4)
-	oep4eAddr, _ := common.HexToBytes(strings.Replace(oep4e, "0x", "", 1))
-	tx5, err := invoker.OntSdk.NeoVM.InvokeNeoVMContract(gasPrice, gasLimit,
-		invoker.OntAcc,
-		invoker.OntAcc,
-		contractAddress,
-		[]interface{}{"bindAssetHash", []interface{}{oep4Addr[:], 2, oep4eAddr}})
-	if err != nil {
-		if !strings.Contains(err.Error(), "already") {
-			return nil, fmt.Errorf("bindAssetHash oep4 error: %s", err)
-		}
-		log.Warnf("oep4 on ethereum is already binded: %v", err)
-	}
-	txs = append(txs, tx5)
-	tx6, err := invoker.OntSdk.NeoVM.InvokeNeoVMContract(gasPrice, gasLimit,
-		invoker.OntAcc,
-		invoker.OntAcc,
-		contractAddress,
-		[]interface{}{"bindAssetHash", []interface{}{oep4Addr[:], config.DefConfig.CMCrossChainId, []byte(config.CM_OEP4)}})
-	if err != nil {
-		if !strings.Contains(err.Error(), "already") {
-			return nil, fmt.Errorf("bindAssetHash oep4 of cosmos error: %s", err)
-		}
-		log.Warnf("oep4 on cosmos is already binded: %v", err)
-	}
-	txs = append(txs, tx6)
-	tx7, err := invoker.OntSdk.NeoVM.InvokeNeoVMContract(gasPrice, gasLimit,
-		invoker.OntAcc,
-		invoker.OntAcc,
-		oep4Addr,
-		[]interface{}{"init", []interface{}{}})
-	if err != nil {
-		log.Errorf("init oep4 error, maybe already inited and approved: %s", err)
-		return txs, nil
-	}
-	invoker.WaitTxConfirmation(tx4)
-	txs = append(txs, tx7)
-
-	tx8, err := invoker.OntSdk.NeoVM.InvokeNeoVMContract(gasPrice, gasLimit,
-		invoker.OntAcc,
-		invoker.OntAcc,
-		oep4Addr,
-		[]interface{}{"approve", []interface{}{invoker.OntAcc.Address[:], contractAddress[:], big.NewInt(1e13)}})
-	if err != nil {
-		return nil, fmt.Errorf("ApproveOEP4, ctx.Ont.NeoVM.InvokeNeoVMContract error: %s", err)
-	}
-	txs = append(txs, tx8)
-
-	return txs, nil
-}
-
 func (invoker *OntInvoker) SetupBtcx(btcx string, redeem, rk []byte, limit, gasPrice, gasLimit uint64) ([]common.Uint256, error) {
 	txs := make([]common.Uint256, 0)
 
@@ -394,26 +169,6 @@ func (invoker *OntInvoker) BindBtcx(btcx string, otherBtcx []byte, chainId int,
 	return tx, nil
 }
 
-func (invoker *OntInvoker) SetOtherLockProxy(other []byte, toChainId int) (common.Uint256, error) {
-	addr, err := utils.AddressFromHexString(config.DefConfig.OntLockProxy)
-	if err != nil {
-		return common.UINT256_EMPTY, err
-	}
-	txhash, err := invoker.OntSdk.NeoVM.InvokeNeoVMContract(config.DefConfig.GasPrice, config.DefConfig.GasLimit,
-		invoker.OntAcc,
-		invoker.OntAcc,
-		addr,
-		[]interface{}{"bindProxyHash", []interface{}{toChainId, other}})
-	if err != nil {
-		if !strings.Contains(err.Error(), "already") {
-			return common.UINT256_EMPTY, fmt.Errorf("bindProxyHash for chain-id %d error: %v", toChainId, err)
-		}
-		log.Warnf("proxy on chain-id %d is already binded: %v", toChainId, err)
-	}
-	invoker.WaitTxConfirmation(txhash)
-	return txhash, nil
-}
-
 func (invoker *OntInvoker) WaitTxConfirmation(tx common.Uint256) {
 	tick := time.NewTicker(time.Second)
 	for range tick.C {
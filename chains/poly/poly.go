@@ -0,0 +1,162 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package poly
+
+import (
+	"encoding/hex"
+	"fmt"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/polynetwork/poly-go-sdk"
+	"github.com/polynetwork/poly-io-test/chains/eth"
+	"github.com/polynetwork/poly-io-test/config"
+	"github.com/polynetwork/poly-io-test/log"
+	"github.com/polynetwork/poly/common"
+	peth "github.com/polynetwork/poly/native/service/cross_chain_manager/eth"
+	"strings"
+	"time"
+)
+
+// TxWait bounds how long WaitTx waits for a poly tx
+var TxWait = 300 * time.Second
+
+// notConfirmed is how poly rejects a cross chain tx whose height is above the
+// headers it synced minus the blocks to wait, for both bitcoin and ethereum
+const notConfirmed = "transaction is not confirmed, current height"
+
+// WaitTx waits until the poly tx txhash is in a block below the current
+// height, and panics if not in TxWait
+func WaitTx(txhash common.Uint256, sdk *poly_go_sdk.PolySdk) {
+	tick := time.NewTicker(100 * time.Millisecond)
+	defer tick.Stop()
+	start := time.Now()
+	for range tick.C {
+		h, _ := sdk.GetBlockHeightByTxHash(txhash.ToHexString())
+		curr, _ := sdk.GetCurrentBlockHeight()
+		if h > 0 && curr > h {
+			return
+		}
+		if time.Since(start) > TxWait {
+			panic(fmt.Errorf("tx( %s ) is not confirm for a long time ( over %v )", txhash.ToHexString(),
+				TxWait))
+		}
+	}
+}
+
+// PreExecImport pre-executes importing the cross chain tx raw from chain
+// chainId into poly, and returns the error of poly
+func PreExecImport(sdk *poly_go_sdk.PolySdk, acc *poly_go_sdk.Account, chainId uint64, raw []byte,
+	height uint32, proof []byte) error {
+	tx, err := sdk.Native.Ccm.NewImportOuterTransferTransaction(chainId, raw, height, proof, acc.Address[:],
+		[]byte{})
+	if err != nil {
+		return err
+	}
+	if err = sdk.SignToTransaction(tx, acc); err != nil {
+		return err
+	}
+	res, err := sdk.PreExecTransaction(tx)
+	if err != nil {
+		return err
+	}
+	if res.State == 0 {
+		return fmt.Errorf("failed without message")
+	}
+	return nil
+}
+
+// WaitImport pre-executes importing the cross chain tx made by tx until poly
+// has synced the headers to confirm it, and returns the error of poly at once
+// if it rejects the tx for any other reason. tx may fail while the tx is not
+// ready on its chain. It fails if poly is still not synced after timeout.
+func WaitImport(sdk *poly_go_sdk.PolySdk, acc *poly_go_sdk.Account, chainId uint64, timeout time.Duration,
+	tx func() (raw []byte, height uint32, proof []byte, err error)) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		raw, height, proof, err := tx()
+		if err == nil {
+			err = PreExecImport(sdk, acc, chainId, raw, height, proof)
+			if err == nil || !strings.Contains(err.Error(), notConfirmed) {
+				return err
+			}
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("poly not synced for the tx in %v: %v", timeout, err)
+		}
+		log.Infof("waiting for poly to sync headers of chain %d: %v", chainId, err)
+		time.Sleep(10 * time.Second)
+	}
+}
+
+// ethImport returns the cross chain event of the ethereum tx txHash through
+// the ECCM eccm, and the func making its import with the proof from ECCD
+func ethImport(ei *eth.EInvoker, eccm string, txHash ethcommon.Hash) (*eth.LockEvent,
+	func() ([]byte, uint32, []byte, error), error) {
+	lock, err := ei.CrossChainEvent(eccm, txHash)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := peth.MappingKeyAt(hex.EncodeToString(lock.Txid), "01")
+	if err != nil {
+		return nil, nil, err
+	}
+	return lock, func() ([]byte, uint32, []byte, error) {
+		proof, err := ei.ETHUtil.GetProof(config.DefConfig.Eccd, "0x"+hex.EncodeToString(key), lock.Height)
+		return lock.Value, uint32(lock.Height), proof, err
+	}, nil
+}
+
+// PreExecEthImport pre-executes importing the cross chain tx of ethereum tx
+// txHash into poly with its proof from ECCD, waiting for poly to sync its
+// header in timeout, and returns the error of poly
+func PreExecEthImport(ei *eth.EInvoker, sdk *poly_go_sdk.PolySdk, acc *poly_go_sdk.Account,
+	txHash ethcommon.Hash, timeout time.Duration) error {
+	_, tx, err := ethImport(ei, config.DefConfig.Eccm, txHash)
+	if err != nil {
+		return err
+	}
+	return WaitImport(sdk, acc, config.ETH_CHAIN_ID, timeout, tx)
+}
+
+// ImportEth imports the cross chain tx of ethereum tx txHash through the ECCM
+// eccm into poly once poly synced its header in timeout, like a relayer does
+// for the ECCM it watches, and returns the cross chain event after the import
+// is in a poly block
+func ImportEth(ei *eth.EInvoker, sdk *poly_go_sdk.PolySdk, acc *poly_go_sdk.Account, eccm string,
+	txHash ethcommon.Hash, timeout time.Duration) (*eth.LockEvent, error) {
+	lock, tx, err := ethImport(ei, eccm, txHash)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		raw, proof []byte
+		height     uint32
+	)
+	err = WaitImport(sdk, acc, config.ETH_CHAIN_ID, timeout, func() ([]byte, uint32, []byte, error) {
+		raw, height, proof, err = tx()
+		return raw, height, proof, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	txhash, err := sdk.Native.Ccm.ImportOuterTransfer(config.ETH_CHAIN_ID, raw, height, proof, acc.Address[:],
+		[]byte{}, acc)
+	if err != nil {
+		return nil, err
+	}
+	WaitTx(txhash, sdk)
+	return lock, nil
+}
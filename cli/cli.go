@@ -0,0 +1,182 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	ExitOk    = 0
+	ExitError = 1
+	ExitUsage = 2
+)
+
+// Globals are the flags shared by every command
+type Globals struct {
+	Conf     string
+	Format   string
+	LogLevel int
+}
+
+func (g *Globals) register(fs *flag.FlagSet) {
+	fs.StringVar(&g.Conf, "conf", g.Conf, "config file path")
+	fs.StringVar(&g.Format, "format", g.Format, "output format: text or json")
+	fs.IntVar(&g.LogLevel, "log_level", g.LogLevel, "log level: 0 trace, 1 debug, 2 info, 3 warn, 4 error")
+}
+
+// Command is a node of the command tree. A command with Subs dispatches to
+// one of them, otherwise it calls Run.
+type Command struct {
+	Name  string
+	Usage string
+	// Flags binds the flags of this command, optional
+	Flags func(fs *flag.FlagSet)
+	Run   func(g *Globals) error
+	Subs  []*Command
+	// Before is called before Run of any command under it, only used on the root
+	Before func(g *Globals) error
+	// Offline commands skip Before, e.g. signing on a machine without config
+	Offline bool
+}
+
+// UsageError means the command line is wrong rather than the command failed
+type UsageError struct {
+	Msg string
+}
+
+func (err *UsageError) Error() string {
+	return err.Msg
+}
+
+func (c *Command) find(name string) *Command {
+	for _, s := range c.Subs {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+func (c *Command) usage(w io.Writer, path string, fs *flag.FlagSet) {
+	if len(c.Subs) > 0 {
+		fmt.Fprintf(w, "usage: %s <command> [flags]\n", path)
+	} else {
+		fmt.Fprintf(w, "usage: %s [flags]\n", path)
+	}
+	if c.Usage != "" {
+		fmt.Fprintf(w, "\n%s\n", c.Usage)
+	}
+	if len(c.Subs) > 0 {
+		fmt.Fprintln(w, "\ncommands:")
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		for _, s := range c.Subs {
+			fmt.Fprintf(tw, "  %s\t%s\n", s.Name, s.Usage)
+		}
+		tw.Flush()
+		fmt.Fprintf(w, "\nrun '%s help <command>' for more about a command\n", path)
+	}
+	fmt.Fprintln(w, "\nflags:")
+	fs.SetOutput(w)
+	fs.PrintDefaults()
+}
+
+func (c *Command) execute(path string, g *Globals, args []string, before func(g *Globals) error) error {
+	fs := flag.NewFlagSet(path, flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	g.register(fs)
+	if c.Flags != nil {
+		c.Flags(fs)
+	}
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			c.usage(os.Stdout, path, fs)
+			return err
+		}
+		return c.fail(path, fs, err.Error())
+	}
+	rest := fs.Args()
+	if len(c.Subs) == 0 {
+		if len(rest) > 0 {
+			return c.fail(path, fs, fmt.Sprintf("unexpected arguments: %s", strings.Join(rest, " ")))
+		}
+		if before != nil && !c.Offline {
+			if err := before(g); err != nil {
+				return err
+			}
+		}
+		return c.Run(g)
+	}
+	if len(rest) == 0 {
+		return c.fail(path, fs, "no command given")
+	}
+	if rest[0] == "help" {
+		return c.help(path, g, rest[1:])
+	}
+	sub := c.find(rest[0])
+	if sub == nil {
+		return c.fail(path, fs, fmt.Sprintf("unknown command '%s'", rest[0]))
+	}
+	return sub.execute(path+" "+sub.Name, g, rest[1:], before)
+}
+
+func (c *Command) help(path string, g *Globals, names []string) error {
+	for _, name := range names {
+		sub := c.find(name)
+		if sub == nil {
+			msg := fmt.Sprintf("%s: unknown command '%s'", path, name)
+			fmt.Fprintln(os.Stderr, msg)
+			return &UsageError{msg}
+		}
+		c, path = sub, path+" "+name
+	}
+	fs := flag.NewFlagSet(path, flag.ContinueOnError)
+	g.register(fs)
+	if c.Flags != nil {
+		c.Flags(fs)
+	}
+	c.usage(os.Stdout, path, fs)
+	return flag.ErrHelp
+}
+
+func (c *Command) fail(path string, fs *flag.FlagSet, msg string) error {
+	fmt.Fprintf(os.Stderr, "%s: %s\n\n", path, msg)
+	c.usage(os.Stderr, path, fs)
+	return &UsageError{fmt.Sprintf("%s: %s", path, msg)}
+}
+
+// Main runs the command tree with args and returns the exit code:
+// ExitUsage for a wrong command line and ExitError if the command failed
+func Main(root *Command, g *Globals, args []string) int {
+	err := root.execute(root.Name, g, args, root.Before)
+	if err == nil || err == flag.ErrHelp {
+		return ExitOk
+	}
+	var uerr *UsageError
+	if errors.As(err, &uerr) {
+		return ExitUsage
+	}
+	fmt.Fprintf(os.Stderr, "%s: %v\n", root.Name, err)
+	return ExitError
+}
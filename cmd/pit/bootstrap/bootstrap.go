@@ -0,0 +1,252 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package bootstrap
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/polynetwork/poly-io-test/cli"
+	"github.com/polynetwork/poly-io-test/cmd/pit/btc_prepare"
+	"github.com/polynetwork/poly-io-test/cmd/pit/cosmos_prepare"
+	"github.com/polynetwork/poly-io-test/cmd/pit/eth_deployer"
+	"github.com/polynetwork/poly-io-test/cmd/pit/ont_deployer"
+	"github.com/polynetwork/poly-io-test/cmd/pit/tools"
+	"github.com/polynetwork/poly-io-test/log"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+const (
+	StepDeployEth         = "deploy-eth"
+	StepDeployOnt         = "deploy-ont"
+	StepRegisterSideChain = "register-side-chain"
+	StepSyncGenesisHeader = "sync-genesis-header"
+	StepDeployBtc         = "deploy-btc"
+	StepSetupCosmos       = "setup-cosmos"
+	StepSetupEth          = "setup-eth"
+	StepSetupOnt          = "setup-ont"
+)
+
+var (
+	stateFile  string
+	fresh      bool
+	listOnly   bool
+	vendorFrom string
+	pWallets   string
+	pPwds      string
+)
+
+// Flags binds the flags of bootstrap
+func Flags(fs *flag.FlagSet) {
+	fs.StringVar(&stateFile, "state", "./bootstrap_state.json", "file recording finished bootstrap steps, used to resume")
+	fs.BoolVar(&fresh, "fresh", false, "ignore the recorded steps and run all of them again")
+	fs.BoolVar(&listOnly, "list", false, "only print the steps in order and whether they are going to run")
+	fs.StringVar(&vendorFrom, "vendor", "new", "setup btc env from existing vendor or new one: new or existing")
+	fs.StringVar(&pWallets, "pwallets", "", "poly wallet files sep by ',', used to approve side chains")
+	fs.StringVar(&pPwds, "ppwds", "", "poly pwd for every wallet, sep by ','")
+}
+
+// State records the finished steps of bootstrap
+type State struct {
+	Done map[string]bool `json:"done"`
+}
+
+func LoadState(file string) (*State, error) {
+	state := &State{Done: make(map[string]bool)}
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return state, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read bootstrap state %s: %v", file, err)
+	}
+	if err = json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal bootstrap state %s: %v", file, err)
+	}
+	if state.Done == nil {
+		state.Done = make(map[string]bool)
+	}
+	return state, nil
+}
+
+func (state *State) Save(file string) error {
+	data, err := json.MarshalIndent(state, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(file, data, 0644); err != nil {
+		return fmt.Errorf("failed to write bootstrap state: %v", err)
+	}
+	return nil
+}
+
+type step struct {
+	name string
+	// steps must be finished before this one. If any of them runs again,
+	// this step runs again too.
+	deps []string
+	// flags and args are parsed before run, so the command behaves like
+	// it is called from the command line
+	flags func(fs *flag.FlagSet)
+	args  func() []string
+	run   func(g *cli.Globals) error
+}
+
+func noArgs() []string {
+	return nil
+}
+
+func toolArgs() []string {
+	return []string{"-pwallets", pWallets, "-ppwds", pPwds}
+}
+
+func steps() []*step {
+	return []*step{
+		{StepDeployEth, nil, eth_deployer.Flags, noArgs, eth_deployer.Deploy},
+		{StepDeployOnt, nil, ont_deployer.Flags, noArgs, ont_deployer.Deploy},
+		{StepRegisterSideChain, []string{StepDeployEth}, tools.Flags, toolArgs,
+			func(g *cli.Globals) error { return tools.Run(g, "register_side_chain") }},
+		{StepSyncGenesisHeader, []string{StepRegisterSideChain, StepDeployOnt}, tools.Flags, toolArgs,
+			func(g *cli.Globals) error { return tools.Run(g, "sync_genesis_header") }},
+		{StepDeployBtc, []string{StepDeployEth, StepDeployOnt, StepRegisterSideChain}, btc_prepare.Flags,
+			func() []string { return []string{"-vendor", vendorFrom} }, btc_prepare.Deploy},
+		{StepSetupCosmos, []string{StepDeployEth, StepDeployOnt, StepDeployBtc}, cosmos_prepare.Flags, noArgs,
+			cosmos_prepare.Setup},
+		{StepSetupEth, []string{StepDeployOnt, StepSetupCosmos}, eth_deployer.Flags, noArgs, eth_deployer.Setup},
+		{StepSetupOnt, []string{StepDeployEth, StepSetupCosmos}, ont_deployer.Flags, noArgs, ont_deployer.Setup},
+	}
+}
+
+// sortSteps orders steps so that every step comes after its deps, keeping
+// the declared order where it is free to choose
+func sortSteps(all []*step) ([]*step, error) {
+	byName := make(map[string]*step, len(all))
+	for _, s := range all {
+		byName[s.name] = s
+	}
+	for _, s := range all {
+		for _, dep := range s.deps {
+			if _, ok := byName[dep]; !ok {
+				return nil, fmt.Errorf("step %s depends on unknown step %s", s.name, dep)
+			}
+		}
+	}
+	sorted := make([]*step, 0, len(all))
+	placed := make(map[string]bool, len(all))
+	for len(sorted) < len(all) {
+		progress := false
+		for _, s := range all {
+			if placed[s.name] {
+				continue
+			}
+			ready := true
+			for _, dep := range s.deps {
+				if !placed[dep] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				sorted = append(sorted, s)
+				placed[s.name] = true
+				progress = true
+			}
+		}
+		if !progress {
+			left := make([]string, 0)
+			for _, s := range all {
+				if !placed[s.name] {
+					left = append(left, s.name)
+				}
+			}
+			return nil, fmt.Errorf("cycle in steps: %s", strings.Join(left, ", "))
+		}
+	}
+	return sorted, nil
+}
+
+func (s *step) exec(g *cli.Globals) (err error) {
+	// the commands still panic on failure
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	fs := flag.NewFlagSet(s.name, flag.ContinueOnError)
+	s.flags(fs)
+	if err = fs.Parse(s.args()); err != nil {
+		return err
+	}
+	return s.run(g)
+}
+
+// Run runs all steps of bootstrap in order, skipping the ones finished before
+func Run(g *cli.Globals) error {
+	sorted, err := sortSteps(steps())
+	if err != nil {
+		return err
+	}
+	state, err := LoadState(stateFile)
+	if err != nil {
+		return err
+	}
+	if fresh {
+		state = &State{Done: make(map[string]bool)}
+	}
+
+	rerun := make(map[string]bool)
+	for _, s := range sorted {
+		rerun[s.name] = !state.Done[s.name]
+		for _, dep := range s.deps {
+			if rerun[dep] {
+				rerun[s.name] = true
+			}
+		}
+	}
+	if listOnly {
+		for i, s := range sorted {
+			status := "done"
+			if rerun[s.name] {
+				status = "pending"
+			}
+			fmt.Printf("%d. %s [%s] after: %s\n", i+1, s.name, status, strings.Join(s.deps, ", "))
+		}
+		return nil
+	}
+
+	for _, s := range sorted {
+		if !rerun[s.name] {
+			log.Infof("bootstrap: step %s already done, skip it", s.name)
+			continue
+		}
+		log.Infof("bootstrap: running step %s", s.name)
+		state.Done[s.name] = false
+		if err := s.exec(g); err != nil {
+			if serr := state.Save(stateFile); serr != nil {
+				log.Errorf("bootstrap: %v", serr)
+			}
+			return fmt.Errorf("step %s failed: %v, fix it and run again to resume", s.name, err)
+		}
+		state.Done[s.name] = true
+		if err := state.Save(stateFile); err != nil {
+			return err
+		}
+	}
+	log.Info("bootstrap: all steps done")
+	return nil
+}
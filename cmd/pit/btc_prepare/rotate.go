@@ -0,0 +1,489 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package btc_prepare
+
+import (
+	"bytes"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/polynetwork/poly-go-sdk"
+	"github.com/polynetwork/poly-io-test/chains/btc"
+	"github.com/polynetwork/poly-io-test/chains/cosmos"
+	"github.com/polynetwork/poly-io-test/chains/eth"
+	btcx_abi "github.com/polynetwork/poly-io-test/chains/eth/abi/btcx"
+	"github.com/polynetwork/poly-io-test/chains/ont"
+	"github.com/polynetwork/poly-io-test/chains/poly"
+	"github.com/polynetwork/poly-io-test/cli"
+	"github.com/polynetwork/poly-io-test/config"
+	"github.com/polynetwork/poly-io-test/log"
+	pcommon "github.com/polynetwork/poly/common"
+	pbtc "github.com/polynetwork/poly/native/service/cross_chain_manager/btc"
+	"github.com/polynetwork/poly/native/service/utils"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// nullBtcx is bound with the retired redeem so that poly rejects unlocks naming it
+const nullBtcx = "0000000000000000000000000000000000000000"
+
+var (
+	rotateKeyFile  string
+	rotateFeeRate  uint64
+	rotateGenerate int
+	rotateImport   bool
+	rotateWait     int
+)
+
+// RotateFlags binds the flags of rotating the vendor
+func RotateFlags(fs *flag.FlagSet) {
+	fs.StringVar(&rotateKeyFile, "key_file", "", "file to save encrypted keys of the new vendor, "+
+		"BtcEncryptedPrivateKeyFile with the redeem key appended if empty")
+	fs.Uint64Var(&rotateFeeRate, "fee_rate", 0, "fee rate in sat/vbyte moving the utxos, "+
+		"BtcSendFeeRate or the node estimation if 0")
+	fs.IntVar(&rotateGenerate, "generate", 1, "blocks to mine for confirming the move, only on regtest")
+	fs.BoolVar(&rotateImport, "import", false, "import the old multisig addresses into the node first, "+
+		"which rescans the chain")
+	fs.IntVar(&rotateWait, "wait", 3600, "seconds to wait for poly to sync the header of the move, "+
+		"and for its btcx minted on ethereum")
+}
+
+// currentVendor returns the vendor of BtcRedeem, with keys from BtcExistingVendorPrivks if set or
+// the files under BtcEncryptedPrivateKeyFile
+func currentVendor() (*btc.Vendor, []*btc.VendorMember, error) {
+	redeem, err := hex.DecodeString(config.DefConfig.BtcRedeem)
+	if err != nil || len(redeem) == 0 {
+		return nil, nil, fmt.Errorf("wrong BtcRedeem %s: %v", config.DefConfig.BtcRedeem, err)
+	}
+	if config.DefConfig.BtcExistingVendorPrivks == "" {
+		members, err := btc.LoadVendorMembers(config.DefConfig.BtcEncryptedPrivateKeyFile,
+			config.DefConfig.BtcEncryptedPrivateKeyPwd, redeem, config.BtcNet)
+		if err != nil {
+			return nil, nil, err
+		}
+		v, err := btc.NewVendorFromMembers(redeem, members)
+		return v, members, err
+	}
+	v, err := btc.NewVendorFromConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	if !bytes.Equal(v.Redeem, redeem) {
+		return nil, nil, fmt.Errorf("BtcExistingVendorPrivks is not the vendor of BtcRedeem")
+	}
+	members := make([]*btc.VendorMember, len(v.PrivateKeys))
+	for i, wif := range v.PrivateKeys {
+		members[i] = &btc.VendorMember{Privk: wif.PrivKey, Addr: v.AddressSet[i]}
+	}
+	return v, members, nil
+}
+
+// btcxBinding is a btcx bound with the redeem of the vendor on poly
+type btcxBinding struct {
+	name    string
+	chainId uint64
+	btcx    string
+	// bind makes the btcx contract take unlocks from the redeem of key
+	bind func(key []byte) error
+}
+
+// boundBtcx returns the btcx bound with the old redeem on poly, which must be the ones in config.
+// Btcx on ethereum must be bound, since the utxos move through it.
+func boundBtcx(invoker *btc.BtcInvoker, ei *eth.EInvoker, old *btc.Vendor) ([]*btcxBinding, error) {
+	all := []*btcxBinding{
+		{name: "ethereum", chainId: config.ETH_CHAIN_ID, btcx: config.DefConfig.BtceContractAddress,
+			bind: func(key []byte) error {
+				contract, err := btcx_abi.NewBTCX(common.HexToAddress(config.DefConfig.BtceContractAddress),
+					ei.ETHUtil.GetEthClient())
+				if err != nil {
+					return err
+				}
+				auth, err := ei.MakeSmartContractAuth()
+				if err != nil {
+					return err
+				}
+				tx, err := contract.BindAssetHash(auth, config.BTC_CHAIN_ID, key)
+				if err != nil {
+					return err
+				}
+				ei.ETHUtil.WaitTransactionConfirm(tx.Hash())
+				return nil
+			}},
+		{name: "ontology", chainId: config.ONT_CHAIN_ID, btcx: config.DefConfig.BtcoContractAddress,
+			bind: func(key []byte) error {
+				oi, err := ont.NewOntInvoker(config.DefConfig.OntJsonRpcAddress,
+					config.DefConfig.OntContractsAvmPath, config.DefConfig.OntWallet,
+					config.DefConfig.OntWalletPassword)
+				if err != nil {
+					return err
+				}
+				tx, err := oi.BindBtcx(config.DefConfig.BtcoContractAddress, key, int(config.BTC_CHAIN_ID),
+					config.DefConfig.GasPrice, config.DefConfig.GasLimit)
+				if err != nil {
+					return err
+				}
+				oi.WaitTxConfirmation(tx)
+				return nil
+			}},
+	}
+	if config.DefConfig.CMCrossChainId != 0 {
+		all = append(all, &btcxBinding{name: "cosmos", chainId: config.DefConfig.CMCrossChainId,
+			btcx: config.CM_BTCX, bind: func(key []byte) error {
+				cm, err := cosmos.NewCosmosInvoker()
+				if err != nil {
+					return err
+				}
+				res, err := cm.BtcxBindAsset(config.CM_BTCX, config.BTC_CHAIN_ID, key)
+				if err != nil {
+					return err
+				}
+				cm.WaitTx(res.Hash)
+				return nil
+			}})
+	}
+	bound := make([]*btcxBinding, 0, len(all))
+	for _, b := range all {
+		c, err := invoker.ContractBinded(old.HashKey, b.chainId)
+		if err != nil {
+			return nil, err
+		}
+		null, _ := btc.BtcxBytes(nullBtcx, b.chainId)
+		if c == nil || bytes.Equal(c.Contract, null) {
+			if b.chainId == config.ETH_CHAIN_ID {
+				return nil, fmt.Errorf("old redeem is not bound with btcx on ethereum")
+			}
+			continue
+		}
+		expected, err := btc.BtcxBytes(b.btcx, b.chainId)
+		if b.btcx == "" || err != nil || !bytes.Equal(c.Contract, expected) {
+			return nil, fmt.Errorf("old redeem is bound with btcx %x on %s, but %q is in config", c.Contract,
+				b.name, b.btcx)
+		}
+		bound = append(bound, b)
+	}
+	return bound, nil
+}
+
+// checkRebound checks on poly that the new redeem is bound with every btcx and the old one with
+// no btcx, and on ethereum that btcx takes unlocks from the new redeem only
+func checkRebound(invoker *btc.BtcInvoker, ei *eth.EInvoker, bound []*btcxBinding, old, vendor *btc.Vendor) error {
+	for _, b := range bound {
+		expected, _ := btc.BtcxBytes(b.btcx, b.chainId)
+		null, _ := btc.BtcxBytes(nullBtcx, b.chainId)
+		c, err := invoker.ContractBinded(vendor.HashKey, b.chainId)
+		if err != nil {
+			return err
+		}
+		if c == nil || !bytes.Equal(c.Contract, expected) {
+			return fmt.Errorf("new redeem is not bound with btcx on %s on poly", b.name)
+		}
+		if c, err = invoker.ContractBinded(old.HashKey, b.chainId); err != nil {
+			return err
+		}
+		if c == nil || !bytes.Equal(c.Contract, null) {
+			return fmt.Errorf("old redeem is still bound with btcx on %s on poly", b.name)
+		}
+	}
+	contract, err := btcx_abi.NewBTCX(common.HexToAddress(config.DefConfig.BtceContractAddress),
+		ei.ETHUtil.GetEthClient())
+	if err != nil {
+		return err
+	}
+	key, err := contract.BondAssetHashes(&bind.CallOpts{}, config.BTC_CHAIN_ID)
+	if err != nil {
+		return fmt.Errorf("failed to get redeem key bound on btcx on ethereum: %v", err)
+	}
+	if !bytes.Equal(key, vendor.HashKey) {
+		return fmt.Errorf("btcx on ethereum takes unlocks from redeem key %x", key)
+	}
+	return nil
+}
+
+// moveUtxos sends all utxos of the old multisig to the new one with the cross chain data, and returns
+// the tx, nil if no utxos
+func moveUtxos(cli btc.BtcClient, old *btc.Vendor, members []*btc.VendorMember, to btcutil.Address,
+	data []byte) (*wire.MsgTx, error) {
+	utxos := make([]*btc.Utxo, 0)
+	for _, addr := range []btcutil.Address{old.P2shAddr, old.P2wshAddr} {
+		if rotateImport {
+			if err := cli.ImportAddress(addr.EncodeAddress()); err != nil {
+				return nil, fmt.Errorf("failed to import %s: %v", addr.EncodeAddress(), err)
+			}
+		}
+		res, err := cli.ListUnspent(0, math.MaxInt32, addr.EncodeAddress())
+		if err != nil {
+			return nil, fmt.Errorf("failed to list utxos of %s: %v", addr.EncodeAddress(), err)
+		}
+		utxos = append(utxos, res...)
+	}
+	if len(utxos) == 0 {
+		return nil, nil
+	}
+	rate := rotateFeeRate
+	if rate == 0 {
+		rate = config.DefConfig.BtcSendFeeRate
+	}
+	tx, err := btc.SweepMultisig(utxos, old.Redeem, members, to, data, btc.FeeRate(cli, rate), config.BtcNet)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err = tx.BtcEncode(&buf, wire.ProtocolVersion, wire.LatestEncoding); err != nil {
+		return nil, err
+	}
+	txid, err := cli.SendRawTx(hex.EncodeToString(buf.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+	if config.DefConfig.BtcNetType == "regtest" && rotateGenerate > 0 {
+		if _, err = cli.GenerateToAddr(rotateGenerate, to.EncodeAddress()); err != nil {
+			return nil, fmt.Errorf("tx %s sent but failed to mine: %v", txid, err)
+		}
+	}
+	return tx, nil
+}
+
+// relayMove imports the move into poly as a deposit to the new multisig, so that poly adds its output
+// to the utxos of the new redeem, and mints the same btcx on ethereum for burnMoved
+func relayMove(invoker *btc.BtcInvoker, tx *wire.MsgTx) error {
+	var buf bytes.Buffer
+	if err := tx.BtcEncode(&buf, wire.ProtocolVersion, wire.LatestEncoding); err != nil {
+		return err
+	}
+	txid := tx.TxHash()
+	var height uint32
+	var proof []byte
+	wait := time.Duration(rotateWait) * time.Second
+	err := poly.WaitImport(invoker.RChain, invoker.RChainAcc, config.BTC_CHAIN_ID, wait, func() ([]byte, uint32,
+		[]byte, error) {
+		confs, err := invoker.BtcCli.GetTxConfs(txid.String())
+		if err != nil || confs == 0 {
+			return nil, 0, nil, fmt.Errorf("tx is not confirmed on bitcoin: %v", err)
+		}
+		tip, err := invoker.BtcCli.GetBlockCount()
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		p, err := invoker.BtcCli.GetProof([]string{txid.String()})
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		height = uint32(tip - confs + 1)
+		proof, _ = hex.DecodeString(p)
+		return buf.Bytes(), height, proof, nil
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "check done transaction") {
+			log.Infof("move %s is already relayed", txid.String())
+			return nil
+		}
+		return err
+	}
+	txhash, err := invoker.RChain.Native.Ccm.ImportOuterTransfer(config.BTC_CHAIN_ID, buf.Bytes(), height, proof,
+		invoker.RChainAcc.Address[:], []byte{}, invoker.RChainAcc)
+	if err != nil {
+		return err
+	}
+	poly.WaitTx(txhash, invoker.RChain)
+	return nil
+}
+
+// burnMoved waits until the btcx minted on ethereum for the move of amount reaches the signer, whose
+// balance was before, and burns it, so that the supply of btcx stays the value of the multisig
+func burnMoved(ei *eth.EInvoker, contract *btcx_abi.BTCX, before *big.Int, amount int64) error {
+	minted := new(big.Int).Add(before, big.NewInt(amount))
+	deadline := time.Now().Add(time.Duration(rotateWait) * time.Second)
+	for {
+		bal, err := contract.BalanceOf(&bind.CallOpts{}, ei.EthTestSigner.Address)
+		if err == nil && bal.Cmp(minted) >= 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("btcx of the move not minted on ethereum in %d seconds, balance: %v, err: %v",
+				rotateWait, bal, err)
+		}
+		log.Infof("waiting for the btcx of the move minted on ethereum")
+		time.Sleep(10 * time.Second)
+	}
+	auth, err := ei.MakeSmartContractAuth()
+	if err != nil {
+		return err
+	}
+	tx, err := contract.Burn(auth, big.NewInt(amount))
+	if err != nil {
+		return fmt.Errorf("failed to burn: %v", err)
+	}
+	ei.ETHUtil.WaitTransactionConfirm(tx.Hash())
+	return nil
+}
+
+// polyUtxos returns the utxos of the multisig of redeem key on poly
+func polyUtxos(poly *poly_go_sdk.PolySdk, redeemKey []byte) (*pbtc.Utxos, error) {
+	raw, err := poly.GetStorage(utils.CrossChainManagerContractAddress.ToHexString(),
+		append(append([]byte(pbtc.UTXOS), utils.GetUint64Bytes(config.BTC_CHAIN_ID)...),
+			[]byte(hex.EncodeToString(redeemKey))...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get utxos from poly: %v", err)
+	}
+	utxos := &pbtc.Utxos{Utxos: make([]*pbtc.Utxo, 0)}
+	if len(raw) == 0 {
+		return utxos, nil
+	}
+	if err = utxos.Deserialization(pcommon.NewZeroCopySource(raw)); err != nil {
+		return nil, fmt.Errorf("failed to deserialize utxos: %v", err)
+	}
+	return utxos, nil
+}
+
+// Rotate replaces the vendor of BtcRedeem with a new one. The btcx bound with the old redeem on
+// poly are bound with the new one with a bumped version, and take unlocks from it on their chains.
+// The btc tx param is set for the new redeem, and the utxos of the old multisig move to the new one
+// by a deposit relayed to poly, whose btcx minted on ethereum is burnt again. At last the old redeem
+// is bound with no btcx, and the bindings are checked on poly and ethereum.
+func Rotate(g *cli.Globals) error {
+	invoker, err := btc.NewBtcInvoker(config.DefConfig.RchainJsonRpcAddress, config.DefConfig.RCWallet,
+		config.DefConfig.RCWalletPwd, config.DefConfig.BtcRestAddr, config.DefConfig.BtcRestUser,
+		config.DefConfig.BtcRestPwd, config.DefConfig.BtcSignerPrivateKey)
+	if err != nil {
+		return fmt.Errorf("failed to new btc invoker: %v", err)
+	}
+	old, members, err := currentVendor()
+	if err != nil {
+		return fmt.Errorf("failed to load current vendor: %v", err)
+	}
+	ei := eth.NewEInvoker()
+	bound, err := boundBtcx(invoker, ei, old)
+	if err != nil {
+		return fmt.Errorf("nothing changed, vendor can not rotate: %v", err)
+	}
+	btce, err := btcx_abi.NewBTCX(common.HexToAddress(config.DefConfig.BtceContractAddress),
+		ei.ETHUtil.GetEthClient())
+	if err != nil {
+		return err
+	}
+	data, err := btc.BuildData(config.ETH_CHAIN_ID, 0, ei.EthTestSigner.Address.Hex())
+	if err != nil {
+		return err
+	}
+
+	vendor, err := invoker.GenerateVendor(config.DefConfig.BtcMultiSigNum, config.DefConfig.BtcMultiSigRequire)
+	if err != nil {
+		return fmt.Errorf("failed to new a vendor: %v", err)
+	}
+	keyFile := rotateKeyFile
+	if keyFile == "" {
+		keyFile = config.DefConfig.BtcEncryptedPrivateKeyFile + "_" + hex.EncodeToString(vendor.HashKey)
+	}
+
+	info := fmt.Sprintf("=============================== rotate vendor ===============================\n"+
+		"old multisig-address: { p2sh: %s, p2wsh: %s }\nold redeem-key: %s\n"+
+		"new multisig-address: { p2sh: %s, p2wsh: %s }\nnew redeem-script: %s\nnew redeem-key: %s\n",
+		old.P2shAddr.EncodeAddress(), old.P2wshAddr.EncodeAddress(), hex.EncodeToString(old.HashKey),
+		vendor.P2shAddr.EncodeAddress(), vendor.P2wshAddr.EncodeAddress(), hex.EncodeToString(vendor.Redeem),
+		hex.EncodeToString(vendor.HashKey))
+	info += saveVendorKeys(vendor, keyFile)
+
+	for _, b := range bound {
+		txhash, err := invoker.BindBtcxWithVendor(b.btcx, b.chainId, vendor)
+		if err != nil {
+			return fmt.Errorf("failed to bind btcx on %s with new redeem: %v", b.name, err)
+		}
+		poly.WaitTx(txhash, invoker.RChain)
+		if err = b.bind(vendor.HashKey); err != nil {
+			return fmt.Errorf("failed to bind new redeem key on btcx on %s: %v", b.name, err)
+		}
+		info += fmt.Sprintf("bind btcx %s on %s with new redeem txhash: %s\n", b.btcx, b.name,
+			txhash.ToHexString())
+	}
+
+	txhash, err := invoker.BindBtcTxParam(config.DefConfig.BtcFeeRate, config.DefConfig.BtcMinChange, vendor)
+	if err != nil {
+		return fmt.Errorf("failed to bind tx param: %v", err)
+	}
+	poly.WaitTx(txhash, invoker.RChain)
+	info += fmt.Sprintf("bind tx param txhash: %s\n", txhash.ToHexString())
+
+	var to btcutil.Address = vendor.P2shAddr
+	if config.DefConfig.BtcLockToP2wsh {
+		to = vendor.P2wshAddr
+	}
+	before, err := btce.BalanceOf(&bind.CallOpts{}, ei.EthTestSigner.Address)
+	if err != nil {
+		return fmt.Errorf("failed to get btcx balance on ethereum: %v", err)
+	}
+	tx, err := moveUtxos(invoker.BtcCli, old, members, to, data)
+	if err != nil {
+		return fmt.Errorf("failed to move utxos to %s: %v", to.EncodeAddress(), err)
+	}
+	if tx != nil {
+		txid := tx.TxHash()
+		if err = relayMove(invoker, tx); err != nil {
+			return fmt.Errorf("move %s is sent but not relayed to poly: %v", txid.String(), err)
+		}
+		utxos, err := polyUtxos(invoker.RChain, vendor.HashKey)
+		if err != nil {
+			return err
+		}
+		found := false
+		for _, u := range utxos.Utxos {
+			found = found || bytes.Equal(u.Op.Hash, txid[:]) && u.Op.Index == 0
+		}
+		if !found {
+			return fmt.Errorf("move %s is relayed but not in utxos of new redeem on poly", txid.String())
+		}
+		if err = burnMoved(ei, btce, before, tx.TxOut[0].Value); err != nil {
+			return fmt.Errorf("move %s is relayed but: %v", txid.String(), err)
+		}
+		info += fmt.Sprintf("move utxos to %s txid: %s, %d satoshi, btcx minted for it burnt\n",
+			to.EncodeAddress(), txid.String(), tx.TxOut[0].Value)
+	}
+
+	for _, b := range bound {
+		txhash, err = invoker.BindBtcxWithVendor(nullBtcx, b.chainId, old)
+		if err != nil {
+			return fmt.Errorf("failed to unbind old redeem on %s: %v", b.name, err)
+		}
+		poly.WaitTx(txhash, invoker.RChain)
+		info += fmt.Sprintf("unbind old redeem on %s txhash: %s\n", b.name, txhash.ToHexString())
+	}
+	if err = checkRebound(invoker, ei, bound, old, vendor); err != nil {
+		return fmt.Errorf("rotated but: %v", err)
+	}
+	info += "verified: btcx is bound with the new redeem only\n"
+	info += "============================================================================\n"
+	fmt.Println(info)
+
+	config.DefConfig.BtcRedeem = hex.EncodeToString(vendor.Redeem)
+	config.DefConfig.BtcEncryptedPrivateKeyFile = keyFile
+	if config.DefConfig.BtcExistingVendorPrivks != "" {
+		wifs := make([]string, len(vendor.PrivateKeys))
+		for i, wif := range vendor.PrivateKeys {
+			wifs[i] = wif.String()
+		}
+		config.DefConfig.BtcExistingVendorPrivks = strings.Join(wifs, ",")
+	}
+	if err = config.DefConfig.Save(g.Conf); err != nil {
+		return fmt.Errorf("failed to save config: %v", err)
+	}
+	return nil
+}
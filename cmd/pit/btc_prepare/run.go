@@ -14,7 +14,7 @@
 * You should have received a copy of the GNU Lesser General Public License
 * along with The poly network . If not, see <http://www.gnu.org/licenses/>.
  */
-package main
+package btc_prepare
 
 import (
 	"encoding/hex"
@@ -28,62 +28,44 @@ import (
 	"github.com/polynetwork/poly-io-test/chains/eth"
 	btcx_abi "github.com/polynetwork/poly-io-test/chains/eth/abi/btcx"
 	"github.com/polynetwork/poly-io-test/chains/ont"
+	"github.com/polynetwork/poly-io-test/cli"
 	"github.com/polynetwork/poly-io-test/config"
 	"github.com/polynetwork/poly-io-test/testcase"
 	"io/ioutil"
 	"path"
 )
 
-var (
-	btcFunc     string
-	btcConfFile string
-)
+var vendorFrom string
 
-func init() {
-	flag.StringVar(&btcFunc, "func", "new", "setup btc env from existing vendor or new one")
-	flag.StringVar(&btcConfFile, "conf", "./config.json", "config file path")
-	flag.Parse()
+// Flags binds the flags of deploy on bitcoin
+func Flags(fs *flag.FlagSet) {
+	fs.StringVar(&vendorFrom, "vendor", "new", "setup btc env from existing vendor or new one: new or existing")
 }
 
-func main() {
-	err := config.DefConfig.Init(btcConfFile)
-	if err != nil {
-		panic(err)
+// Deploy sets up the btc multisig vendor and deploys btcx on ethereum and ontology
+func Deploy(g *cli.Globals) error {
+	if vendorFrom != "new" && vendorFrom != "existing" {
+		return fmt.Errorf("unknown vendor '%s', should be new or existing", vendorFrom)
 	}
-
 	invoker, err := btc.NewBtcInvoker(config.DefConfig.RchainJsonRpcAddress, config.DefConfig.RCWallet,
 		config.DefConfig.RCWalletPwd, config.DefConfig.BtcRestAddr, config.DefConfig.BtcRestUser,
 		config.DefConfig.BtcRestPwd, config.DefConfig.BtcSignerPrivateKey)
 	if err != nil {
-		panic(fmt.Errorf("failed to new btc invoker: %v", err))
+		return fmt.Errorf("failed to new btc invoker: %v", err)
 	}
-	switch btcFunc {
-	case "new":
-		SetupNewVendor(invoker)
-	case "existing":
-		SetupExistingVendor(invoker)
+	if vendorFrom == "new" {
+		SetupNewVendor(invoker, g.Conf)
+	} else {
+		SetupExistingVendor(invoker, g.Conf)
 	}
+	return nil
 }
 
-func SetupNewVendor(invoker *btc.BtcInvoker) {
-	vendor, err := invoker.GenerateVendor(config.DefConfig.BtcMultiSigNum, config.DefConfig.BtcMultiSigRequire)
-	if err != nil {
-		panic(fmt.Errorf("failed to new a vendor: %v", err))
-	}
-
-	info := fmt.Sprintf("=============================== vendor info ===============================\n"+
-		"multisig-address: { p2sh: %s, p2wsh: %s }\nredeem-script: %s\nredeem-key: %s\nmembers: {\n",
-		vendor.P2shAddr.EncodeAddress(), vendor.P2wshAddr.EncodeAddress(), hex.EncodeToString(vendor.Redeem),
-		hex.EncodeToString(vendor.HashKey))
-	for i := 0; i < len(vendor.PrivateKeys); i++ {
-		info += fmt.Sprintf("\tNo %d member: [ private-key: %s, public-key: %s, p2pkh-address: %s ]\n",
-			i, vendor.PrivateKeys[i].String(), hex.EncodeToString(vendor.PrivateKeys[i].SerializePubKey()),
-			vendor.AddressSet[i].EncodeAddress())
-	}
-
-	info += "}\nencrypted private keys for vendor signing tool: {\n"
-	files, err := vendor.EncryptPrivateKeys(config.DefConfig.BtcEncryptedPrivateKeyFile,
-		config.DefConfig.BtcEncryptedPrivateKeyPwd)
+// saveVendorKeys encrypts the private keys of vendor into files named after keyFile, and writes the
+// config files of the vendor signing tool for them
+func saveVendorKeys(vendor *btc.Vendor, keyFile string) string {
+	info := "encrypted private keys for vendor signing tool: {\n"
+	files, err := vendor.EncryptPrivateKeys(keyFile, config.DefConfig.BtcEncryptedPrivateKeyPwd)
 	if err != nil {
 		panic(fmt.Errorf("failed to encrypt private keys: %v", err))
 	}
@@ -99,6 +81,26 @@ func SetupNewVendor(invoker *btc.BtcInvoker) {
 		info += fmt.Sprintf("}\nconfig file for vendor signing tool: %s_%d\n",
 			config.DefConfig.BtcVendorSigningToolConfFile, i+1)
 	}
+	return info
+}
+
+func SetupNewVendor(invoker *btc.BtcInvoker, confFile string) {
+	vendor, err := invoker.GenerateVendor(config.DefConfig.BtcMultiSigNum, config.DefConfig.BtcMultiSigRequire)
+	if err != nil {
+		panic(fmt.Errorf("failed to new a vendor: %v", err))
+	}
+
+	info := fmt.Sprintf("=============================== vendor info ===============================\n"+
+		"multisig-address: { p2sh: %s, p2wsh: %s }\nredeem-script: %s\nredeem-key: %s\nmembers: {\n",
+		vendor.P2shAddr.EncodeAddress(), vendor.P2wshAddr.EncodeAddress(), hex.EncodeToString(vendor.Redeem),
+		hex.EncodeToString(vendor.HashKey))
+	for i := 0; i < len(vendor.PrivateKeys); i++ {
+		info += fmt.Sprintf("\tNo %d member: [ private-key: %s, public-key: %s, p2pkh-address: %s ]\n",
+			i, vendor.PrivateKeys[i].String(), hex.EncodeToString(vendor.PrivateKeys[i].SerializePubKey()),
+			vendor.AddressSet[i].EncodeAddress())
+	}
+
+	info += "}\n" + saveVendorKeys(vendor, config.DefConfig.BtcEncryptedPrivateKeyFile)
 
 	// get btcx
 	var (
@@ -216,13 +218,13 @@ func SetupNewVendor(invoker *btc.BtcInvoker) {
 	config.DefConfig.BtceContractAddress = ebtcx.String()
 	config.DefConfig.BtcoContractAddress = obtcx.ToHexString()
 	config.DefConfig.BtcRedeem = hex.EncodeToString(vendor.Redeem)
-	err = config.DefConfig.Save(btcConfFile)
+	err = config.DefConfig.Save(confFile)
 	if err != nil {
 		panic(fmt.Errorf("failed to save config: %v", err))
 	}
 }
 
-func SetupExistingVendor(invoker *btc.BtcInvoker) {
+func SetupExistingVendor(invoker *btc.BtcInvoker, confFile string) {
 	vendor, err := btc.NewVendorFromConfig()
 	if err != nil {
 		panic(err)
@@ -373,7 +375,7 @@ func SetupExistingVendor(invoker *btc.BtcInvoker) {
 
 	config.DefConfig.BtceContractAddress = ebtcx.String()
 	config.DefConfig.BtcoContractAddress = obtcx.ToHexString()
-	err = config.DefConfig.Save(btcConfFile)
+	err = config.DefConfig.Save(confFile)
 	if err != nil {
 		panic(fmt.Errorf("failed to save config: %v", err))
 	}
@@ -0,0 +1,175 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package btc_wallet
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/polynetwork/poly-io-test/chains/btc"
+	"github.com/polynetwork/poly-io-test/cli"
+	"github.com/polynetwork/poly-io-test/config"
+	"github.com/polynetwork/poly-io-test/log"
+	"github.com/polynetwork/poly-io-test/plan"
+	"io"
+	"os"
+)
+
+var (
+	splitNum     int
+	splitValue   int64
+	mergeBelow   int64
+	mergeMax     int
+	feeRate      uint64
+	blocksToMine int
+)
+
+func commonFlags(fs *flag.FlagSet) {
+	fs.Uint64Var(&feeRate, "fee_rate", 0, "fee rate in sat/vbyte, BtcSendFeeRate or the node estimation if 0")
+	fs.IntVar(&blocksToMine, "generate", 1, "blocks to mine to the signer address for confirming, only on regtest")
+}
+
+// SplitFlags binds the flags of btc split
+func SplitFlags(fs *flag.FlagSet) {
+	fs.IntVar(&splitNum, "n", 50, "number of outputs")
+	fs.Int64Var(&splitValue, "value", 100000, "satoshi of each output")
+	commonFlags(fs)
+}
+
+// ConsolidateFlags binds the flags of btc consolidate
+func ConsolidateFlags(fs *flag.FlagSet) {
+	fs.Int64Var(&mergeBelow, "below", 0, "merge utxos under this many satoshi, all utxos if 0")
+	fs.IntVar(&mergeMax, "max_inputs", 500, "max utxos merged in one tx, no limit if 0")
+	commonFlags(fs)
+}
+
+// Result is what btc split and consolidate did
+type Result struct {
+	Address string
+	Txid    string
+	Inputs  int
+	Outputs int
+	Fee     int64
+	FeeRate uint64
+	Blocks  []string `json:",omitempty"`
+}
+
+func newWallet() (*btc.Wallet, error) {
+	signer, err := btc.NewBtcSigner(config.DefConfig.BtcSignerPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	cli := btc.NewRestCli(config.DefConfig.BtcRestAddr, config.DefConfig.BtcRestUser, config.DefConfig.BtcRestPwd)
+	return btc.NewWallet(cli, signer, config.DefConfig.BtcSignerInputType, config.BtcNet)
+}
+
+func sendFeeRate() uint64 {
+	if feeRate > 0 {
+		return feeRate
+	}
+	return config.DefConfig.BtcSendFeeRate
+}
+
+func txFee(w *btc.Wallet, tx *wire.MsgTx) (int64, error) {
+	fee := int64(0)
+	for _, in := range tx.TxIn {
+		val, err := w.Cli.GetTxOutVal(in.PreviousOutPoint.Hash.String(), in.PreviousOutPoint.Index)
+		if err != nil {
+			return 0, err
+		}
+		fee += val
+	}
+	for _, out := range tx.TxOut {
+		fee -= out.Value
+	}
+	return fee, nil
+}
+
+// send broadcasts tx, and mines blocks on regtest to confirm it so that batch runs start from
+// confirmed utxos
+func send(g *cli.Globals, w *btc.Wallet, tx *wire.MsgTx, rate uint64) error {
+	txid, err := w.Send(tx)
+	if err != nil {
+		return err
+	}
+	res := &Result{
+		Address: w.Addr.EncodeAddress(),
+		Txid:    txid,
+		Inputs:  len(tx.TxIn),
+		Outputs: len(tx.TxOut),
+		FeeRate: rate,
+	}
+	if res.Fee, err = txFee(w, tx); err != nil {
+		log.Warnf("failed to get fee of %s: %v", txid, err)
+	}
+	if config.DefConfig.BtcNetType == "regtest" && blocksToMine > 0 {
+		if res.Blocks, err = w.Cli.GenerateToAddr(blocksToMine, w.Addr.EncodeAddress()); err != nil {
+			return fmt.Errorf("tx %s sent but failed to mine: %v", txid, err)
+		}
+	}
+	return Print(os.Stdout, res, g.Format)
+}
+
+// Split splits utxos of BtcSignerPrivateKey into outputs of the same value
+func Split(g *cli.Globals) error {
+	w, err := newWallet()
+	if err != nil {
+		return err
+	}
+	rate := btc.FeeRate(w.Cli, sendFeeRate())
+	tx, err := w.Split(splitNum, splitValue, rate)
+	if err != nil {
+		return fmt.Errorf("failed to split: %v", err)
+	}
+	return send(g, w, tx, rate)
+}
+
+// Consolidate merges small utxos of BtcSignerPrivateKey into one
+func Consolidate(g *cli.Globals) error {
+	w, err := newWallet()
+	if err != nil {
+		return err
+	}
+	rate := btc.FeeRate(w.Cli, sendFeeRate())
+	tx, err := w.Consolidate(mergeBelow, mergeMax, rate)
+	if err != nil {
+		return fmt.Errorf("failed to consolidate: %v", err)
+	}
+	return send(g, w, tx, rate)
+}
+
+func Print(w io.Writer, res *Result, format string) error {
+	switch format {
+	case plan.FormatJson:
+		raw, err := json.MarshalIndent(res, "", "\t")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(raw))
+		return err
+	case plan.FormatText, "":
+		_, err := fmt.Fprintf(w, "address: %s\ntxid: %s\ninputs: %d, outputs: %d, fee: %d sat (%d sat/vbyte)\n",
+			res.Address, res.Txid, res.Inputs, res.Outputs, res.Fee, res.FeeRate)
+		if err == nil && len(res.Blocks) > 0 {
+			_, err = fmt.Fprintf(w, "mined %d blocks, last: %s\n", len(res.Blocks), res.Blocks[len(res.Blocks)-1])
+		}
+		return err
+	default:
+		return fmt.Errorf("unknown format %s", format)
+	}
+}
@@ -0,0 +1,172 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package cctest
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"github.com/polynetwork/poly-go-sdk"
+	"github.com/polynetwork/poly-io-test/chains/btc"
+	"github.com/polynetwork/poly-io-test/chains/cosmos"
+	"github.com/polynetwork/poly-io-test/chains/eth"
+	"github.com/polynetwork/poly-io-test/chains/ont"
+	"github.com/polynetwork/poly-io-test/cli"
+	"github.com/polynetwork/poly-io-test/config"
+	"github.com/polynetwork/poly-io-test/log"
+	"github.com/polynetwork/poly-io-test/monitor"
+	_ "github.com/polynetwork/poly-io-test/testcase"
+	"github.com/polynetwork/poly-io-test/testframework"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	TestCases  string //TestCase list in cmdline
+	LoopNumber int
+
+	MonitorInterval  uint64
+	MonitorThreshold uint64
+	VendorInterval   uint64
+
+	SignerInterval uint64
+	SignerKeys     int
+	SignerDelay    uint64
+)
+
+// Flags binds the flags of running test cases
+func Flags(fs *flag.FlagSet) {
+	fs.StringVar(&TestCases, "t", "", "Test case to run. use ',' to split test case")
+	fs.IntVar(&LoopNumber, "loop", 1, " the number the whole test cases run")
+	fs.Uint64Var(&MonitorInterval, "monitor", 0, "check header sync of side chains every this many seconds "+
+		"in background and warn about lagging relayers, disabled if 0")
+	fs.Uint64Var(&MonitorThreshold, "monitor_threshold", 20, "blocks of lag to warn about for -monitor")
+	fs.Uint64Var(&VendorInterval, "vendor_monitor", 0, "check utxos and signing of the btc vendor every "+
+		"this many seconds in background, disabled if 0")
+	fs.Uint64Var(&SignerInterval, "vendor_signer", 0, "sign unlock txs for the btc vendor in process every this "+
+		"many seconds with keys in BtcEncryptedPrivateKeyFile, instead of the vendor signing tool, disabled if 0")
+	fs.IntVar(&SignerKeys, "vendor_signer_keys", 0, "members signing for -vendor_signer, all if 0")
+	fs.Uint64Var(&SignerDelay, "vendor_signer_delay", 0, "seconds -vendor_signer waits before signing a tx")
+}
+
+// Run runs the test cases and waits for the exit signal
+func Run(g *cli.Globals) error {
+	rcSdk := poly_go_sdk.NewPolySdk()
+	if err := btc.SetUpPoly(rcSdk, config.DefConfig.RchainJsonRpcAddress); err != nil {
+		return err
+	}
+
+	ethInvoker := eth.NewEInvoker()
+	btcInvoker, err := btc.NewBtcInvoker(config.DefConfig.RchainJsonRpcAddress, config.DefConfig.RCWallet,
+		config.DefConfig.RCWalletPwd, config.DefConfig.BtcRestAddr, config.DefConfig.BtcRestUser,
+		config.DefConfig.BtcRestPwd, config.DefConfig.BtcSignerPrivateKey)
+	if err != nil {
+		log.Errorf("failed to new a btc invoker, do not test cases about BTC: %v", err)
+	}
+	ontInvoker, err := ont.NewOntInvoker(config.DefConfig.OntJsonRpcAddress, config.DefConfig.OntContractsAvmPath,
+		config.DefConfig.OntWallet, config.DefConfig.OntWalletPassword)
+	if err != nil {
+		log.Errorf("failed to new a ont invoker, do not test cases about ONT: %v", err)
+	}
+	cmInvoker, err := cosmos.NewCosmosInvoker()
+	if err != nil {
+		log.Errorf("failed to new a cosmos invoker, do not test cases about COSMOS: %v", err)
+	}
+
+	testCases := make([]string, 0)
+	if TestCases != "" {
+		testCases = strings.Split(TestCases, ",")
+	}
+	testframework.TFramework.SetRcSdk(rcSdk)
+	testframework.TFramework.SetEthInvoker(ethInvoker)
+	testframework.TFramework.SetBtcInvoker(btcInvoker)
+	testframework.TFramework.SetOntInvoker(ontInvoker)
+	testframework.TFramework.SetCosmosInvoker(cmInvoker)
+
+	if MonitorInterval > 0 {
+		stop := make(chan struct{})
+		defer close(stop)
+		go monitor.Dial(rcSdk, MonitorThreshold).Watch(time.Duration(MonitorInterval)*time.Second, stop)
+	}
+	if VendorInterval > 0 {
+		if m, err := monitor.DialVendor(rcSdk, uint32(MonitorThreshold)); err != nil {
+			log.Errorf("failed to monitor btc vendor: %v", err)
+		} else {
+			stop := make(chan struct{})
+			defer close(stop)
+			h, _ := rcSdk.GetCurrentBlockHeight()
+			m.ScanFrom(h)
+			go m.Watch(time.Duration(VendorInterval)*time.Second, stop)
+		}
+	}
+
+	if SignerInterval > 0 {
+		if vs, err := newVendorSigner(rcSdk); err != nil {
+			log.Errorf("failed to run vendor signer: %v", err)
+		} else {
+			stop := make(chan struct{})
+			defer close(stop)
+			go vs.Run(time.Duration(SignerInterval)*time.Second, stop)
+		}
+	}
+
+	//Start run test case
+	testframework.TFramework.Run(testCases, LoopNumber)
+	waitToExit()
+	return nil
+}
+
+func newVendorSigner(poly *poly_go_sdk.PolySdk) (*btc.VendorSigner, error) {
+	redeem, err := hex.DecodeString(config.DefConfig.BtcRedeem)
+	if err != nil {
+		return nil, fmt.Errorf("wrong BtcRedeem: %v", err)
+	}
+	members, err := btc.LoadVendorMembers(config.DefConfig.BtcEncryptedPrivateKeyFile,
+		config.DefConfig.BtcEncryptedPrivateKeyPwd, redeem, config.BtcNet)
+	if err != nil {
+		return nil, err
+	}
+	acc, err := btc.GetAccountByPassword(poly, config.DefConfig.RCWallet, []byte(config.DefConfig.RCWalletPwd))
+	if err != nil {
+		return nil, err
+	}
+	h, err := poly.GetCurrentBlockHeight()
+	if err != nil {
+		return nil, err
+	}
+	vs := btc.NewVendorSigner(poly, acc, redeem, members)
+	vs.Keys, vs.Delay = SignerKeys, time.Duration(SignerDelay)*time.Second
+	vs.ScanFrom(h)
+	return vs, nil
+}
+
+func waitToExit() {
+	exit := make(chan bool, 0)
+	sc := make(chan os.Signal, 1)
+	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	go func() {
+		for sig := range sc {
+			fmt.Println("cross chain test received exit signal: ", sig.String())
+			close(exit)
+			break
+		}
+	}()
+	<-exit
+}
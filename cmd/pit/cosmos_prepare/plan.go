@@ -0,0 +1,163 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package cosmos_prepare
+
+import (
+	"encoding/hex"
+	"fmt"
+	"github.com/btcsuite/btcutil"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ontio/ontology-go-sdk"
+	common2 "github.com/ontio/ontology/common"
+	"github.com/polynetwork/poly-io-test/chains/cosmos"
+	"github.com/polynetwork/poly-io-test/config"
+	"github.com/polynetwork/poly-io-test/plan"
+	"github.com/tendermint/tendermint/rpc/core/types"
+)
+
+const planChain = "cosmos"
+
+// sender returns the func sending a cosmos tx by broadcast for plan.Tx.SentBy,
+// waiting for the tx to be included if wait is set
+func sender(invoker *cosmos.CosmosInvoker, broadcast func() (*coretypes.ResultBroadcastTx, error),
+	wait bool) func() (string, error) {
+	return func() (string, error) {
+		res, err := broadcast()
+		if err != nil {
+			return "", err
+		}
+		if wait {
+			invoker.WaitTx(res.Hash)
+		}
+		return res.Hash.String(), nil
+	}
+}
+
+// PlanCosmosSetup lists the messages creating the lock proxy and binding all
+// assets and btcx, sent by Setup or printed in plan mode
+func PlanCosmosSetup(invoker *cosmos.CosmosInvoker) (*plan.Plan, error) {
+	p := plan.NewPlan("setup assets on cosmos")
+	signer := invoker.Acc.Acc.String()
+	proxy := invoker.Acc.Acc.Bytes()
+
+	p.Invoke(planChain, "lockproxy", "MsgCreateLockProxy",
+		plan.NewArg("creator", signer)).SignedBy(signer).
+		SentBy(sender(invoker, invoker.CreateLockProxy, true))
+
+	ontAddr := func(name, val string) ([]byte, error) {
+		addr, err := common2.AddressFromHexString(val)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s '%s': %v", name, val, err)
+		}
+		return addr[:], nil
+	}
+	assets := []struct {
+		denom string
+		amt   string
+		onEth []byte
+		onOnt func() ([]byte, error)
+	}{
+		{config.CM_ETHX, "1000000000000000000000000", common.HexToAddress("0x0000000000000000000000000000000000000000").Bytes(),
+			func() ([]byte, error) { return ontAddr("OntEth", config.DefConfig.OntEth) }},
+		{config.CM_ERC20, "1000000000000000000000000", common.HexToAddress(config.DefConfig.EthErc20).Bytes(),
+			func() ([]byte, error) { return ontAddr("OntErc20", config.DefConfig.OntErc20) }},
+		{config.CM_ONT, "1000000000", common.HexToAddress(config.DefConfig.EthOntx).Bytes(),
+			func() ([]byte, error) { return ontology_go_sdk.ONT_CONTRACT_ADDRESS[:], nil }},
+		{config.CM_ONG, "1000000000000000000", common.HexToAddress(config.DefConfig.EthOngx).Bytes(),
+			func() ([]byte, error) { return ontology_go_sdk.ONG_CONTRACT_ADDRESS[:], nil }},
+		{config.CM_OEP4, "10000000000000", common.HexToAddress(config.DefConfig.EthOep4).Bytes(),
+			func() ([]byte, error) { return ontAddr("OntOep4", config.DefConfig.OntOep4) }},
+	}
+	bindAsset := func(denom string, toChainId uint64, to []byte) {
+		p.Invoke(planChain, "lockproxy", "MsgBindAssetHash",
+			plan.NewArg("sourceAssetDenom", denom),
+			plan.NewArg("toChainId", toChainId),
+			plan.NewArg("toAssetHash", to)).SignedBy(signer).
+			SentBy(sender(invoker, func() (*coretypes.ResultBroadcastTx, error) {
+				return invoker.BindAsset(denom, toChainId, to)
+			}, false))
+	}
+	for _, a := range assets {
+		denom, amt := a.denom, a.amt
+		p.Invoke(planChain, "lockproxy", "MsgCreateCoinAndDelegateToProxy",
+			plan.NewArg("coin", amt+denom),
+			plan.NewArg("lockProxy", proxy)).SignedBy(signer).
+			SentBy(sender(invoker, func() (*coretypes.ResultBroadcastTx, error) {
+				return invoker.CreateAsset(denom, amt, proxy)
+			}, false))
+		bindAsset(denom, config.ETH_CHAIN_ID, a.onEth)
+		onOnt, err := a.onOnt()
+		if err != nil {
+			return nil, err
+		}
+		bindAsset(denom, config.ONT_CHAIN_ID, onOnt)
+	}
+	ontProxy, err := ontAddr("OntLockProxy", config.DefConfig.OntLockProxy)
+	if err != nil {
+		return nil, err
+	}
+	for _, other := range []struct {
+		chainId uint64
+		proxy   []byte
+	}{
+		{config.ONT_CHAIN_ID, ontProxy},
+		{config.ETH_CHAIN_ID, common.HexToAddress(config.DefConfig.EthLockProxy).Bytes()},
+	} {
+		toChainId, to := other.chainId, other.proxy
+		p.Invoke(planChain, "lockproxy", "MsgBindProxyHash",
+			plan.NewArg("toChainId", toChainId),
+			plan.NewArg("toChainProxyHash", to)).SignedBy(signer).
+			SentBy(sender(invoker, func() (*coretypes.ResultBroadcastTx, error) {
+				return invoker.BindProxy(toChainId, to)
+			}, false))
+	}
+
+	rawRdm, err := hex.DecodeString(config.DefConfig.BtcRedeem)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode BtcRedeem: %v", err)
+	}
+	p.Invoke(planChain, "btcx", "MsgCreateDenom",
+		plan.NewArg("denom", config.CM_BTCX),
+		plan.NewArg("redeemScript", config.DefConfig.BtcRedeem)).SignedBy(signer).
+		SentBy(sender(invoker, func() (*coretypes.ResultBroadcastTx, error) {
+			return invoker.CreateDenom(config.CM_BTCX, config.DefConfig.BtcRedeem)
+		}, true))
+	btco, err := ontAddr("BtcoContractAddress", config.DefConfig.BtcoContractAddress)
+	if err != nil {
+		return nil, err
+	}
+	btcxBinds := []struct {
+		chainId uint64
+		asset   []byte
+	}{
+		{config.BTC_CHAIN_ID, btcutil.Hash160(rawRdm)},
+		{config.ETH_CHAIN_ID, common.HexToAddress(config.DefConfig.BtceContractAddress).Bytes()},
+		{config.ONT_CHAIN_ID, btco},
+	}
+	for i, b := range btcxBinds {
+		toChainId, to := b.chainId, b.asset
+		p.Invoke(planChain, "btcx", "MsgBindAssetHash",
+			plan.NewArg("sourceAssetDenom", config.CM_BTCX),
+			plan.NewArg("toChainId", toChainId),
+			plan.NewArg("toAssetHash", to)).SignedBy(signer).
+			SentBy(sender(invoker, func() (*coretypes.ResultBroadcastTx, error) {
+				return invoker.BtcxBindAsset(config.CM_BTCX, toChainId, to)
+			}, i == len(btcxBinds)-1))
+	}
+
+	return p, nil
+}
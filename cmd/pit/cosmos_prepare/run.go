@@ -14,52 +14,46 @@
 * You should have received a copy of the GNU Lesser General Public License
 * along with The poly network . If not, see <http://www.gnu.org/licenses/>.
  */
-package main
+package cosmos_prepare
 
 import (
 	"flag"
 	"github.com/polynetwork/poly-io-test/chains/cosmos"
+	"github.com/polynetwork/poly-io-test/cli"
 	"github.com/polynetwork/poly-io-test/config"
 	"github.com/polynetwork/poly-io-test/log"
+	"os"
 )
 
-var (
-	cmConfFile string
-)
+var planMode bool
 
-func init() {
-	flag.StringVar(&cmConfFile, "conf", "./config.json", "config file path")
-	flag.Parse()
+// Flags binds the flags of setup on cosmos
+func Flags(fs *flag.FlagSet) {
+	fs.BoolVar(&planMode, "plan", false, "only print the transactions to send without broadcasting them")
 }
 
-func main() {
-	err := config.DefConfig.Init(cmConfFile)
-	if err != nil {
-		panic(err)
-	}
+// Setup creates the lock proxy and binds all assets and btcx on cosmos
+func Setup(g *cli.Globals) error {
 	invoker, err := cosmos.NewCosmosInvoker()
 	if err != nil {
-		panic(err)
+		return err
 	}
-	res, err := invoker.CreateLockProxy()
+	p, err := PlanCosmosSetup(invoker)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	invoker.WaitTx(res.Hash)
-	err = invoker.SetupAllAssets(invoker.Acc.Acc.Bytes())
-	if err != nil {
-		panic(err)
+	if planMode {
+		return p.Print(os.Stdout, g.Format)
 	}
-
-	err = invoker.SetupBtcx(config.CM_BTCX, config.DefConfig.BtcRedeem)
-	if err != nil {
-		panic(err)
+	if err = p.Run(os.Stdout); err != nil {
+		return err
 	}
 
-	err = config.DefConfig.Save(cmConfFile)
+	err = config.DefConfig.Save(g.Conf)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
 	log.Info("successful to set cosmos up")
+	return nil
 }
@@ -0,0 +1,115 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package eth_deployer
+
+import (
+	"fmt"
+	common2 "github.com/ethereum/go-ethereum/common"
+	"github.com/ontio/ontology/common"
+	"github.com/polynetwork/poly-io-test/chains/eth"
+	"github.com/polynetwork/poly-io-test/config"
+	"github.com/polynetwork/poly-io-test/plan"
+	"os"
+)
+
+func PlanETHSmartContract(format string) {
+	invoker := eth.NewEInvoker()
+	deployer, err := NewEthDeployer(invoker, deployStateFile, freshDeploy)
+	if err != nil {
+		panic(err)
+	}
+	if eccmRedeploy != 1 {
+		deployer.UseExistingECCM(config.DefConfig.Eccd, config.DefConfig.Eccm, config.DefConfig.Eccmp)
+	}
+	p, err := deployer.Plan()
+	if err != nil {
+		panic(err)
+	}
+	if err = p.Print(os.Stdout, format); err != nil {
+		panic(err)
+	}
+}
+
+// PlanLockProxyForETH prints what SetUpLockProxyForETH is going to send
+func PlanLockProxyForETH(format string) {
+	if err := lockProxyPlan(eth.NewEInvoker()).Print(os.Stdout, format); err != nil {
+		panic(err)
+	}
+}
+
+// lockProxyPlan lists the txs binding assets and proxies on the lock proxy,
+// sent by SetUpLockProxyForETH and printed by PlanLockProxyForETH
+func lockProxyPlan(invoker *eth.EInvoker) *plan.Plan {
+	p := plan.NewPlan("setup lock proxy on ethereum")
+	SetupETH(p, invoker)
+	SetupERC20(p, invoker)
+	SetupOntAsset(p, invoker)
+	SetOtherLockProxy(p, invoker)
+	return p
+}
+
+// bindAsset adds the tx binding asset from on the lock proxy to asset to on chain toChainId
+func bindAsset(p *plan.Plan, invoker *eth.EInvoker, from string, toChainId uint64, to []byte, note string) {
+	proxy := config.DefConfig.EthLockProxy
+	p.Invoke(planChain, proxy, "bindAssetHash",
+		plan.NewArg("fromAssetHash", common2.HexToAddress(from).Hex()),
+		plan.NewArg("toChainId", toChainId),
+		plan.NewArg("toAssetHash", to)).SignedBy(invoker.EthTestSigner.Address.Hex()).WithNote(note).
+		SentBy(func() (string, error) {
+			auth, contract, err := invoker.MakeLockProxy(proxy)
+			if err != nil {
+				return "", err
+			}
+			tx, err := contract.BindAssetHash(auth, common2.HexToAddress(from), toChainId, to)
+			if err != nil {
+				return "", err
+			}
+			invoker.ETHUtil.WaitTransactionConfirm(tx.Hash())
+			return tx.Hash().Hex(), nil
+		})
+}
+
+// bindProxy adds the tx binding the lock proxy to proxy on chain toChainId
+func bindProxy(p *plan.Plan, invoker *eth.EInvoker, toChainId uint64, proxy []byte, note string) {
+	p.Invoke(planChain, config.DefConfig.EthLockProxy, "bindProxyHash",
+		plan.NewArg("toChainId", toChainId),
+		plan.NewArg("targetProxyHash", proxy)).SignedBy(invoker.EthTestSigner.Address.Hex()).WithNote(note).
+		SentBy(func() (string, error) {
+			auth, contract, err := invoker.MakeLockProxy(config.DefConfig.EthLockProxy)
+			if err != nil {
+				return "", err
+			}
+			tx, err := contract.BindProxyHash(auth, toChainId, proxy)
+			if err != nil {
+				return "", err
+			}
+			invoker.ETHUtil.WaitTransactionConfirm(tx.Hash())
+			return tx.Hash().Hex(), nil
+		})
+}
+
+// ontAddr decodes the ontology address val of config field name
+func ontAddr(name, val string) []byte {
+	if val == "" {
+		panic(fmt.Errorf("%s is blank", name))
+	}
+	addr, err := common.AddressFromHexString(val)
+	if err != nil {
+		panic(fmt.Errorf("failed to decode %s '%s': %v", name, val, err))
+	}
+	return addr[:]
+}
@@ -0,0 +1,161 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package eth_deployer
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	utils2 "github.com/ontio/ontology/smartcontract/service/native/utils"
+	"github.com/polynetwork/poly-io-test/chains/eth"
+	"github.com/polynetwork/poly-io-test/cli"
+	"github.com/polynetwork/poly-io-test/config"
+	"github.com/polynetwork/poly-io-test/plan"
+	"os"
+)
+
+var (
+	eccmRedeploy    int
+	deployStateFile string
+	freshDeploy     bool
+	planMode        bool
+)
+
+// Flags binds the flags of deploy and setup on ethereum
+func Flags(fs *flag.FlagSet) {
+	fs.IntVar(&eccmRedeploy, "redeploy_eccm", 1, "redeploy eccd, eccm and eccmp or not")
+	fs.StringVar(&deployStateFile, "state", "./eth_deploy_state.json", "file recording finished deploy steps, used to resume")
+	fs.BoolVar(&freshDeploy, "fresh", false, "ignore the recorded deploy steps and deploy everything again")
+	fs.BoolVar(&planMode, "plan", false, "only print the transactions to send without broadcasting them")
+}
+
+// Deploy deploys all contracts on ethereum and saves them into config
+func Deploy(g *cli.Globals) error {
+	if planMode {
+		PlanETHSmartContract(g.Format)
+		return nil
+	}
+	SetupETHSmartContract(g.Conf)
+	return nil
+}
+
+// Setup binds assets and proxies on ethereum lock proxy
+func Setup(g *cli.Globals) error {
+	if planMode {
+		PlanLockProxyForETH(g.Format)
+		return nil
+	}
+	SetUpLockProxyForETH()
+	return nil
+}
+
+func SetupETHSmartContract(confFile string) {
+	invoker := eth.NewEInvoker()
+	deployer, err := NewEthDeployer(invoker, deployStateFile, freshDeploy)
+	if err != nil {
+		panic(err)
+	}
+	if eccmRedeploy != 1 {
+		deployer.UseExistingECCM(config.DefConfig.Eccd, config.DefConfig.Eccm, config.DefConfig.Eccmp)
+	}
+	if err = deployer.Run(); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("=============================ETH info=============================")
+	fmt.Println("erc20:", deployer.Contract(StepERC20))
+	fmt.Println("ope4:", deployer.Contract(StepOEP4))
+	fmt.Println("eccd address:", deployer.Contract(StepECCD))
+	fmt.Println("eccm address:", deployer.Contract(StepECCM))
+	fmt.Println("eccmp address:", deployer.Contract(StepECCMP))
+	fmt.Println("lock proxy address: ", deployer.Contract(StepLockProxy))
+	fmt.Println("ongx address: ", deployer.Contract(StepONGX))
+	fmt.Println("ontx proxy address: ", deployer.Contract(StepONTX))
+	fmt.Println("==================================================================")
+
+	config.DefConfig.EthErc20 = deployer.Contract(StepERC20)
+	config.DefConfig.EthOep4 = deployer.Contract(StepOEP4)
+	config.DefConfig.Eccd = deployer.Contract(StepECCD)
+	config.DefConfig.Eccm = deployer.Contract(StepECCM)
+	config.DefConfig.Eccmp = deployer.Contract(StepECCMP)
+	config.DefConfig.EthLockProxy = deployer.Contract(StepLockProxy)
+	config.DefConfig.EthOngx = deployer.Contract(StepONGX)
+	config.DefConfig.EthOntx = deployer.Contract(StepONTX)
+
+	if err := config.DefConfig.Save(confFile); err != nil {
+		panic(fmt.Errorf("failed to save config, you better save it youself: %v", err))
+	}
+}
+
+func SetupERC20(p *plan.Plan, invoker *eth.EInvoker) {
+	bindAsset(p, invoker, config.DefConfig.EthErc20, config.ONT_CHAIN_ID, ontAddr("OntErc20", config.DefConfig.OntErc20),
+		"erc20 of ontology")
+	bindAsset(p, invoker, config.DefConfig.EthErc20, config.DefConfig.CMCrossChainId, []byte(config.CM_ERC20),
+		"erc20 of cosmos")
+}
+
+func SetupOntAsset(p *plan.Plan, invoker *eth.EInvoker) {
+	if config.DefConfig.EthLockProxy == "" {
+		panic(fmt.Errorf("EthLockProxy is blank"))
+	}
+	if config.DefConfig.EthOntx == "" {
+		panic(fmt.Errorf("EthOntx is blank"))
+	}
+	if config.DefConfig.EthOngx == "" {
+		panic(fmt.Errorf("EthOngx is blank"))
+	}
+	if config.DefConfig.EthOep4 == "" {
+		panic(fmt.Errorf("EthOep4 is blank"))
+	}
+	bindAsset(p, invoker, config.DefConfig.EthOntx, config.ONT_CHAIN_ID, utils2.OntContractAddress[:],
+		"ont of ontology")
+	bindAsset(p, invoker, config.DefConfig.EthOntx, config.DefConfig.CMCrossChainId, []byte(config.CM_ONT),
+		"ont of cosmos")
+	bindAsset(p, invoker, config.DefConfig.EthOngx, config.ONT_CHAIN_ID, utils2.OngContractAddress[:],
+		"ong of ontology")
+	bindAsset(p, invoker, config.DefConfig.EthOngx, config.DefConfig.CMCrossChainId, []byte(config.CM_ONG),
+		"ong of cosmos")
+	bindAsset(p, invoker, config.DefConfig.EthOep4, config.ONT_CHAIN_ID, ontAddr("OntOep4", config.DefConfig.OntOep4),
+		"oep4 of ontology")
+	bindAsset(p, invoker, config.DefConfig.EthOep4, config.DefConfig.CMCrossChainId, []byte(config.CM_OEP4),
+		"oep4 of cosmos")
+}
+
+func SetupETH(p *plan.Plan, invoker *eth.EInvoker) {
+	ethNativeAddr := "0x0000000000000000000000000000000000000000"
+	bindAsset(p, invoker, ethNativeAddr, config.ONT_CHAIN_ID, ontAddr("OntEth", config.DefConfig.OntEth),
+		"ethx of ontology")
+	bindAsset(p, invoker, ethNativeAddr, config.DefConfig.CMCrossChainId, []byte(config.CM_ETHX), "ethx of cosmos")
+}
+
+func SetOtherLockProxy(p *plan.Plan, invoker *eth.EInvoker) {
+	bindProxy(p, invoker, config.ONT_CHAIN_ID, ontAddr("OntLockProxy", config.DefConfig.OntLockProxy), "ont proxy")
+	if config.DefConfig.CMLockProxy == "" {
+		panic(fmt.Errorf("COSMOS lockproxy is blank"))
+	}
+	raw, err := hex.DecodeString(config.DefConfig.CMLockProxy)
+	if err != nil {
+		panic(fmt.Errorf("failed to decode CMLockProxy: %v", err))
+	}
+	bindProxy(p, invoker, config.DefConfig.CMCrossChainId, raw, "cosmos proxy")
+}
+
+func SetUpLockProxyForETH() {
+	if err := lockProxyPlan(eth.NewEInvoker()).Run(os.Stdout); err != nil {
+		panic(err)
+	}
+}
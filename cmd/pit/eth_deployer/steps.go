@@ -0,0 +1,446 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package eth_deployer
+
+import (
+	"encoding/json"
+	"fmt"
+	common2 "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/polynetwork/poly-io-test/chains/eth"
+	erc20_api "github.com/polynetwork/poly-io-test/chains/eth/abi/erc20"
+	"github.com/polynetwork/poly-io-test/plan"
+	"io/ioutil"
+	"math/big"
+	"os"
+)
+
+const planChain = "ethereum"
+
+const (
+	StepECCD          = "eccd"
+	StepECCM          = "eccm"
+	StepECCMP         = "eccmp"
+	StepECCDOwnership = "eccd_ownership"
+	StepECCMOwnership = "eccm_ownership"
+	StepLockProxy     = "lockproxy"
+	StepERC20         = "erc20"
+	StepERC20Approve  = "erc20_approve"
+	StepOEP4          = "oep4"
+	StepONGX          = "ongx"
+	StepONTX          = "ontx"
+)
+
+// DeployState is what the deployer knows about the previous runs. It is
+// saved after every finished step so we can resume from the failed one.
+type DeployState struct {
+	Contracts map[string]string `json:"contracts"`
+	Done      map[string]bool   `json:"done"`
+}
+
+func LoadDeployState(file string) (*DeployState, error) {
+	state := &DeployState{
+		Contracts: make(map[string]string),
+		Done:      make(map[string]bool),
+	}
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return state, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read deploy state %s: %v", file, err)
+	}
+	if err = json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal deploy state %s: %v", file, err)
+	}
+	if state.Contracts == nil {
+		state.Contracts = make(map[string]string)
+	}
+	if state.Done == nil {
+		state.Done = make(map[string]bool)
+	}
+	return state, nil
+}
+
+func (state *DeployState) Save(file string) error {
+	data, err := json.MarshalIndent(state, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(file, data, 0644); err != nil {
+		return fmt.Errorf("failed to write deploy state: %v", err)
+	}
+	return nil
+}
+
+type deployStep struct {
+	name string
+	// steps must be finished before this one. If any of them runs again,
+	// this step runs again too.
+	deps []string
+	// check tells if the result of a finished step still holds on chain
+	check func(d *EthDeployer) (bool, error)
+	run   func(d *EthDeployer) error
+	// describe adds the transactions of this step to a plan without sending them
+	describe func(d *EthDeployer, p *plan.Plan)
+}
+
+type EthDeployer struct {
+	invoker   *eth.EInvoker
+	state     *DeployState
+	stateFile string
+	rerun     map[string]bool
+	steps     []*deployStep
+}
+
+func NewEthDeployer(invoker *eth.EInvoker, stateFile string, fresh bool) (*EthDeployer, error) {
+	state, err := LoadDeployState(stateFile)
+	if err != nil {
+		return nil, err
+	}
+	if fresh {
+		state = &DeployState{
+			Contracts: make(map[string]string),
+			Done:      make(map[string]bool),
+		}
+	}
+	d := &EthDeployer{
+		invoker:   invoker,
+		state:     state,
+		stateFile: stateFile,
+		rerun:     make(map[string]bool),
+	}
+	d.steps = []*deployStep{
+		{
+			name:  StepECCD,
+			check: d.codeExists(StepECCD),
+			describe: func(d *EthDeployer, p *plan.Plan) {
+				p.Deploy(planChain, "EthCrossChainData")
+			},
+			run: func(d *EthDeployer) error {
+				addr, _, err := d.invoker.DeployEthChainDataContract()
+				if err != nil {
+					return err
+				}
+				d.state.Contracts[StepECCD] = addr.Hex()
+				return nil
+			},
+		},
+		{
+			name:  StepECCM,
+			deps:  []string{StepECCD},
+			check: d.codeExists(StepECCM),
+			describe: func(d *EthDeployer, p *plan.Plan) {
+				p.Deploy(planChain, "EthCrossChainManager", plan.NewArg("eccd", d.addrOf(StepECCD)))
+			},
+			run: func(d *EthDeployer) error {
+				addr, _, err := d.invoker.DeployECCMContract(d.state.Contracts[StepECCD])
+				if err != nil {
+					return err
+				}
+				d.state.Contracts[StepECCM] = addr.Hex()
+				return nil
+			},
+		},
+		{
+			name:  StepECCMP,
+			deps:  []string{StepECCM},
+			check: d.codeExists(StepECCMP),
+			describe: func(d *EthDeployer, p *plan.Plan) {
+				p.Deploy(planChain, "EthCrossChainManagerProxy", plan.NewArg("eccm", d.addrOf(StepECCM)))
+			},
+			run: func(d *EthDeployer) error {
+				addr, _, err := d.invoker.DeployECCMPContract(d.state.Contracts[StepECCM])
+				if err != nil {
+					return err
+				}
+				d.state.Contracts[StepECCMP] = addr.Hex()
+				return nil
+			},
+		},
+		{
+			name:  StepECCDOwnership,
+			deps:  []string{StepECCD, StepECCM},
+			check: d.ownedBy(StepECCD, StepECCM, invoker.GetOwnerOfECCD),
+			describe: func(d *EthDeployer, p *plan.Plan) {
+				p.Invoke(planChain, d.addrOf(StepECCD), "transferOwnership", plan.NewArg("newOwner", d.addrOf(StepECCM)))
+			},
+			run: func(d *EthDeployer) error {
+				return d.transferOwnership(StepECCD, StepECCM, invoker.GetOwnerOfECCD, invoker.TransferOwnershipForECCD)
+			},
+		},
+		{
+			name:  StepECCMOwnership,
+			deps:  []string{StepECCM, StepECCMP},
+			check: d.ownedBy(StepECCM, StepECCMP, invoker.GetOwnerOfECCM),
+			describe: func(d *EthDeployer, p *plan.Plan) {
+				p.Invoke(planChain, d.addrOf(StepECCM), "transferOwnership", plan.NewArg("newOwner", d.addrOf(StepECCMP)))
+			},
+			run: func(d *EthDeployer) error {
+				return d.transferOwnership(StepECCM, StepECCMP, invoker.GetOwnerOfECCM, invoker.TransferOwnershipForECCM)
+			},
+		},
+		{
+			name:  StepLockProxy,
+			deps:  []string{StepECCMP},
+			check: d.codeExists(StepLockProxy),
+			describe: func(d *EthDeployer, p *plan.Plan) {
+				p.Deploy(planChain, "LockProxy")
+				p.Invoke(planChain, d.addrOf(StepLockProxy), "setManagerProxy", plan.NewArg("ethCCMProxyAddr", d.addrOf(StepECCMP)))
+			},
+			run: func(d *EthDeployer) error {
+				addr, _, err := d.invoker.DeployLockProxyContract(common2.HexToAddress(d.state.Contracts[StepECCMP]))
+				if err != nil {
+					return err
+				}
+				d.state.Contracts[StepLockProxy] = addr.Hex()
+				return nil
+			},
+		},
+		{
+			name:  StepERC20,
+			check: d.codeExists(StepERC20),
+			describe: func(d *EthDeployer, p *plan.Plan) {
+				p.Deploy(planChain, "ERC20Template")
+			},
+			run: func(d *EthDeployer) error {
+				addr, _, err := d.invoker.DeployERC20()
+				if err != nil {
+					return err
+				}
+				d.state.Contracts[StepERC20] = addr.Hex()
+				return nil
+			},
+		},
+		{
+			name: StepERC20Approve,
+			deps: []string{StepERC20, StepLockProxy},
+			describe: func(d *EthDeployer, p *plan.Plan) {
+				p.Invoke(planChain, d.addrOf(StepERC20), "approve", plan.NewArg("spender", d.addrOf(StepLockProxy)),
+					plan.NewArg("amount", "totalSupply"))
+			},
+			run: func(d *EthDeployer) error {
+				erc20, err := erc20_api.NewERC20Template(common2.HexToAddress(d.state.Contracts[StepERC20]),
+					d.invoker.ETHUtil.GetEthClient())
+				if err != nil {
+					return fmt.Errorf("failed to new erc20: %v", err)
+				}
+				total, err := erc20.TotalSupply(nil)
+				if err != nil {
+					return fmt.Errorf("failed to get total supply for erc20: %v", err)
+				}
+				auth, err := d.invoker.MakeSmartContractAuth()
+				if err != nil {
+					return err
+				}
+				tx, err := erc20.Approve(auth, common2.HexToAddress(d.state.Contracts[StepLockProxy]), total)
+				if err != nil {
+					return fmt.Errorf("failed to approve erc20 to lockproxy: %v", err)
+				}
+				d.invoker.ETHUtil.WaitTransactionConfirm(tx.Hash())
+				return nil
+			},
+		},
+		{
+			name:  StepOEP4,
+			deps:  []string{StepLockProxy},
+			check: d.codeExists(StepOEP4),
+			describe: func(d *EthDeployer, p *plan.Plan) {
+				p.Deploy(planChain, "OEP4Template", plan.NewArg("lockProxy", d.addrOf(StepLockProxy)))
+				p.Invoke(planChain, d.addrOf(StepOEP4), "deletageToProxy", plan.NewArg("lockProxy", d.addrOf(StepLockProxy)),
+					plan.NewArg("amount", big.NewInt(1e13)))
+			},
+			run: func(d *EthDeployer) error {
+				addr, _, err := d.invoker.DeployOEP4(d.state.Contracts[StepLockProxy])
+				if err != nil {
+					return err
+				}
+				d.state.Contracts[StepOEP4] = addr.Hex()
+				return nil
+			},
+		},
+		{
+			name:  StepONGX,
+			deps:  []string{StepLockProxy},
+			check: d.codeExists(StepONGX),
+			describe: func(d *EthDeployer, p *plan.Plan) {
+				p.Deploy(planChain, "ONGX", plan.NewArg("lockProxy", d.addrOf(StepLockProxy)))
+			},
+			run: func(d *EthDeployer) error {
+				addr, _, err := d.invoker.DeployONGXContract(d.state.Contracts[StepLockProxy])
+				if err != nil {
+					return err
+				}
+				d.state.Contracts[StepONGX] = addr.Hex()
+				return nil
+			},
+		},
+		{
+			name:  StepONTX,
+			deps:  []string{StepLockProxy},
+			check: d.codeExists(StepONTX),
+			describe: func(d *EthDeployer, p *plan.Plan) {
+				p.Deploy(planChain, "ONTX", plan.NewArg("lockProxy", d.addrOf(StepLockProxy)))
+			},
+			run: func(d *EthDeployer) error {
+				addr, _, err := d.invoker.DeployONTXContract(d.state.Contracts[StepLockProxy])
+				if err != nil {
+					return err
+				}
+				d.state.Contracts[StepONTX] = addr.Hex()
+				return nil
+			},
+		},
+	}
+	return d, nil
+}
+
+// UseExistingECCM takes ECCD, ECCM and ECCMP from config instead of deploying them.
+// They still have to be on chain and owned correctly.
+func (d *EthDeployer) UseExistingECCM(eccd, eccm, eccmp string) {
+	d.state.Contracts[StepECCD] = eccd
+	d.state.Contracts[StepECCM] = eccm
+	d.state.Contracts[StepECCMP] = eccmp
+	for _, name := range []string{StepECCD, StepECCM, StepECCMP, StepECCDOwnership, StepECCMOwnership} {
+		d.state.Done[name] = true
+	}
+}
+
+// Run executes all steps in order. Finished steps are skipped if their
+// results still hold, and the state file is updated after each step.
+func (d *EthDeployer) Run() error {
+	for _, step := range d.steps {
+		done, err := d.isDone(step)
+		if err != nil {
+			return fmt.Errorf("failed to check step %s: %v", step.name, err)
+		}
+		if done {
+			fmt.Printf("step %s is already done, skip it\n", step.name)
+			continue
+		}
+		fmt.Printf("running step %s...\n", step.name)
+		d.state.Done[step.name] = false
+		if err = step.run(d); err != nil {
+			if serr := d.state.Save(d.stateFile); serr != nil {
+				fmt.Printf("failed to save deploy state: %v\n", serr)
+			}
+			return fmt.Errorf("step %s failed: %v, fix it and run again to resume", step.name, err)
+		}
+		d.state.Done[step.name] = true
+		d.rerun[step.name] = true
+		if err = d.state.Save(d.stateFile); err != nil {
+			return err
+		}
+		fmt.Printf("step %s done\n", step.name)
+	}
+	return nil
+}
+
+// Plan lists the transactions Run would send for now. It only reads the chain.
+func (d *EthDeployer) Plan() (*plan.Plan, error) {
+	p := plan.NewPlan("deploy contracts on ethereum")
+	for _, step := range d.steps {
+		done, err := d.isDone(step)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check step %s: %v", step.name, err)
+		}
+		if done {
+			continue
+		}
+		d.rerun[step.name] = true
+		from := len(p.Txs)
+		step.describe(d, p)
+		for _, tx := range p.Txs[from:] {
+			tx.SignedBy(d.invoker.EthTestSigner.Address.Hex())
+			tx.WithNote("step " + step.name)
+		}
+	}
+	return p, nil
+}
+
+func (d *EthDeployer) Contract(name string) string {
+	return d.state.Contracts[name]
+}
+
+// addrOf returns the recorded address of contract, or a placeholder if it
+// is going to be deployed again.
+func (d *EthDeployer) addrOf(contract string) string {
+	if d.rerun[contract] || d.state.Contracts[contract] == "" {
+		return fmt.Sprintf("<new %s>", contract)
+	}
+	return d.state.Contracts[contract]
+}
+
+func (d *EthDeployer) isDone(step *deployStep) (bool, error) {
+	if !d.state.Done[step.name] {
+		return false, nil
+	}
+	for _, dep := range step.deps {
+		if d.rerun[dep] {
+			return false, nil
+		}
+	}
+	if step.check == nil {
+		return true, nil
+	}
+	return step.check(d)
+}
+
+func (d *EthDeployer) codeExists(contract string) func(d *EthDeployer) (bool, error) {
+	return func(d *EthDeployer) (bool, error) {
+		ok, err := d.invoker.IsContractDeployed(d.state.Contracts[contract])
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			fmt.Printf("no code found for %s at %s\n", contract, d.state.Contracts[contract])
+		}
+		return ok, nil
+	}
+}
+
+func (d *EthDeployer) ownedBy(contract, owner string,
+	getOwner func(string) (common2.Address, error)) func(d *EthDeployer) (bool, error) {
+	return func(d *EthDeployer) (bool, error) {
+		curr, err := getOwner(d.state.Contracts[contract])
+		if err != nil {
+			return false, err
+		}
+		return curr == common2.HexToAddress(d.state.Contracts[owner]), nil
+	}
+}
+
+func (d *EthDeployer) transferOwnership(contract, owner string, getOwner func(string) (common2.Address, error),
+	transfer func(string, string) (*types.Transaction, error)) error {
+	ok, err := d.ownedBy(contract, owner, getOwner)(d)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if _, err = transfer(d.state.Contracts[contract], d.state.Contracts[owner]); err != nil {
+			return err
+		}
+	}
+	curr, err := getOwner(d.state.Contracts[contract])
+	if err != nil {
+		return err
+	}
+	if curr != common2.HexToAddress(d.state.Contracts[owner]) {
+		return fmt.Errorf("owner of %s %s is %s, not %s %s after transferring", contract,
+			d.state.Contracts[contract], curr.Hex(), owner, d.state.Contracts[owner])
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package main
+
+import (
+	olog "github.com/ontio/ontology/common/log"
+	"github.com/polynetwork/poly-io-test/cli"
+	"github.com/polynetwork/poly-io-test/cmd/pit/bootstrap"
+	"github.com/polynetwork/poly-io-test/cmd/pit/btc_prepare"
+	"github.com/polynetwork/poly-io-test/cmd/pit/btc_wallet"
+	"github.com/polynetwork/poly-io-test/cmd/pit/cctest"
+	"github.com/polynetwork/poly-io-test/cmd/pit/cosmos_prepare"
+	"github.com/polynetwork/poly-io-test/cmd/pit/eth_deployer"
+	"github.com/polynetwork/poly-io-test/cmd/pit/ont_deployer"
+	"github.com/polynetwork/poly-io-test/cmd/pit/tools"
+	"github.com/polynetwork/poly-io-test/config"
+	"github.com/polynetwork/poly-io-test/log"
+	"os"
+	"strings"
+)
+
+func setup(g *cli.Globals) error {
+	log.InitLog(g.LogLevel, log.Stdout)
+	olog.InitLog(g.LogLevel, olog.Stdout)
+	return config.DefConfig.Init(g.Conf)
+}
+
+func toolCmd(name, usage, tool string) *cli.Command {
+	return &cli.Command{
+		Name:  name,
+		Usage: usage,
+		Flags: tools.Flags,
+		Run: func(g *cli.Globals) error {
+			return tools.Run(g, tool)
+		},
+	}
+}
+
+func polyCmd() *cli.Command {
+	cmd := &cli.Command{Name: "poly", Usage: "govern poly and the side chains registered on it"}
+	for _, t := range tools.Tools {
+		if t.Name == "verify_bindings" {
+			continue
+		}
+		cmd.Subs = append(cmd.Subs, toolCmd(strings.Replace(t.Name, "_", "-", -1), t.Usage, t.Name))
+	}
+	cmd.Subs = append(cmd.Subs, &cli.Command{
+		Name:  "status",
+		Usage: "list side chains, relayers, pending proposals with approvals and the peer pool",
+		Flags: tools.GovStatusFlags,
+		Run:   tools.GovStatus,
+	}, &cli.Command{
+		Name: "gov",
+		Usage: "sign governance transactions offline: export the payload, sign it by every key holder, " +
+			"combine the copies and submit",
+		Subs: []*cli.Command{
+			{Name: "export", Usage: "write an unsigned governance payload",
+				Flags: tools.GovExportFlags, Run: tools.GovExport},
+			{Name: "sign", Usage: "sign the payload with one wallet, no config or connection needed",
+				Flags: tools.GovSignFlags, Run: tools.GovSign, Offline: true},
+			{Name: "combine", Usage: "merge payloads signed separately",
+				Flags: tools.GovCombineFlags, Run: tools.GovCombine, Offline: true},
+			{Name: "submit", Usage: "send the signed payload to poly",
+				Flags: tools.GovSubmitFlags, Run: tools.GovSubmit},
+		},
+	})
+	return cmd
+}
+
+func rootCmd() *cli.Command {
+	return &cli.Command{
+		Name:   "pit",
+		Usage:  "poly-io-test, deploy the cross chain environment and test it",
+		Before: setup,
+		Subs: []*cli.Command{
+			{
+				Name: "bootstrap",
+				Usage: "deploy and setup all chains and register them on poly in dependency order, " +
+					"skipping the steps finished before",
+				Flags: bootstrap.Flags,
+				Run:   bootstrap.Run,
+			},
+			{
+				Name:  "deploy",
+				Usage: "deploy contracts on a chain and save them into config",
+				Subs: []*cli.Command{
+					{Name: "eth", Usage: "deploy eccd, eccm, eccmp, lock proxy and assets on ethereum",
+						Flags: eth_deployer.Flags, Run: eth_deployer.Deploy},
+					{Name: "ont", Usage: "deploy lock proxy and assets on ontology",
+						Flags: ont_deployer.Flags, Run: ont_deployer.Deploy},
+					{Name: "btc", Usage: "setup the btc vendor and deploy btcx on ethereum and ontology",
+						Flags: btc_prepare.Flags, Run: btc_prepare.Deploy},
+				},
+			},
+			{
+				Name:  "setup",
+				Usage: "bind assets and lock proxies on a chain",
+				Subs: []*cli.Command{
+					{Name: "eth", Usage: "bind assets and lock proxies on ethereum",
+						Flags: eth_deployer.Flags, Run: eth_deployer.Setup},
+					{Name: "ont", Usage: "bind assets and lock proxies on ontology",
+						Flags: ont_deployer.Flags, Run: ont_deployer.Setup},
+					{Name: "cosmos", Usage: "create lock proxy and bind assets and btcx on cosmos",
+						Flags: cosmos_prepare.Flags, Run: cosmos_prepare.Setup},
+				},
+			},
+			polyCmd(),
+			{
+				Name:  "btc",
+				Usage: "reshape the utxos of BtcSignerPrivateKey for batch tests, and check or rotate the btc vendor",
+				Subs: []*cli.Command{
+					{Name: "split", Usage: "split utxos into outputs of the same value, mined on regtest",
+						Flags: btc_wallet.SplitFlags, Run: btc_wallet.Split},
+					{Name: "consolidate", Usage: "merge small utxos into one, mined on regtest",
+						Flags: btc_wallet.ConsolidateFlags, Run: btc_wallet.Consolidate},
+					{Name: "vendor", Usage: "compare utxos of the multisig on bitcoin and poly, " +
+						"and list unlock txs waiting for signatures", Flags: tools.VendorFlags, Run: tools.CheckVendor},
+					{Name: "rotate-vendor", Usage: "replace the vendor of BtcRedeem with a new one, rebinding btcx " +
+						"and moving the multisig utxos through poly", Flags: btc_prepare.RotateFlags, Run: btc_prepare.Rotate},
+				},
+			},
+			{
+				Name:  "verify",
+				Usage: "verify the environment",
+				Subs: []*cli.Command{
+					toolCmd("bindings", "verify lock proxy and btcx bindings on all chains", "verify_bindings"),
+					{Name: "headers", Usage: "compare headers synced on poly with the tips of side chains, " +
+						"and the poly epoch known by every chain", Flags: tools.HeadersFlags, Run: tools.VerifyHeaders},
+				},
+			},
+			{
+				Name:  "test",
+				Usage: "run cross chain test cases",
+				Subs: []*cli.Command{
+					{Name: "run", Usage: "run test cases and wait for the exit signal",
+						Flags: cctest.Flags, Run: cctest.Run},
+				},
+			},
+		},
+	}
+}
+
+func main() {
+	g := &cli.Globals{
+		Conf:     "./config.json",
+		Format:   "text",
+		LogLevel: log.InfoLog,
+	}
+	os.Exit(cli.Main(rootCmd(), g, os.Args[1:]))
+}
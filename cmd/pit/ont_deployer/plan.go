@@ -0,0 +1,250 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package ont_deployer
+
+import (
+	"encoding/hex"
+	"fmt"
+	common2 "github.com/ethereum/go-ethereum/common"
+	"github.com/ontio/ontology/common"
+	utils2 "github.com/ontio/ontology/smartcontract/service/native/utils"
+	"github.com/polynetwork/poly-io-test/chains/ont"
+	"github.com/polynetwork/poly-io-test/config"
+	"github.com/polynetwork/poly-io-test/log"
+	"github.com/polynetwork/poly-io-test/plan"
+	"math/big"
+	"strings"
+)
+
+const planChain = "ontology"
+
+func PlanDeploy(invoker *ont.OntInvoker) (*plan.Plan, error) {
+	p := plan.NewPlan("deploy contracts on ontology")
+	for _, name := range ont.ContractNames {
+		_, addr, deployed, err := invoker.GetContractAddress(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get address of %s: %v", name, err)
+		}
+		if deployed {
+			continue
+		}
+		p.Deploy(planChain, name, plan.NewArg("address", addr.ToHexString())).
+			SignedBy(invoker.OntAcc.Address.ToBase58())
+	}
+	return p, nil
+}
+
+// setupPlan lists the txs binding assets and proxies on the lock proxy, sent
+// by Setup or printed in plan mode
+func setupPlan(invoker *ont.OntInvoker) (*plan.Plan, error) {
+	p := plan.NewPlan("setup lock proxy on ontology")
+	for _, setup := range []func(*plan.Plan, *ont.OntInvoker) error{SetupOntAsset, SetupEthAsset,
+		SetOtherLockProxy} {
+		if err := setup(p, invoker); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// invokeSender returns the func invoking method of contract with params for plan.Tx.SentBy
+func invokeSender(invoker *ont.OntInvoker, contract common.Address, method string,
+	params []interface{}) func() (string, error) {
+	return func() (string, error) {
+		txhash, err := invoker.OntSdk.NeoVM.InvokeNeoVMContract(config.DefConfig.GasPrice, config.DefConfig.GasLimit,
+			invoker.OntAcc, invoker.OntAcc, contract, []interface{}{method, params})
+		if err != nil {
+			return "", err
+		}
+		invoker.WaitTxConfirmation(txhash)
+		return txhash.ToHexString(), nil
+	}
+}
+
+// skipDone makes send skipped if it fails for already done
+func skipDone(send func() (string, error)) func() (string, error) {
+	return func() (string, error) {
+		hash, err := send()
+		if err != nil && strings.Contains(err.Error(), "already") {
+			log.Warnf("already done: %v", err)
+			return "", nil
+		}
+		return hash, err
+	}
+}
+
+// invoke adds the tx invoking method of contract signed by the ontology account
+func invoke(p *plan.Plan, invoker *ont.OntInvoker, contract common.Address, method string,
+	args ...plan.Arg) *plan.Tx {
+	return p.Invoke(planChain, contract.ToHexString(), method, args...).SignedBy(invoker.OntAcc.Address.ToBase58())
+}
+
+// bindAsset adds the tx binding asset from on the lock proxy to asset to on chain toChainId
+func bindAsset(p *plan.Plan, invoker *ont.OntInvoker, proxy common.Address, from []byte, toChainId uint64,
+	to []byte, note string) {
+	invoke(p, invoker, proxy, "bindAssetHash",
+		plan.NewArg("fromAssetHash", from),
+		plan.NewArg("toChainId", toChainId),
+		plan.NewArg("toAssetHash", to)).WithNote(note).
+		SentBy(skipDone(invokeSender(invoker, proxy, "bindAssetHash", []interface{}{from, toChainId, to})))
+}
+
+// delegateToProxy adds the tx delegating amount of asset to proxy, skipped if
+// proxy already holds some
+func delegateToProxy(p *plan.Plan, invoker *ont.OntInvoker, proxy, asset common.Address, amount *big.Int,
+	note string) {
+	send := invokeSender(invoker, asset, "delegateToProxy", []interface{}{proxy[:], amount})
+	invoke(p, invoker, asset, "delegateToProxy",
+		plan.NewArg("proxy", proxy[:]),
+		plan.NewArg("amount", amount)).WithNote(note).
+		SentBy(func() (string, error) {
+			res, err := invoker.OntSdk.NeoVM.PreExecInvokeNeoVMContract(asset,
+				[]interface{}{"balanceOf", []interface{}{proxy[:]}})
+			if err != nil {
+				return "", fmt.Errorf("failed to check the balance of proxy: %v", err)
+			}
+			val, err := res.Result.ToInteger()
+			if err != nil {
+				return "", fmt.Errorf("failed to get value from result: %v", err)
+			}
+			if val.Sign() > 0 {
+				return "", nil
+			}
+			return send()
+		})
+}
+
+// ontAddr decodes the ontology address val of config field name
+func ontAddr(name, val string) (common.Address, error) {
+	addr, err := common.AddressFromHexString(val)
+	if err != nil {
+		return common.ADDRESS_EMPTY, fmt.Errorf("failed to decode %s '%s': %v", name, val, err)
+	}
+	return addr, nil
+}
+
+func ethAddr(val string) []byte {
+	return common2.HexToAddress(val).Bytes()
+}
+
+// SetupOntAsset adds the txs binding ont, ong and oep4 to p
+func SetupOntAsset(p *plan.Plan, invoker *ont.OntInvoker) error {
+	proxy, err := ontAddr("OntLockProxy", config.DefConfig.OntLockProxy)
+	if err != nil {
+		return err
+	}
+	bindAsset(p, invoker, proxy, utils2.OntContractAddress[:], config.ETH_CHAIN_ID, ethAddr(config.DefConfig.EthOntx),
+		"ont of ethereum")
+	bindAsset(p, invoker, proxy, utils2.OntContractAddress[:], config.DefConfig.CMCrossChainId,
+		[]byte(config.CM_ONT), "ont of cosmos")
+	bindAsset(p, invoker, proxy, utils2.OngContractAddress[:], config.ETH_CHAIN_ID, ethAddr(config.DefConfig.EthOngx),
+		"ong of ethereum")
+	bindAsset(p, invoker, proxy, utils2.OngContractAddress[:], config.DefConfig.CMCrossChainId,
+		[]byte(config.CM_ONG), "ong of cosmos")
+	oep4, err := ontAddr("OntOep4", config.DefConfig.OntOep4)
+	if err != nil {
+		return err
+	}
+	bindAsset(p, invoker, proxy, oep4[:], config.ETH_CHAIN_ID, ethAddr(config.DefConfig.EthOep4), "oep4 of ethereum")
+	bindAsset(p, invoker, proxy, oep4[:], config.DefConfig.CMCrossChainId, []byte(config.CM_OEP4),
+		"oep4 of cosmos")
+
+	// oep4 can only be inited once, and the approve goes with it
+	inited := false
+	initOep4 := invokeSender(invoker, oep4, "init", []interface{}{})
+	invoke(p, invoker, oep4, "init").WithNote("skipped with the approve below if oep4 is already inited").
+		SentBy(func() (string, error) {
+			hash, err := initOep4()
+			if err != nil {
+				log.Errorf("init oep4 error, maybe already inited and approved: %s", err)
+				return "", nil
+			}
+			inited = true
+			return hash, nil
+		})
+	approve := invokeSender(invoker, oep4, "approve",
+		[]interface{}{invoker.OntAcc.Address[:], proxy[:], big.NewInt(1e13)})
+	invoke(p, invoker, oep4, "approve",
+		plan.NewArg("owner", invoker.OntAcc.Address[:]),
+		plan.NewArg("spender", proxy[:]),
+		plan.NewArg("amount", big.NewInt(1e13))).
+		SentBy(func() (string, error) {
+			if !inited {
+				return "", nil
+			}
+			return approve()
+		})
+	return nil
+}
+
+// SetupEthAsset adds the txs delegating and binding ethx and erc20x to p
+func SetupEthAsset(p *plan.Plan, invoker *ont.OntInvoker) error {
+	proxy, err := ontAddr("OntLockProxy", config.DefConfig.OntLockProxy)
+	if err != nil {
+		return err
+	}
+	etho, err := ontAddr("OntEth", config.DefConfig.OntEth)
+	if err != nil {
+		return err
+	}
+	delegateToProxy(p, invoker, proxy, etho, big.NewInt(1e18), "only if lock proxy holds no ethx")
+	bindAsset(p, invoker, proxy, etho[:], config.ETH_CHAIN_ID, make([]byte, 20), "eth of ethereum")
+	bindAsset(p, invoker, proxy, etho[:], config.DefConfig.CMCrossChainId, []byte(config.CM_ETHX), "eth of cosmos")
+	erc20o, err := ontAddr("OntErc20", config.DefConfig.OntErc20)
+	if err != nil {
+		return err
+	}
+	delegateToProxy(p, invoker, proxy, erc20o, big.NewInt(1e13), "only if lock proxy holds no erc20x")
+	erc20, err := hex.DecodeString(strings.Replace(config.DefConfig.EthErc20, "0x", "", 1))
+	if err != nil {
+		return fmt.Errorf("failed to decode EthErc20: %v", err)
+	}
+	bindAsset(p, invoker, proxy, erc20o[:], config.ETH_CHAIN_ID, erc20, "erc20 of ethereum")
+	bindAsset(p, invoker, proxy, erc20o[:], config.DefConfig.CMCrossChainId, []byte(config.CM_ERC20),
+		"erc20 of cosmos")
+	return nil
+}
+
+// SetOtherLockProxy adds the txs binding the lock proxies of ethereum and cosmos to p
+func SetOtherLockProxy(p *plan.Plan, invoker *ont.OntInvoker) error {
+	proxy, err := ontAddr("OntLockProxy", config.DefConfig.OntLockProxy)
+	if err != nil {
+		return err
+	}
+	if config.DefConfig.EthLockProxy == "" {
+		return fmt.Errorf("EthLockProxy is blank")
+	}
+	cmProxy, err := hex.DecodeString(config.DefConfig.CMLockProxy)
+	if err != nil {
+		return fmt.Errorf("failed to decode proxy: %v", err)
+	}
+	for _, other := range []struct {
+		chainId uint64
+		proxy   []byte
+		note    string
+	}{
+		{config.ETH_CHAIN_ID, ethAddr(config.DefConfig.EthLockProxy), "eth proxy"},
+		{config.DefConfig.CMCrossChainId, cmProxy, "cosmos proxy"},
+	} {
+		invoke(p, invoker, proxy, "bindProxyHash",
+			plan.NewArg("toChainId", other.chainId),
+			plan.NewArg("targetProxyHash", other.proxy)).WithNote(other.note).
+			SentBy(skipDone(invokeSender(invoker, proxy, "bindProxyHash",
+				[]interface{}{other.chainId, other.proxy})))
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package ont_deployer
+
+import (
+	"flag"
+	"fmt"
+	"github.com/ontio/ontology/common"
+	"github.com/polynetwork/poly-io-test/chains/ont"
+	"github.com/polynetwork/poly-io-test/cli"
+	"github.com/polynetwork/poly-io-test/config"
+	"github.com/polynetwork/poly-io-test/plan"
+	"os"
+)
+
+var planMode bool
+
+// Flags binds the flags of deploy and setup on ontology
+func Flags(fs *flag.FlagSet) {
+	fs.BoolVar(&planMode, "plan", false, "only print the transactions to send without broadcasting them")
+}
+
+func newInvoker() (*ont.OntInvoker, error) {
+	return ont.NewOntInvoker(config.DefConfig.OntJsonRpcAddress, config.DefConfig.OntContractsAvmPath,
+		config.DefConfig.OntWallet, config.DefConfig.OntWalletPassword)
+}
+
+func printPlan(p *plan.Plan, err error, format string) error {
+	if err != nil {
+		return err
+	}
+	return p.Print(os.Stdout, format)
+}
+
+// Deploy deploys all contracts on ontology and saves them into config
+func Deploy(g *cli.Globals) error {
+	invoker, err := newInvoker()
+	if err != nil {
+		return err
+	}
+	if planMode {
+		p, err := PlanDeploy(invoker)
+		return printPlan(p, err, g.Format)
+	}
+	addrs, err := invoker.DeployContracts()
+	if err != nil {
+		return err
+	}
+
+	config.DefConfig.OntLockProxy = addrs[2].ToHexString()
+	config.DefConfig.OntErc20 = addrs[3].ToHexString()
+	config.DefConfig.OntOep4 = addrs[4].ToHexString()
+	config.DefConfig.OntEth = addrs[5].ToHexString()
+
+	err = config.DefConfig.Save(g.Conf)
+	if err != nil {
+		return fmt.Errorf("failed to save config: %v", err)
+	}
+
+	fmt.Println(GetInfo(addrs))
+	return nil
+}
+
+// Setup binds assets and proxies on ontology lock proxy
+func Setup(g *cli.Globals) error {
+	invoker, err := newInvoker()
+	if err != nil {
+		return err
+	}
+	p, err := setupPlan(invoker)
+	if err != nil {
+		return err
+	}
+	if planMode {
+		return p.Print(os.Stdout, g.Format)
+	}
+	return p.Run(os.Stdout)
+}
+
+func GetInfo(addrs []common.Address) string {
+	str := "=============================ONT info=============================\n"
+	for i, name := range ont.ContractNames {
+		str += fmt.Sprintf("{contract_name: %s, address: %s}\n", name, addrs[i].ToHexString())
+	}
+	str += "=================================================================="
+	return str
+}
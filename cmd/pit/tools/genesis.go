@@ -0,0 +1,607 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	common3 "github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/ontio/ontology-go-sdk"
+	common2 "github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/smartcontract/service/native/cross_chain/header_sync"
+	utils2 "github.com/ontio/ontology/smartcontract/service/native/utils"
+	"github.com/polynetwork/cosmos-poly-module/headersync"
+	"github.com/polynetwork/poly-go-sdk"
+	"github.com/polynetwork/poly-io-test/chains/btc"
+	cosmos2 "github.com/polynetwork/poly-io-test/chains/cosmos"
+	"github.com/polynetwork/poly-io-test/chains/eth"
+	"github.com/polynetwork/poly-io-test/chains/eth/abi/eccd"
+	"github.com/polynetwork/poly-io-test/chains/eth/abi/eccm"
+	"github.com/polynetwork/poly-io-test/chains/ont"
+	"github.com/polynetwork/poly-io-test/config"
+	"github.com/polynetwork/poly-io-test/log"
+	"github.com/polynetwork/poly-io-test/testcase"
+	"github.com/polynetwork/poly/common"
+	"github.com/polynetwork/poly/consensus/vbft/config"
+	hsbtc "github.com/polynetwork/poly/native/service/header_sync/btc"
+	hscom "github.com/polynetwork/poly/native/service/header_sync/common"
+	"github.com/polynetwork/poly/native/service/header_sync/cosmos"
+	"github.com/polynetwork/poly/native/service/utils"
+	"math/big"
+	"strings"
+)
+
+var (
+	btcGenesisHeight    int64
+	ethGenesisHeight    int64
+	ontGenesisHeight    int64
+	cosmosGenesisHeight int64
+	polyGenesisHeight   int64
+)
+
+// genesis is a header of one chain to sync to another as the genesis
+type genesis struct {
+	what   string
+	height uint64
+	// the height is given by flag or config, so the stored one must be it
+	pinned bool
+	// epoch switches move the stored height forward
+	movable bool
+	// stored reads the genesis stored on the target chain
+	stored func() (found bool, height uint64, hash string, err error)
+	// hashAt returns the hash of the source chain at height, nil if the
+	// target stores no hash
+	hashAt func(height uint64) (string, error)
+}
+
+func (g *genesis) verify(height uint64, hash string) error {
+	if g.hashAt != nil {
+		actual, err := g.hashAt(height)
+		if err != nil {
+			return fmt.Errorf("%s: failed to get the source header at %d: %v", g.what, height, err)
+		}
+		if actual != hash {
+			return fmt.Errorf("%s drifted: stored %s at height %d but the source chain has %s", g.what, hash,
+				height, actual)
+		}
+	}
+	if g.pinned && (height < g.height || !g.movable && height != g.height) {
+		return fmt.Errorf("%s drifted: stored at height %d but %d is intended", g.what, height, g.height)
+	}
+	return nil
+}
+
+// sync submits the genesis header only if nothing is stored on the target.
+// If anything is stored, it must match the source chain and the intended
+// height, otherwise it refuses to go on. After submitting, the stored one is
+// verified again.
+func (g *genesis) sync(submit func() error) error {
+	found, height, hash, err := g.stored()
+	if err != nil {
+		return fmt.Errorf("%s: failed to read the stored genesis: %v", g.what, err)
+	}
+	if found {
+		if err = g.verify(height, hash); err != nil {
+			return fmt.Errorf("%v, refuse to sync", err)
+		}
+		log.Infof("%s is already synced at height %d, skip it", g.what, height)
+		return nil
+	}
+	if err = submit(); err != nil {
+		return fmt.Errorf("%s: %v", g.what, err)
+	}
+	found, height, hash, err = g.stored()
+	if err != nil {
+		return fmt.Errorf("%s: failed to read the stored genesis after sync: %v", g.what, err)
+	}
+	if !found {
+		return fmt.Errorf("%s: nothing stored after sync", g.what)
+	}
+	if height != g.height {
+		return fmt.Errorf("%s: stored at height %d after sync but %d is submitted", g.what, height, g.height)
+	}
+	return g.verify(height, hash)
+}
+
+func heightOr(flagVal int64, def uint64) (uint64, bool) {
+	if flagVal < 0 {
+		return def, false
+	}
+	return uint64(flagVal), true
+}
+
+func polyStorage(poly *poly_go_sdk.PolySdk, prefix string, keys ...[]byte) ([]byte, error) {
+	key := []byte(prefix)
+	for _, k := range keys {
+		key = append(key, k...)
+	}
+	return poly.GetStorage(utils.HeaderSyncContractAddress.ToHexString(), key)
+}
+
+func polyHash(poly *poly_go_sdk.PolySdk, height uint64) (string, error) {
+	hdr, err := poly.GetHeaderByHeight(uint32(height))
+	if err != nil {
+		return "", err
+	}
+	hash := hdr.Hash()
+	return hash.ToHexString(), nil
+}
+
+// polyBookkeepers returns the consensus keys set in the poly key header at
+// height, in the form ECCM takes
+func polyBookkeepers(poly *poly_go_sdk.PolySdk, height uint32) ([]byte, error) {
+	gB, err := poly.GetBlockByHeight(height)
+	if err != nil {
+		return nil, err
+	}
+	info := &vconfig.VbftBlockInfo{}
+	if err := json.Unmarshal(gB.Header.ConsensusPayload, info); err != nil {
+		return nil, fmt.Errorf("unmarshal blockInfo error: %s", err)
+	}
+	if info.NewChainConfig == nil {
+		return nil, fmt.Errorf("poly header at %d is not a key header", height)
+	}
+	var bookkeepers []keypair.PublicKey
+	for _, peer := range info.NewChainConfig.Peers {
+		keystr, _ := hex.DecodeString(peer.ID)
+		key, _ := keypair.DeserializePublicKey(keystr)
+		bookkeepers = append(bookkeepers, key)
+	}
+	bookkeepers = keypair.SortPublicKeys(bookkeepers)
+
+	publickeys := make([]byte, 0)
+	for _, key := range bookkeepers {
+		publickeys = append(publickeys, ont.GetOntNoCompressKey(key)...)
+	}
+	return publickeys, nil
+}
+
+// SyncGenesisHeaders syncs genesis headers of all side chains to poly and
+// the poly one to them. A chain drifted from the intended genesis is
+// reported and skipped, the others still go on.
+func SyncGenesisHeaders(poly *poly_go_sdk.PolySdk, acc *poly_go_sdk.Account, accArr []*poly_go_sdk.Account) error {
+	failed := make([]string, 0)
+	for _, f := range []func() error{
+		func() error { return SyncBtcGenesisHeader(poly, acc) },
+		func() error { return SyncEthGenesisHeader(poly, accArr) },
+		func() error { return SyncOntGenesisHeader(poly, accArr) },
+		func() error { return SyncCosmosGenesisHeader(poly, accArr) },
+	} {
+		if err := f(); err != nil {
+			log.Error(err)
+			failed = append(failed, err.Error())
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to sync genesis headers:\n\t%s", strings.Join(failed, "\n\t"))
+	}
+	return nil
+}
+
+func SyncBtcGenesisHeader(poly *poly_go_sdk.PolySdk, acc *poly_go_sdk.Account) error {
+	cli := btc.NewRestCli(config.DefConfig.BtcRestAddr, config.DefConfig.BtcRestUser, config.DefConfig.BtcRestPwd)
+	curr, _, err := cli.GetCurrentHeightAndHash()
+	if err != nil {
+		return fmt.Errorf("SyncBtcGenesisHeader failed: %v", err)
+	}
+	start, pinned := heightOr(btcGenesisHeight, uint64(curr-curr%2016))
+	g := &genesis{
+		what:   "btc genesis on poly",
+		height: start,
+		pinned: pinned,
+		stored: func() (bool, uint64, string, error) {
+			raw, err := polyStorage(poly, hscom.GENESIS_HEADER, utils.GetUint64Bytes(config.BTC_CHAIN_ID))
+			if err != nil || len(raw) == 0 {
+				return false, 0, "", err
+			}
+			hdr := new(hsbtc.StoredHeader)
+			if err = hdr.Deserialization(common.NewZeroCopySource(raw)); err != nil {
+				return false, 0, "", err
+			}
+			return true, uint64(hdr.Height), hdr.Header.BlockHash().String(), nil
+		},
+		hashAt: func(height uint64) (string, error) {
+			hdr, err := cli.GetHeader(int32(height))
+			if err != nil {
+				return "", err
+			}
+			return hdr.BlockHash().String(), nil
+		},
+	}
+	return g.sync(func() error {
+		hdr, err := cli.GetHeader(int32(start))
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err = hdr.BtcEncode(&buf, wire.ProtocolVersion, wire.LatestEncoding); err != nil {
+			return err
+		}
+		hb := make([]byte, 4)
+		binary.BigEndian.PutUint32(hb, uint32(start))
+		txhash, err := poly.Native.Hs.SyncGenesisHeader(config.BTC_CHAIN_ID, append(buf.Bytes(), hb...),
+			[]*poly_go_sdk.Account{acc})
+		if err != nil {
+			return err
+		}
+		testcase.WaitPolyTx(txhash, poly)
+		blkHash := hdr.BlockHash()
+		log.Infof("successful to sync btc genesis header: ( height: %d, block_hash: %s, txhash: %s )", start,
+			blkHash.String(), txhash.ToHexString())
+		return nil
+	})
+}
+
+func SyncEthGenesisHeader(poly *poly_go_sdk.PolySdk, accArr []*poly_go_sdk.Account) error {
+	tool := eth.NewEthTools(config.DefConfig.EthURL)
+	curr, err := tool.GetNodeHeight()
+	if err != nil {
+		return err
+	}
+	height, pinned := heightOr(ethGenesisHeight, curr)
+	ethHash := func(height uint64) (string, error) {
+		hdr, err := tool.GetBlockHeader(height)
+		if err != nil {
+			return "", err
+		}
+		return hdr.Hash().String(), nil
+	}
+	g := &genesis{
+		what:   "eth genesis on poly",
+		height: height,
+		pinned: pinned,
+		stored: func() (bool, uint64, string, error) {
+			raw, err := polyStorage(poly, hscom.GENESIS_HEADER, utils.GetUint64Bytes(config.ETH_CHAIN_ID))
+			if err != nil || len(raw) == 0 {
+				return false, 0, "", err
+			}
+			stored := &struct {
+				Header ethtypes.Header `json:"header"`
+			}{}
+			if err = json.Unmarshal(raw, stored); err != nil {
+				return false, 0, "", err
+			}
+			return true, stored.Header.Number.Uint64(), stored.Header.Hash().String(), nil
+		},
+		hashAt: ethHash,
+	}
+	err = g.sync(func() error {
+		hdr, err := tool.GetBlockHeader(height)
+		if err != nil {
+			return err
+		}
+		raw, err := hdr.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		txhash, err := poly.Native.Hs.SyncGenesisHeader(config.ETH_CHAIN_ID, raw, accArr)
+		if err != nil {
+			return err
+		}
+		testcase.WaitPolyTx(txhash, poly)
+		log.Infof("successful to sync eth genesis header: (height: %d, blk_hash: %s, txhash: %s )", height,
+			hdr.Hash().String(), txhash.ToHexString())
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	polyHeight, _ := heightOr(polyGenesisHeight, uint64(config.DefConfig.RCEpoch))
+	eccdContract, err := eccd.NewEthCrossChainData(common3.HexToAddress(config.DefConfig.Eccd), tool.GetEthClient())
+	if err != nil {
+		return err
+	}
+	g = &genesis{
+		what:    "poly genesis on ethereum",
+		height:  polyHeight,
+		pinned:  true,
+		movable: true,
+		stored: func() (bool, uint64, string, error) {
+			pks, err := eccdContract.GetCurEpochConPubKeyBytes(&bind.CallOpts{})
+			if err != nil || len(pks) == 0 {
+				return false, 0, "", err
+			}
+			height, err := eccdContract.GetCurEpochStartHeight(&bind.CallOpts{})
+			if err != nil {
+				return false, 0, "", err
+			}
+			return true, uint64(height), hex.EncodeToString(pks), nil
+		},
+		hashAt: func(height uint64) (string, error) {
+			pks, err := polyBookkeepers(poly, uint32(height))
+			if err != nil {
+				return "", err
+			}
+			return hex.EncodeToString(pks), nil
+		},
+	}
+	return g.sync(func() error {
+		eccmContract, err := eccm.NewEthCrossChainManager(common3.HexToAddress(config.DefConfig.Eccm),
+			tool.GetEthClient())
+		if err != nil {
+			return err
+		}
+		signer, err := eth.NewEthSigner(config.DefConfig.ETHPrivateKey)
+		if err != nil {
+			return err
+		}
+		nonce := eth.NewNonceManager(tool.GetEthClient()).GetAddressNonce(signer.Address)
+		gasPrice, err := tool.GetEthClient().SuggestGasPrice(context.Background())
+		if err != nil {
+			return fmt.Errorf("get suggest gas price failed error: %s", err.Error())
+		}
+		gasPrice = gasPrice.Mul(gasPrice, big.NewInt(5))
+		auth := testcase.MakeEthAuth(signer, nonce, gasPrice.Uint64(), uint64(8000000))
+
+		gB, err := poly.GetBlockByHeight(uint32(polyHeight))
+		if err != nil {
+			return err
+		}
+		publickeys, err := polyBookkeepers(poly, uint32(polyHeight))
+		if err != nil {
+			return err
+		}
+		tx, err := eccmContract.InitGenesisBlock(auth, gB.Header.ToArray(), publickeys)
+		if err != nil {
+			return fmt.Errorf("failed to init genesis block on eccm: %v", err)
+		}
+		tool.WaitTransactionConfirm(tx.Hash())
+		log.Infof("successful to sync poly genesis header to Ethereum: ( txhash: %s )", tx.Hash().String())
+		return nil
+	})
+}
+
+func SyncOntGenesisHeader(poly *poly_go_sdk.PolySdk, accArr []*poly_go_sdk.Account) error {
+	ontCli := ontology_go_sdk.NewOntologySdk()
+	ontCli.NewRpcClient().SetAddress(config.DefConfig.OntJsonRpcAddress)
+
+	height, _ := heightOr(ontGenesisHeight, uint64(config.DefConfig.OntEpoch))
+	ontChainId := utils.GetUint64Bytes(config.ONT_CHAIN_ID)
+	g := &genesis{
+		what:   "ontology genesis on poly",
+		height: height,
+		pinned: true,
+		// poly keeps no genesis of ontology, so look for the intended
+		// height and complain if headers start elsewhere
+		stored: func() (bool, uint64, string, error) {
+			raw, err := polyStorage(poly, hscom.HEADER_INDEX, ontChainId, utils.GetUint32Bytes(uint32(height)))
+			if err != nil {
+				return false, 0, "", err
+			}
+			if len(raw) > 0 {
+				hash, err := common.Uint256ParseFromBytes(raw)
+				if err != nil {
+					return false, 0, "", err
+				}
+				return true, height, hash.ToHexString(), nil
+			}
+			raw, err = polyStorage(poly, hscom.CURRENT_HEADER_HEIGHT, ontChainId)
+			if err != nil || len(raw) == 0 {
+				return false, 0, "", err
+			}
+			return false, 0, "", fmt.Errorf("headers on poly start at another height, current is %d and %d "+
+				"is not found", utils.GetBytesUint32(raw), height)
+		},
+		hashAt: func(height uint64) (string, error) {
+			blk, err := ontCli.GetBlockByHeight(uint32(height))
+			if err != nil {
+				return "", err
+			}
+			hash := blk.Hash()
+			return hash.ToHexString(), nil
+		},
+	}
+	err := g.sync(func() error {
+		genesisBlock, err := ontCli.GetBlockByHeight(uint32(height))
+		if err != nil {
+			return err
+		}
+		txhash, err := poly.Native.Hs.SyncGenesisHeader(config.ONT_CHAIN_ID, genesisBlock.Header.ToArray(), accArr)
+		if err != nil {
+			return err
+		}
+		testcase.WaitPolyTx(txhash, poly)
+		log.Infof("successful to sync ont genesis header: ( height: %d, txhash: %s )", height, txhash.ToHexString())
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	polyHeight, _ := heightOr(polyGenesisHeight, uint64(config.DefConfig.RCEpoch))
+	polyChainId, err := utils2.GetUint64Bytes(poly.ChainId)
+	if err != nil {
+		return err
+	}
+	g = &genesis{
+		what:   "poly genesis on ontology",
+		height: polyHeight,
+		pinned: true,
+		stored: func() (bool, uint64, string, error) {
+			raw, err := ontCli.GetStorage(utils2.HeaderSyncContractAddress.ToHexString(),
+				append([]byte(header_sync.KEY_HEIGHTS), polyChainId...))
+			if err != nil || len(raw) == 0 {
+				return false, 0, "", err
+			}
+			heights := new(header_sync.KeyHeights)
+			if err = heights.Deserialization(common2.NewZeroCopySource(raw)); err != nil {
+				return false, 0, "", err
+			}
+			if len(heights.HeightList) == 0 {
+				return false, 0, "", nil
+			}
+			first := heights.HeightList[0]
+			hb, _ := utils2.GetUint32Bytes(first)
+			raw, err = ontCli.GetStorage(utils2.HeaderSyncContractAddress.ToHexString(),
+				append(append([]byte(header_sync.HEADER_INDEX), polyChainId...), hb...))
+			if err != nil {
+				return false, 0, "", err
+			}
+			hash, err := common.Uint256ParseFromBytes(raw)
+			if err != nil {
+				return false, 0, "", err
+			}
+			return true, uint64(first), hash.ToHexString(), nil
+		},
+		hashAt: func(height uint64) (string, error) {
+			return polyHash(poly, height)
+		},
+	}
+	return g.sync(func() error {
+		ow := strings.Split(oWalletFiles, ",")
+		op := strings.Split(oPwds, ",")
+		oAccArr := make([]*ontology_go_sdk.Account, len(ow))
+		pks := make([]keypair.PublicKey, len(ow))
+		for i, v := range ow {
+			oAccArr[i], err = ont.GetOntAccByPwd(v, op[i])
+			if err != nil {
+				return fmt.Errorf("failed to decode no%d wallet %s with pwd %s", i, ow[i], op[i])
+			}
+			pks[i] = oAccArr[i].PublicKey
+		}
+		gB, err := poly.GetBlockByHeight(uint32(polyHeight))
+		if err != nil {
+			return err
+		}
+		txHash, err := InvokeNativeContractWithMultiSign(ontCli, 0, 2000000, pks, oAccArr, byte(0),
+			utils2.HeaderSyncContractAddress, header_sync.SYNC_GENESIS_HEADER,
+			[]interface{}{
+				&header_sync.SyncGenesisHeaderParam{
+					GenesisHeader: gB.Header.ToArray(),
+				}})
+		if err != nil {
+			return fmt.Errorf("faild to sync poly header to ontology: %v", err)
+		}
+		ont.WaitOntTx(txHash, ontCli)
+		log.Infof("successful to sync poly genesis header to Ontology: ( txhash: %s )", txHash.ToHexString())
+		return nil
+	})
+}
+
+func SyncCosmosGenesisHeader(poly *poly_go_sdk.PolySdk, accArr []*poly_go_sdk.Account) error {
+	invoker, err := cosmos2.NewCosmosInvoker()
+	if err != nil {
+		return err
+	}
+
+	height, _ := heightOr(cosmosGenesisHeight, uint64(config.DefConfig.CMEpoch))
+	g := &genesis{
+		what:    "cosmos genesis on poly",
+		height:  height,
+		pinned:  true,
+		movable: true,
+		stored: func() (bool, uint64, string, error) {
+			raw, err := polyStorage(poly, hscom.EPOCH_SWITCH, utils.GetUint64Bytes(config.DefConfig.CMCrossChainId))
+			if err != nil || len(raw) == 0 {
+				return false, 0, "", err
+			}
+			info := new(cosmos.CosmosEpochSwitchInfo)
+			if err = info.Deserialization(common.NewZeroCopySource(raw)); err != nil {
+				return false, 0, "", err
+			}
+			return true, uint64(info.Height), hex.EncodeToString(info.BlockHash), nil
+		},
+		hashAt: func(height uint64) (string, error) {
+			h := int64(height)
+			res, err := invoker.RpcCli.Commit(&h)
+			if err != nil {
+				return "", err
+			}
+			return hex.EncodeToString(res.Header.Hash()), nil
+		},
+	}
+	err = g.sync(func() error {
+		h := int64(height)
+		res, err := invoker.RpcCli.Commit(&h)
+		if err != nil {
+			return err
+		}
+		vals, err := getValidators(invoker.RpcCli, h)
+		if err != nil {
+			return err
+		}
+		ch := &cosmos.CosmosHeader{
+			Header:  *res.Header,
+			Commit:  res.Commit,
+			Valsets: vals,
+		}
+		raw, err := invoker.CMCdc.MarshalBinaryBare(ch)
+		if err != nil {
+			return err
+		}
+		txhash, err := poly.Native.Hs.SyncGenesisHeader(config.DefConfig.CMCrossChainId, raw, accArr)
+		if err != nil {
+			return err
+		}
+		testcase.WaitPolyTx(txhash, poly)
+		log.Infof("successful to sync cosmos genesis header: ( height: %d, txhash: %s )", height,
+			txhash.ToHexString())
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	polyHeight, _ := heightOr(polyGenesisHeight, uint64(config.DefConfig.RCEpoch))
+	g = &genesis{
+		what:    "poly genesis on cosmos",
+		height:  polyHeight,
+		pinned:  true,
+		movable: true,
+		// cosmos keeps only the height of current poly epoch
+		stored: func() (bool, uint64, string, error) {
+			param, err := invoker.CMCdc.MarshalJSON(headersync.NewQueryConsensusPeersParams(poly.ChainId))
+			if err != nil {
+				return false, 0, "", err
+			}
+			res, err := invoker.RpcCli.ABCIQuery(fmt.Sprintf("custom/%s/%s", headersync.QuerierRoute,
+				headersync.QueryConsensusPeers), param)
+			if err != nil {
+				return false, 0, "", err
+			}
+			if !res.Response.IsOK() || len(res.Response.Value) == 0 {
+				return false, 0, "", nil
+			}
+			peers := new(headersync.ConsensusPeers)
+			if err = peers.Deserialization(common.NewZeroCopySource(res.Response.Value)); err != nil {
+				return false, 0, "", err
+			}
+			return true, uint64(peers.Height), "", nil
+		},
+	}
+	return g.sync(func() error {
+		header, err := poly.GetHeaderByHeight(uint32(polyHeight))
+		if err != nil {
+			return err
+		}
+		tx, err := invoker.SyncPolyGenesisHdr(invoker.Acc.Acc, header.ToArray())
+		if err != nil {
+			return err
+		}
+		log.Infof("successful to sync poly genesis header to cosmos: ( txhash: %s )", tx.Hash.String())
+		return nil
+	})
+}
@@ -0,0 +1,244 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package tools
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/polynetwork/poly-go-sdk"
+	"github.com/polynetwork/poly-io-test/chains/btc"
+	"github.com/polynetwork/poly-io-test/cli"
+	"github.com/polynetwork/poly-io-test/config"
+	"github.com/polynetwork/poly-io-test/gov"
+	"github.com/polynetwork/poly-io-test/log"
+	"github.com/polynetwork/poly-io-test/testcase"
+	"github.com/polynetwork/poly/common"
+	"github.com/polynetwork/poly/native/service/governance/node_manager"
+	"os"
+	"sort"
+	"strings"
+)
+
+var (
+	govAction     string
+	govId         uint64
+	govPeerPubkey string
+	govPubKeys    string
+	govIn         string
+	govOut        string
+	govWallet     string
+	govPwd        string
+	govMaxChainId uint64
+	govRelayers   string
+)
+
+// GovExportFlags binds the flags of gov export
+func GovExportFlags(fs *flag.FlagSet) {
+	fs.StringVar(&govAction, "action", "", "governance action: "+strings.Join(gov.Actions, ", "))
+	fs.Uint64Var(&govId, "id", 0, "chain id to approve for side chain actions, or apply id for approve_relayer")
+	fs.StringVar(&govPeerPubkey, "peer_pubkey", "", "peer pubkey of candidate for approve_candidate")
+	fs.StringVar(&govPubKeys, "pubkeys", "", "consensus pubkeys in hex sep by ',' for commit_dpos, "+
+		"consensus peers on poly by default")
+	fs.StringVar(&govOut, "out", "./gov_payload.json", "file to write the unsigned payload")
+}
+
+// GovSignFlags binds the flags of gov sign
+func GovSignFlags(fs *flag.FlagSet) {
+	fs.StringVar(&govIn, "in", "./gov_payload.json", "payload file to sign")
+	fs.StringVar(&govOut, "out", "", "file to write the signed payload, overwrite the input by default")
+	fs.StringVar(&govWallet, "wallet", "", "poly wallet file of the key holder")
+	fs.StringVar(&govPwd, "pwd", "", "password of the wallet")
+}
+
+// GovCombineFlags binds the flags of gov combine
+func GovCombineFlags(fs *flag.FlagSet) {
+	fs.StringVar(&govIn, "in", "", "signed payload files sep by ','")
+	fs.StringVar(&govOut, "out", "./gov_payload.json", "file to write the combined payload")
+}
+
+// GovSubmitFlags binds the flags of gov submit
+func GovSubmitFlags(fs *flag.FlagSet) {
+	fs.StringVar(&govIn, "in", "./gov_payload.json", "signed payload file to submit")
+}
+
+// GovStatusFlags binds the flags of gov status
+func GovStatusFlags(fs *flag.FlagSet) {
+	fs.Uint64Var(&govMaxChainId, "max_chain_id", 20, "side chains with id up to this are looked up, "+
+		"besides the ones in config")
+	fs.StringVar(&govRelayers, "relayers", "", "relayer addresses in base58 sep by ',' to check, "+
+		"besides the ones in pending applications")
+}
+
+func consensusPubKeys(poly *poly_go_sdk.PolySdk) ([]keypair.PublicKey, error) {
+	var strs []string
+	if govPubKeys != "" {
+		strs = strings.Split(govPubKeys, ",")
+	} else {
+		_, m, err := gov.GetPeerPool(poly)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get peer pool: %v", err)
+		}
+		peers := make([]*node_manager.PeerPoolItem, 0)
+		for _, v := range m.PeerPoolMap {
+			if v.Status == node_manager.ConsensusStatus {
+				peers = append(peers, v)
+			}
+		}
+		sort.Slice(peers, func(i, j int) bool {
+			return peers[i].Index < peers[j].Index
+		})
+		for _, v := range peers {
+			strs = append(strs, v.PeerPubkey)
+		}
+	}
+	pks := make([]keypair.PublicKey, len(strs))
+	for i, str := range strs {
+		raw, err := hex.DecodeString(str)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode pubkey %s: %v", str, err)
+		}
+		if pks[i], err = keypair.DeserializePublicKey(raw); err != nil {
+			return nil, fmt.Errorf("failed to deserialize pubkey %s: %v", str, err)
+		}
+	}
+	return pks, nil
+}
+
+// GovExport writes an unsigned governance payload for key holders to sign
+func GovExport(g *cli.Globals) error {
+	poly := poly_go_sdk.NewPolySdk()
+	if err := btc.SetUpPoly(poly, config.DefConfig.RchainJsonRpcAddress); err != nil {
+		return err
+	}
+	var pks []keypair.PublicKey
+	if govAction == gov.ActionCommitDpos {
+		var err error
+		if pks, err = consensusPubKeys(poly); err != nil {
+			return err
+		}
+	}
+	p, err := gov.NewPayload(poly, govAction, govId, govPeerPubkey, pks)
+	if err != nil {
+		return err
+	}
+	if err = p.Save(govOut); err != nil {
+		return err
+	}
+	log.Infof("unsigned payload of %s is written to %s", govAction, govOut)
+	return nil
+}
+
+// GovSign adds the signature of one key holder. It needs no config and no
+// connection to poly.
+func GovSign(g *cli.Globals) error {
+	p, err := gov.LoadPayload(govIn)
+	if err != nil {
+		return err
+	}
+	acc, err := btc.GetAccountByPassword(poly_go_sdk.NewPolySdk(), govWallet, []byte(govPwd))
+	if err != nil {
+		return fmt.Errorf("failed to get account from %s: %v", govWallet, err)
+	}
+	if err = p.Sign(acc); err != nil {
+		return err
+	}
+	out := govOut
+	if out == "" {
+		out = govIn
+	}
+	if err = p.Save(out); err != nil {
+		return err
+	}
+	log.Infof("payload of %s is signed by %s and written to %s", p.Action, acc.Address.ToBase58(), out)
+	return nil
+}
+
+// GovCombine merges payloads signed by different key holders
+func GovCombine(g *cli.Globals) error {
+	files := strings.Split(govIn, ",")
+	ps := make([]*gov.Payload, len(files))
+	for i, f := range files {
+		p, err := gov.LoadPayload(f)
+		if err != nil {
+			return err
+		}
+		ps[i] = p
+	}
+	p, err := gov.Combine(ps)
+	if err != nil {
+		return err
+	}
+	if err = p.Save(govOut); err != nil {
+		return err
+	}
+	log.Infof("payload of %s signed by [ %s ] is written to %s", p.Action, strings.Join(p.Signers(), ", "), govOut)
+	return nil
+}
+
+// GovSubmit sends the signed payload to poly
+func GovSubmit(g *cli.Globals) error {
+	p, err := gov.LoadPayload(govIn)
+	if err != nil {
+		return err
+	}
+	poly := poly_go_sdk.NewPolySdk()
+	if err := btc.SetUpPoly(poly, config.DefConfig.RchainJsonRpcAddress); err != nil {
+		return err
+	}
+	if poly.ChainId != p.PolyChainId {
+		return fmt.Errorf("payload is for poly chain %d but connected to %d", p.PolyChainId, poly.ChainId)
+	}
+	hashes, err := p.Submit(poly)
+	for i, h := range hashes {
+		log.Infof("No%d: successful to send %s: ( txhash: %s )", i, p.Action, h.ToHexString())
+	}
+	if err != nil {
+		return err
+	}
+	testcase.WaitPolyTx(hashes[len(hashes)-1], poly)
+	return nil
+}
+
+// GovStatus prints side chains, relayers, pending proposals with their
+// approvals and the peer pool of poly
+func GovStatus(g *cli.Globals) error {
+	poly := poly_go_sdk.NewPolySdk()
+	if err := btc.SetUpPoly(poly, config.DefConfig.RchainJsonRpcAddress); err != nil {
+		return err
+	}
+	chainIds := []uint64{config.BTC_CHAIN_ID, config.ETH_CHAIN_ID, config.ONT_CHAIN_ID, config.DefConfig.CMCrossChainId}
+	for id := uint64(0); id <= govMaxChainId; id++ {
+		chainIds = append(chainIds, id)
+	}
+	relayers := make([]common.Address, 0)
+	if govRelayers != "" {
+		for _, str := range strings.Split(govRelayers, ",") {
+			addr, err := common.AddressFromBase58(str)
+			if err != nil {
+				return fmt.Errorf("failed to decode relayer %s: %v", str, err)
+			}
+			relayers = append(relayers, addr)
+		}
+	}
+	status, err := gov.QueryStatus(poly, chainIds, relayers)
+	if err != nil {
+		return err
+	}
+	return status.Print(os.Stdout, g.Format)
+}
@@ -0,0 +1,72 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package tools
+
+import (
+	"flag"
+	"fmt"
+	"github.com/polynetwork/poly-go-sdk"
+	"github.com/polynetwork/poly-io-test/chains/btc"
+	"github.com/polynetwork/poly-io-test/cli"
+	"github.com/polynetwork/poly-io-test/config"
+	"github.com/polynetwork/poly-io-test/monitor"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+var (
+	headersThreshold uint64
+	headersWatch     uint64
+)
+
+// HeadersFlags binds the flags of verify headers
+func HeadersFlags(fs *flag.FlagSet) {
+	fs.Uint64Var(&headersThreshold, "threshold", 20, "warn if headers on poly lag more blocks than this behind the chain")
+	fs.Uint64Var(&headersWatch, "watch", 0, "keep checking every this many seconds and log warnings, "+
+		"check once if 0")
+}
+
+// VerifyHeaders prints the header sync state of every side chain. It fails
+// if any chain is not in sync.
+func VerifyHeaders(g *cli.Globals) error {
+	poly := poly_go_sdk.NewPolySdk()
+	if err := btc.SetUpPoly(poly, config.DefConfig.RchainJsonRpcAddress); err != nil {
+		return err
+	}
+	m := monitor.Dial(poly, headersThreshold)
+	if headersWatch > 0 {
+		stop := make(chan struct{})
+		sc := make(chan os.Signal, 1)
+		signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM)
+		go func() {
+			<-sc
+			close(stop)
+		}()
+		m.Watch(time.Duration(headersWatch)*time.Second, stop)
+		return nil
+	}
+	res := m.Check()
+	if err := monitor.Print(os.Stdout, res, g.Format); err != nil {
+		return err
+	}
+	if bad := monitor.Unhealthy(res); len(bad) > 0 {
+		return fmt.Errorf("%d chains are not in sync", len(bad))
+	}
+	return nil
+}
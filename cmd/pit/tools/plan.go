@@ -0,0 +1,153 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package tools
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"github.com/polynetwork/poly-go-sdk"
+	"github.com/polynetwork/poly-io-test/config"
+	"github.com/polynetwork/poly-io-test/plan"
+	"github.com/polynetwork/poly-io-test/testcase"
+	"github.com/polynetwork/poly/native/service/governance/side_chain_manager"
+	"github.com/polynetwork/poly/native/service/utils"
+	"strings"
+)
+
+// sideChainReg is the params of registerSideChain for a chain
+type sideChainReg struct {
+	id        uint64
+	router    uint64
+	name      string
+	blkToWait uint64
+	ccmc      []byte
+}
+
+func btcSideChainReg() *sideChainReg {
+	blkToWait := uint64(1)
+	var tyNet utils.BtcNetType
+	switch config.BtcNet.Name {
+	case "testnet3":
+		blkToWait = 6
+		tyNet = utils.TyTestnet3
+	case "mainnet":
+		blkToWait = 6
+		tyNet = utils.TyMainnet
+	case "regtest":
+		tyNet = utils.TyRegtest
+	case "simnet":
+		tyNet = utils.TySimnet
+	}
+
+	rawTy := make([]byte, 8)
+	binary.LittleEndian.PutUint64(rawTy, uint64(tyNet))
+	return &sideChainReg{config.BTC_CHAIN_ID, config.BTC_CHAIN_ID, "btc", blkToWait, rawTy}
+}
+
+func ethSideChainReg() (*sideChainReg, error) {
+	blkToWait := uint64(1)
+	if config.BtcNet.Name == "testnet3" {
+		blkToWait = 12
+	}
+	eccd, err := hex.DecodeString(strings.Replace(config.DefConfig.Eccd, "0x", "", 1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode eccd '%s' : %v", config.DefConfig.Eccd, err)
+	}
+	return &sideChainReg{config.ETH_CHAIN_ID, config.ETH_CHAIN_ID, "eth", blkToWait, eccd}, nil
+}
+
+func ontSideChainReg() *sideChainReg {
+	return &sideChainReg{config.ONT_CHAIN_ID, 3, "ont", 1, []byte{}}
+}
+
+func cosmosSideChainReg() *sideChainReg {
+	return &sideChainReg{config.DefConfig.CMCrossChainId, 5, "switcheochain", 1, []byte{}}
+}
+
+func isSideChainStored(poly *poly_go_sdk.PolySdk, prefix string, id uint64) (bool, error) {
+	store, err := poly.GetStorage(utils.SideChainManagerContractAddress.ToHexString(),
+		append([]byte(prefix), utils.GetUint64Bytes(id)...))
+	if err != nil {
+		return false, err
+	}
+	return store != nil, nil
+}
+
+// PlanRegisterSideChain lists the governance txs registering the side chains not
+// registered yet, sent by tool register_side_chain or printed in plan mode
+func PlanRegisterSideChain(poly *poly_go_sdk.PolySdk, acc *poly_go_sdk.Account, accArr []*poly_go_sdk.Account) (*plan.Plan, error) {
+	p := plan.NewPlan("register side chains on poly")
+	eth, err := ethSideChainReg()
+	if err != nil {
+		return nil, err
+	}
+	approvers := make([]string, len(accArr))
+	for i, a := range accArr {
+		approvers[i] = a.Address.ToBase58()
+	}
+	contract := utils.SideChainManagerContractAddress.ToHexString()
+	for _, reg := range []*sideChainReg{btcSideChainReg(), ontSideChainReg(), eth, cosmosSideChainReg()} {
+		registered, err := isSideChainStored(poly, side_chain_manager.SIDE_CHAIN, reg.id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get side chain %d: %v", reg.id, err)
+		}
+		if registered {
+			continue
+		}
+		requested, err := isSideChainStored(poly, side_chain_manager.SIDE_CHAIN_APPLY, reg.id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get side chain application %d: %v", reg.id, err)
+		}
+		if !requested {
+			reg := reg
+			p.PolyGov(contract, side_chain_manager.REGISTER_SIDE_CHAIN, []string{acc.Address.ToBase58()},
+				plan.NewArg("address", acc.Address.ToBase58()),
+				plan.NewArg("chainId", reg.id),
+				plan.NewArg("router", reg.router),
+				plan.NewArg("name", reg.name),
+				plan.NewArg("blocksToWait", reg.blkToWait),
+				plan.NewArg("CMCCAddress", reg.ccmc)).
+				SentBy(func() (string, error) {
+					txhash, err := poly.Native.Scm.RegisterSideChain(acc.Address, reg.id, reg.router, reg.name,
+						reg.blkToWait, reg.ccmc, acc)
+					if err != nil {
+						return "", err
+					}
+					testcase.WaitPolyTx(txhash, poly)
+					return txhash.ToHexString(), nil
+				})
+		}
+		for i, a := range accArr {
+			id, a, last := reg.id, a, i == len(accArr)-1
+			p.PolyGov(contract, side_chain_manager.APPROVE_REGISTER_SIDE_CHAIN, []string{approvers[i]},
+				plan.NewArg("chainId", id),
+				plan.NewArg("address", approvers[i])).
+				SentBy(func() (string, error) {
+					txhash, err := poly.Native.Scm.ApproveRegisterSideChain(id, a)
+					if err != nil {
+						return "", err
+					}
+					if last {
+						testcase.WaitPolyTx(txhash, poly)
+					}
+					return txhash.ToHexString(), nil
+				})
+		}
+	}
+	return p, nil
+}
@@ -14,33 +14,28 @@
 * You should have received a copy of the GNU Lesser General Public License
 * along with The poly network . If not, see <http://www.gnu.org/licenses/>.
  */
-package main
+package tools
 
 import (
-	"bytes"
-	"context"
 	"encoding/binary"
 	"encoding/hex"
-	"encoding/json"
 	"flag"
 	"fmt"
-	"github.com/btcsuite/btcd/wire"
 	types3 "github.com/cosmos/cosmos-sdk/types"
-	common3 "github.com/ethereum/go-ethereum/common"
 	"github.com/ontio/ontology-crypto/keypair"
 	"github.com/ontio/ontology-go-sdk"
 	common2 "github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/core/types"
-	"github.com/ontio/ontology/smartcontract/service/native/cross_chain/header_sync"
 	"github.com/ontio/ontology/smartcontract/service/native/governance"
-	utils2 "github.com/ontio/ontology/smartcontract/service/native/utils"
 	"github.com/polynetwork/poly-go-sdk"
+	"github.com/polynetwork/poly-io-test/binding"
 	"github.com/polynetwork/poly-io-test/chains/btc"
 	cosmos2 "github.com/polynetwork/poly-io-test/chains/cosmos"
 	"github.com/polynetwork/poly-io-test/chains/eth"
-	"github.com/polynetwork/poly-io-test/chains/eth/abi/eccm"
 	"github.com/polynetwork/poly-io-test/chains/ont"
+	"github.com/polynetwork/poly-io-test/cli"
 	"github.com/polynetwork/poly-io-test/config"
+	"github.com/polynetwork/poly-io-test/gov"
 	"github.com/polynetwork/poly-io-test/log"
 	"github.com/polynetwork/poly-io-test/testcase"
 	"github.com/polynetwork/poly/common"
@@ -48,18 +43,14 @@ import (
 	"github.com/polynetwork/poly/native/service/governance/node_manager"
 	"github.com/polynetwork/poly/native/service/governance/relayer_manager"
 	"github.com/polynetwork/poly/native/service/governance/side_chain_manager"
-	"github.com/polynetwork/poly/native/service/header_sync/cosmos"
 	"github.com/polynetwork/poly/native/service/utils"
 	"github.com/tendermint/tendermint/rpc/client/http"
 	types2 "github.com/tendermint/tendermint/types"
-	"math/big"
 	"os"
 	"strings"
 )
 
 var (
-	tool                                                                  string
-	toolConf                                                              string
 	pWalletFiles                                                          string
 	pPwds                                                                 string
 	oWalletFiles                                                          string
@@ -71,44 +62,81 @@ var (
 	stateFile                                                             string
 	id                                                                    uint64
 	blockMsgDelay, hashMsgDelay, peerHandshakeTimeout, maxBlockChangeView uint64
+	planMode                                                              bool
 )
 
-func init() {
-	flag.StringVar(&tool, "tool", "", "choose a tool to run")
-	flag.StringVar(&toolConf, "conf", "./config.json", "configuration file path")
-	flag.StringVar(&pWalletFiles, "pwallets", "", "poly wallet files sep by ','")
-	flag.StringVar(&pPwds, "ppwds", "", "poly pwd for every wallet, sep by ','")
-	flag.StringVar(&oWalletFiles, "owallets", "", "ontology wallet files sep by ','")
-	flag.StringVar(&oPwds, "opwds", "", "ontology pwd for every wallet, sep by ','")
-	flag.StringVar(&newWallet, "newwallet", "", "new wallet adding to poly consensus")
-	flag.StringVar(&newPwd, "newpwd", "", "password for new wallet")
-	flag.Int64Var(&amt, "amt", 50, "amount to create new cosmos validator")
-	flag.StringVar(&keyFile, "cosmos_val_privk_file", "", "cosmos validator's privk file")
-	flag.StringVar(&stateFile, "cosmos_val_state_file", "", "cosmos validator's state file")
-	flag.Uint64Var(&id, "id", 0, "chain id to quit")
-	flag.Uint64Var(&blockMsgDelay, "blk_msg_delay", 5000, "")
-	flag.Uint64Var(&hashMsgDelay, "hash_msg_delay", 5000, "")
-	flag.Uint64Var(&peerHandshakeTimeout, "peer_handshake_timeout", 10, "")
-	flag.Uint64Var(&maxBlockChangeView, "max_blk_change_view", 10000, "")
-
-	flag.Parse()
-}
-
-func main() {
-	log.InitLog(2, os.Stdout)
-
-	err := config.DefConfig.Init(toolConf)
-	if err != nil {
-		panic(err)
-	}
+// Tool is a tool Run knows
+type Tool struct {
+	Name  string
+	Usage string
+}
+
+var Tools = []Tool{
+	{"register_side_chain", "register btc, ont, eth and cosmos on poly and approve them"},
+	{"sync_genesis_header", "sync genesis headers between poly and side chains"},
+	{"update_btc", "update the side chain info of btc on poly"},
+	{"update_eth", "update the side chain info of eth on poly"},
+	{"init_ont_acc", "withdraw ont from ontology consensus to the test account"},
+	{"poly_add_node", "register a new poly consensus node and commit dpos"},
+	{"cosmos_create_validator", "create a new cosmos validator"},
+	{"cosmos_delegate_validator", "delegate to the cosmos validator"},
+	{"black_poly_node", "put a poly consensus node into the black list"},
+	{"white_poly_node", "remove a poly consensus node from the black list"},
+	{"quit_poly_node", "quit a poly consensus node and commit dpos"},
+	{"get_poly_consensus", "print the poly consensus info"},
+	{"add_relayer", "register a relayer on poly and approve it"},
+	{"reg_poly_node", "register a poly consensus candidate"},
+	{"unreg_poly_node", "unregister a poly consensus candidate"},
+	{"remove_relayer", "remove a relayer from poly and approve it"},
+	{"get_relayer", "check whether an account is a relayer"},
+	{"quit_side_chain", "quit the side chain with -id and approve it"},
+	{"get_side_chain", "print the side chain with -id"},
+	{"get_poly_config", "print the poly consensus config"},
+	{"update_poly_config", "update the poly consensus config"},
+	{"commit_poly_dpos", "commit poly dpos"},
+	{"commit_ont_dpos", "commit ontology dpos"},
+	{"verify_bindings", "verify lock proxy and btcx bindings on all chains"},
+}
+
+// Flags binds the flags of all tools
+func Flags(fs *flag.FlagSet) {
+	fs.StringVar(&pWalletFiles, "pwallets", "", "poly wallet files sep by ','")
+	fs.StringVar(&pPwds, "ppwds", "", "poly pwd for every wallet, sep by ','")
+	fs.StringVar(&oWalletFiles, "owallets", "", "ontology wallet files sep by ','")
+	fs.StringVar(&oPwds, "opwds", "", "ontology pwd for every wallet, sep by ','")
+	fs.StringVar(&newWallet, "newwallet", "", "new wallet adding to poly consensus")
+	fs.StringVar(&newPwd, "newpwd", "", "password for new wallet")
+	fs.Int64Var(&amt, "amt", 50, "amount to create new cosmos validator")
+	fs.StringVar(&keyFile, "cosmos_val_privk_file", "", "cosmos validator's privk file")
+	fs.StringVar(&stateFile, "cosmos_val_state_file", "", "cosmos validator's state file")
+	fs.Uint64Var(&id, "id", 0, "chain id to quit")
+	fs.Uint64Var(&blockMsgDelay, "blk_msg_delay", 5000, "")
+	fs.Uint64Var(&hashMsgDelay, "hash_msg_delay", 5000, "")
+	fs.Uint64Var(&peerHandshakeTimeout, "peer_handshake_timeout", 10, "")
+	fs.Uint64Var(&maxBlockChangeView, "max_blk_change_view", 10000, "")
+	fs.Int64Var(&btcGenesisHeight, "btc_height", -1, "height of btc genesis header to sync to poly, "+
+		"the last difficulty retarget by default")
+	fs.Int64Var(&ethGenesisHeight, "eth_height", -1, "height of eth genesis header to sync to poly, the tip by default")
+	fs.Int64Var(&ontGenesisHeight, "ont_height", -1, "height of ontology genesis header to sync to poly, "+
+		"OntEpoch in config by default")
+	fs.Int64Var(&cosmosGenesisHeight, "cosmos_height", -1, "height of cosmos genesis header to sync to poly, "+
+		"CMEpoch in config by default")
+	fs.Int64Var(&polyGenesisHeight, "poly_height", -1, "height of poly genesis header to sync to side chains, "+
+		"RCEpoch in config by default")
+	fs.BoolVar(&planMode, "plan", false, "only print the transactions to send without broadcasting them, "+
+		"only for register_side_chain now")
+}
+
+// Run runs tool with the account in config
+func Run(g *cli.Globals, tool string) error {
 	poly := poly_go_sdk.NewPolySdk()
 	if err := btc.SetUpPoly(poly, config.DefConfig.RchainJsonRpcAddress); err != nil {
-		panic(err)
+		return err
 	}
 
 	acc, err := btc.GetAccountByPassword(poly, config.DefConfig.RCWallet, []byte(config.DefConfig.RCWalletPwd))
 	if err != nil {
-		panic(err)
+		return err
 	}
 
 	switch tool {
@@ -123,17 +151,15 @@ func main() {
 				panic(fmt.Errorf("failed to decode no%d wallet %s with pwd %s", i, wArr[i], pArr[i]))
 			}
 		}
-		if RegisterBtcChain(poly, acc) {
-			ApproveRegisterSideChain(config.BTC_CHAIN_ID, poly, accArr)
-		}
-		if RegisterOntChain(poly, acc) {
-			ApproveRegisterSideChain(config.ONT_CHAIN_ID, poly, accArr)
+		p, err := PlanRegisterSideChain(poly, acc, accArr)
+		if err != nil {
+			return err
 		}
-		if RegisterEthChain(poly, acc) {
-			ApproveRegisterSideChain(config.ETH_CHAIN_ID, poly, accArr)
+		if planMode {
+			return p.Print(os.Stdout, g.Format)
 		}
-		if RegisterCosmos(poly, acc) {
-			ApproveRegisterSideChain(config.DefConfig.CMCrossChainId, poly, accArr)
+		if err = p.Run(os.Stdout); err != nil {
+			return err
 		}
 
 	case "sync_genesis_header":
@@ -147,10 +173,7 @@ func main() {
 				panic(fmt.Errorf("failed to decode no%d wallet %s with pwd %s", i, wArr[i], pArr[i]))
 			}
 		}
-		SyncBtcGenesisHeader(poly, acc)
-		SyncEthGenesisHeader(poly, accArr)
-		SyncOntGenesisHeader(poly, accArr)
-		SyncCosmosGenesisHeader(poly, accArr)
+		return SyncGenesisHeaders(poly, acc, accArr)
 
 	case "update_btc":
 		accArr := getPolyAccounts(poly)
@@ -165,10 +188,7 @@ func main() {
 		}
 
 	case "init_ont_acc":
-		err := InitOntAcc()
-		if err != nil {
-			panic(err)
-		}
+		return InitOntAcc()
 
 	case "poly_add_node":
 		accArr := getPolyAccounts(poly)
@@ -260,7 +280,14 @@ func main() {
 		CommitPolyDpos(poly, accArr)
 	case "commit_ont_dpos":
 		CommitOntDpos()
+	case "verify_bindings":
+		if !VerifyBindings(poly, g.Format) {
+			return fmt.Errorf("some bindings are broken")
+		}
+	default:
+		return fmt.Errorf("unknown tool %s", tool)
 	}
+	return nil
 }
 
 func getPolyAccounts(poly *poly_go_sdk.PolySdk) []*poly_go_sdk.Account {
@@ -352,206 +379,6 @@ func WithdrawONTFromConsensus(ontSdk *ontology_go_sdk.OntologySdk, gasPrice, gas
 	return ontSdk.SendTransaction(tx)
 }
 
-func SyncBtcGenesisHeader(poly *poly_go_sdk.PolySdk, acc *poly_go_sdk.Account) {
-	cli := btc.NewRestCli(config.DefConfig.BtcRestAddr, config.DefConfig.BtcRestUser, config.DefConfig.BtcRestPwd)
-	curr, _, err := cli.GetCurrentHeightAndHash()
-	if err != nil {
-		panic(fmt.Errorf("SyncBtcGenesisHeader failed: %v", err))
-	}
-	start := curr - curr%2016
-	hdr, err := cli.GetHeader(start)
-	if err != nil {
-		panic(fmt.Errorf("SyncBtcGenesisHeader falied: %v", err))
-	}
-	var buf bytes.Buffer
-	err = hdr.BtcEncode(&buf, wire.ProtocolVersion, wire.LatestEncoding)
-	if err != nil {
-		panic(err)
-	}
-
-	hb := make([]byte, 4)
-	binary.BigEndian.PutUint32(hb, uint32(start))
-	txhash, err := poly.Native.Hs.SyncGenesisHeader(config.BTC_CHAIN_ID, append(buf.Bytes(), hb...),
-		[]*poly_go_sdk.Account{acc})
-	if err != nil {
-		if strings.Contains(err.Error(), "had been initialized") {
-			log.Info("btc already synced")
-		} else {
-			panic(fmt.Errorf("SyncBtcGenesisHeader failed: %v", err))
-		}
-	} else {
-		testcase.WaitPolyTx(txhash, poly)
-		blkHash := hdr.BlockHash()
-		log.Infof("successful to sync btc genesis header: ( height: %d, block_hash: %s, txhash: %s )", start,
-			blkHash.String(), txhash.ToHexString())
-	}
-}
-
-func SyncEthGenesisHeader(poly *poly_go_sdk.PolySdk, accArr []*poly_go_sdk.Account) {
-	tool := eth.NewEthTools(config.DefConfig.EthURL)
-	curr, err := tool.GetNodeHeight()
-	if err != nil {
-		panic(err)
-	}
-	hdr, err := tool.GetBlockHeader(curr)
-	if err != nil {
-		panic(err)
-	}
-	raw, err := hdr.MarshalJSON()
-	if err != nil {
-		panic(err)
-	}
-	txhash, err := poly.Native.Hs.SyncGenesisHeader(config.ETH_CHAIN_ID, raw, accArr)
-	if err != nil {
-		if strings.Contains(err.Error(), "had been initialized") {
-			log.Info("eth already synced")
-		} else {
-			panic(fmt.Errorf("SyncEthGenesisHeader failed: %v", err))
-		}
-	} else {
-		testcase.WaitPolyTx(txhash, poly)
-		log.Infof("successful to sync eth genesis header: (height: %d, blk_hash: %s, txhash: %s )", curr,
-			hdr.Hash().String(), txhash.ToHexString())
-	}
-
-	eccmContract, err := eccm.NewEthCrossChainManager(common3.HexToAddress(config.DefConfig.Eccm), tool.GetEthClient())
-	if err != nil {
-		panic(err)
-	}
-	signer, err := eth.NewEthSigner(config.DefConfig.ETHPrivateKey)
-	if err != nil {
-		panic(err)
-	}
-	nonce := eth.NewNonceManager(tool.GetEthClient()).GetAddressNonce(signer.Address)
-	gasPrice, err := tool.GetEthClient().SuggestGasPrice(context.Background())
-	if err != nil {
-		panic(fmt.Errorf("SyncEthGenesisHeader, get suggest gas price failed error: %s", err.Error()))
-	}
-	gasPrice = gasPrice.Mul(gasPrice, big.NewInt(5))
-	auth := testcase.MakeEthAuth(signer, nonce, gasPrice.Uint64(), uint64(8000000))
-
-	gB, err := poly.GetBlockByHeight(config.DefConfig.RCEpoch)
-	if err != nil {
-		panic(err)
-	}
-	info := &vconfig.VbftBlockInfo{}
-	if err := json.Unmarshal(gB.Header.ConsensusPayload, info); err != nil {
-		panic(fmt.Errorf("commitGenesisHeader - unmarshal blockInfo error: %s", err))
-	}
-
-	var bookkeepers []keypair.PublicKey
-	for _, peer := range info.NewChainConfig.Peers {
-		keystr, _ := hex.DecodeString(peer.ID)
-		key, _ := keypair.DeserializePublicKey(keystr)
-		bookkeepers = append(bookkeepers, key)
-	}
-	bookkeepers = keypair.SortPublicKeys(bookkeepers)
-
-	publickeys := make([]byte, 0)
-	for _, key := range bookkeepers {
-		publickeys = append(publickeys, ont.GetOntNoCompressKey(key)...)
-	}
-
-	tx, err := eccmContract.InitGenesisBlock(auth, gB.Header.ToArray(), publickeys)
-	tool.WaitTransactionConfirm(tx.Hash())
-	log.Infof("successful to sync poly genesis header to Ethereum: ( txhash: %s )", tx.Hash().String())
-}
-
-func SyncOntGenesisHeader(poly *poly_go_sdk.PolySdk, accArr []*poly_go_sdk.Account) {
-	ontCli := ontology_go_sdk.NewOntologySdk()
-	ontCli.NewRpcClient().SetAddress(config.DefConfig.OntJsonRpcAddress)
-
-	genesisBlock, err := ontCli.GetBlockByHeight(config.DefConfig.OntEpoch)
-	if err != nil {
-		panic(err)
-	}
-	txhash, err := poly.Native.Hs.SyncGenesisHeader(config.ONT_CHAIN_ID, genesisBlock.Header.ToArray(), accArr)
-	if err != nil {
-		if strings.Contains(err.Error(), "had been initialized") {
-			log.Info("ont already synced")
-		} else {
-			panic(fmt.Errorf("SyncOntGenesisHeader failed: %v", err))
-		}
-	} else {
-		testcase.WaitPolyTx(txhash, poly)
-		log.Infof("successful to sync ont genesis header: ( txhash: %s )", txhash.ToHexString())
-	}
-	ow := strings.Split(oWalletFiles, ",")
-	op := strings.Split(oPwds, ",")
-	oAccArr := make([]*ontology_go_sdk.Account, len(ow))
-	pks := make([]keypair.PublicKey, len(ow))
-	for i, v := range ow {
-		oAccArr[i], err = ont.GetOntAccByPwd(v, op[i])
-		if err != nil {
-			panic(fmt.Errorf("failed to decode no%d wallet %s with pwd %s", i, ow[i], op[i]))
-		}
-		pks[i] = oAccArr[i].PublicKey
-	}
-	gB, err := poly.GetBlockByHeight(config.DefConfig.RCEpoch)
-	if err != nil {
-		panic(err)
-	}
-	txHash, err := InvokeNativeContractWithMultiSign(ontCli, 0, 2000000, pks, oAccArr, byte(0),
-		utils2.HeaderSyncContractAddress, header_sync.SYNC_GENESIS_HEADER,
-		[]interface{}{
-			&header_sync.SyncGenesisHeaderParam{
-				GenesisHeader: gB.Header.ToArray(),
-			}})
-	if err != nil {
-		panic(fmt.Errorf("faild to sync poly header to ontology: %v", err))
-	}
-	ont.WaitOntTx(txHash, ontCli)
-	log.Infof("successful to sync poly genesis header to Ontology: ( txhash: %s )", txHash.ToHexString())
-}
-
-func SyncCosmosGenesisHeader(poly *poly_go_sdk.PolySdk, accArr []*poly_go_sdk.Account) {
-	invoker, err := cosmos2.NewCosmosInvoker()
-	if err != nil {
-		panic(err)
-	}
-
-	res, err := invoker.RpcCli.Commit(&config.DefConfig.CMEpoch)
-	if err != nil {
-		panic(err)
-	}
-	vals, err := getValidators(invoker.RpcCli, config.DefConfig.CMEpoch)
-	if err != nil {
-		panic(err)
-	}
-	ch := &cosmos.CosmosHeader{
-		Header:  *res.Header,
-		Commit:  res.Commit,
-		Valsets: vals,
-	}
-	raw, err := invoker.CMCdc.MarshalBinaryBare(ch)
-	if err != nil {
-		panic(err)
-	}
-	txhash, err := poly.Native.Hs.SyncGenesisHeader(config.DefConfig.CMCrossChainId, raw, accArr)
-	if err != nil {
-		if strings.Contains(err.Error(), "had been initialized") {
-			log.Info("cosmos already synced")
-		} else {
-			panic(err)
-		}
-	} else {
-		testcase.WaitPolyTx(txhash, poly)
-		log.Infof("successful to sync cosmos genesis header: ( txhash: %s )", txhash.ToHexString())
-	}
-
-	header, err := poly.GetHeaderByHeight(config.DefConfig.RCEpoch)
-	if err != nil {
-		panic(err)
-	}
-
-	tx, err := invoker.SyncPolyGenesisHdr(invoker.Acc.Acc, header.ToArray())
-	if err != nil {
-		panic(err)
-	}
-
-	log.Infof("successful to sync poly genesis header to cosmos: ( txhash: %s )", tx.Hash.String())
-}
-
 func getValidators(rpc *http.HTTP, h int64) ([]*types2.Validator, error) {
 	p := 1
 	vSet := make([]*types2.Validator, 0)
@@ -596,131 +423,6 @@ func InvokeNativeContractWithMultiSign(
 	return sdk.SendTransaction(tx)
 }
 
-func ApproveRegisterSideChain(id uint64, poly *poly_go_sdk.PolySdk, accArr []*poly_go_sdk.Account) {
-	var (
-		txhash common.Uint256
-		err    error
-	)
-
-	for i, a := range accArr {
-		txhash, err = poly.Native.Scm.ApproveRegisterSideChain(id, a)
-		if err != nil {
-			panic(fmt.Errorf("No%d ApproveRegisterSideChain failed: %v", i, err))
-		}
-		log.Infof("No%d: successful to approve: ( acc: %s, txhash: %s, chain-id: %d )", i, a.Address.ToBase58(), txhash.ToHexString(), id)
-	}
-	testcase.WaitPolyTx(txhash, poly)
-}
-
-func RegisterBtcChain(poly *poly_go_sdk.PolySdk, acc *poly_go_sdk.Account) bool {
-	blkToWait := uint64(1)
-	var tyNet utils.BtcNetType
-	switch config.BtcNet.Name {
-	case "testnet3":
-		blkToWait = 6
-		tyNet = utils.TyTestnet3
-	case "mainnet":
-		blkToWait = 6
-		tyNet = utils.TyMainnet
-	case "regtest":
-		tyNet = utils.TyRegtest
-	case "simnet":
-		tyNet = utils.TySimnet
-	}
-
-	rawTy := make([]byte, 8)
-	binary.LittleEndian.PutUint64(rawTy, uint64(tyNet))
-	txhash, err := poly.Native.Scm.RegisterSideChain(acc.Address, config.BTC_CHAIN_ID, config.BTC_CHAIN_ID, "btc",
-		blkToWait, rawTy, acc)
-	if err != nil {
-		if strings.Contains(err.Error(), "already registered") {
-			log.Infof("btc chain %d already registered", config.BTC_CHAIN_ID)
-			return false
-		}
-		if strings.Contains(err.Error(), "already requested") {
-			log.Infof("btc chain %d already requested", config.BTC_CHAIN_ID)
-			return true
-		}
-		panic(fmt.Errorf("RegisterBtcChain failed: %v", err))
-	}
-
-	testcase.WaitPolyTx(txhash, poly)
-	log.Infof("successful to register btc chain: ( txhash: %s )", txhash.ToHexString())
-
-	return true
-}
-
-func RegisterEthChain(poly *poly_go_sdk.PolySdk, acc *poly_go_sdk.Account) bool {
-	blkToWait := uint64(1)
-	if config.BtcNet.Name == "testnet3" {
-		blkToWait = 12
-	}
-	eccd, err := hex.DecodeString(strings.Replace(config.DefConfig.Eccd, "0x", "", 1))
-	if err != nil {
-		panic(fmt.Errorf("RegisterEthChain, failed to decode eccd '%s' : %v", config.DefConfig.Eccd, err))
-	}
-	txhash, err := poly.Native.Scm.RegisterSideChain(acc.Address, config.ETH_CHAIN_ID, config.ETH_CHAIN_ID, "eth",
-		blkToWait, eccd, acc)
-	if err != nil {
-		if strings.Contains(err.Error(), "already registered") {
-			log.Infof("eth chain %d already registered", config.ETH_CHAIN_ID)
-			return false
-		}
-		if strings.Contains(err.Error(), "already requested") {
-			log.Infof("eth chain %d already requested", config.ETH_CHAIN_ID)
-			return true
-		}
-		panic(fmt.Errorf("RegisterEthChain failed: %v", err))
-	}
-	testcase.WaitPolyTx(txhash, poly)
-	log.Infof("successful to register eth chain: ( txhash: %s )", txhash.ToHexString())
-
-	return true
-}
-
-func RegisterCosmos(poly *poly_go_sdk.PolySdk, acc *poly_go_sdk.Account) bool {
-	blkToWait := uint64(1)
-	txhash, err := poly.Native.Scm.RegisterSideChain(acc.Address, config.DefConfig.CMCrossChainId, 5, "switcheochain",
-		blkToWait, []byte{}, acc)
-	if err != nil {
-		if strings.Contains(err.Error(), "already registered") {
-			log.Infof("Cosmos chain %d already registered", config.DefConfig.CMCrossChainId)
-			return false
-		}
-		if strings.Contains(err.Error(), "already requested") {
-			log.Infof("Cosmos chain %d already requested", config.DefConfig.CMCrossChainId)
-			return true
-		}
-		panic(fmt.Errorf("RegisterCosmosChain failed: %v", err))
-	}
-
-	testcase.WaitPolyTx(txhash, poly)
-	log.Infof("successful to register cosmos chain: ( txhash: %s )", txhash.ToHexString())
-
-	return true
-}
-
-func RegisterOntChain(poly *poly_go_sdk.PolySdk, acc *poly_go_sdk.Account) bool {
-	txhash, err := poly.Native.Scm.RegisterSideChain(acc.Address, config.ONT_CHAIN_ID, 3, "ont",
-		1, []byte{}, acc)
-	if err != nil {
-		if strings.Contains(err.Error(), "already registered") {
-			log.Infof("ont chain %d already registered", config.ONT_CHAIN_ID)
-			return false
-		}
-		if strings.Contains(err.Error(), "already requested") {
-			log.Infof("ont chain %d already requested", config.ONT_CHAIN_ID)
-			return true
-		}
-		panic(fmt.Errorf("RegisterOntChain failed: %v", err))
-	}
-
-	testcase.WaitPolyTx(txhash, poly)
-	log.Infof("successful to register ont chain: ( txhash: %s )", txhash.ToHexString())
-
-	return true
-}
-
 func RegisterNeoChain(poly *poly_go_sdk.PolySdk, acc *poly_go_sdk.Account) bool {
 	txhash, err := poly.Native.Scm.RegisterSideChain(acc.Address, 4, 4, "neo",
 		1, []byte{}, acc)
@@ -930,27 +632,10 @@ func QuitNode(poly *poly_go_sdk.PolySdk, acc *poly_go_sdk.Account) {
 }
 
 func GetPolyConsensusInfo(poly *poly_go_sdk.PolySdk) {
-	storeBs, err := poly.GetStorage(utils.NodeManagerContractAddress.ToHexString(), []byte(node_manager.GOVERNANCE_VIEW))
-	if err != nil {
-		panic(err)
-	}
-	source := common.NewZeroCopySource(storeBs)
-	gv := new(node_manager.GovernanceView)
-	if err := gv.Deserialization(source); err != nil {
-		panic(err)
-	}
-
-	raw, err := poly.GetStorage(utils.NodeManagerContractAddress.ToHexString(),
-		append([]byte(node_manager.PEER_POOL), utils.GetUint32Bytes(gv.View)...))
+	gv, m, err := gov.GetPeerPool(poly)
 	if err != nil {
 		panic(err)
 	}
-	m := &node_manager.PeerPoolMap{
-		PeerPoolMap: make(map[string]*node_manager.PeerPoolItem),
-	}
-	if err := m.Deserialization(common.NewZeroCopySource(raw)); err != nil {
-		panic(err)
-	}
 	str := ""
 	for _, v := range m.PeerPoolMap {
 		str += fmt.Sprintf("[ index: %d, address: %s, pubk: %s, status: %d ]\n",
@@ -1165,3 +850,22 @@ func CosmosDelegateToVal(amt int64) {
 	log.Infof("CosmosDelegateToVal, delegate %d %s to validator %s: txhash %s",
 		amt, demon, types3.ValAddress(invoker.Acc.Acc).String(), res.Hash.String())
 }
+
+func VerifyBindings(poly *poly_go_sdk.PolySdk, format string) bool {
+	ethTools := eth.NewEthTools(config.DefConfig.EthURL)
+	if ethTools == nil {
+		panic(fmt.Errorf("failed to dial ethereum %s", config.DefConfig.EthURL))
+	}
+	ontSdk := ontology_go_sdk.NewOntologySdk()
+	ontSdk.NewRpcClient().SetAddress(config.DefConfig.OntJsonRpcAddress)
+	cmCli, err := http.New(config.DefConfig.CMRpcUrl, "/websocket")
+	if err != nil {
+		panic(fmt.Errorf("failed to new cosmos client: %v", err))
+	}
+	checker := binding.NewChecker(ethTools.GetEthClient(), ontSdk, cmCli, cosmos2.NewCodec(), poly)
+	res := checker.CheckAll()
+	if err = binding.Print(os.Stdout, res, format); err != nil {
+		panic(err)
+	}
+	return len(binding.Broken(res)) == 0
+}
@@ -0,0 +1,99 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package tools
+
+import (
+	"flag"
+	"fmt"
+	"github.com/polynetwork/poly-go-sdk"
+	"github.com/polynetwork/poly-io-test/chains/btc"
+	"github.com/polynetwork/poly-io-test/cli"
+	"github.com/polynetwork/poly-io-test/config"
+	"github.com/polynetwork/poly-io-test/monitor"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+var (
+	vendorBlocks    uint64
+	vendorThreshold uint64
+	vendorWatch     uint64
+	vendorImport    bool
+)
+
+// VendorFlags binds the flags of checking the btc vendor
+func VendorFlags(fs *flag.FlagSet) {
+	fs.Uint64Var(&vendorBlocks, "blocks", 1000, "scan makeBtcTx events in this many poly blocks back")
+	fs.Uint64Var(&vendorThreshold, "threshold", 20, "warn if an unlock tx waits signatures more poly blocks "+
+		"than this")
+	fs.Uint64Var(&vendorWatch, "watch", 0, "keep checking every this many seconds and log warnings, "+
+		"check once if 0")
+	fs.BoolVar(&vendorImport, "import", false, "import the multisig addresses into the node first, "+
+		"which rescans the chain")
+}
+
+// CheckVendor prints the utxos of the btc multisig on bitcoin and poly, and the unlock
+// txs waiting for signatures of the vendor. It fails if the vendor is not ok.
+func CheckVendor(g *cli.Globals) error {
+	poly := poly_go_sdk.NewPolySdk()
+	if err := btc.SetUpPoly(poly, config.DefConfig.RchainJsonRpcAddress); err != nil {
+		return err
+	}
+	m, err := monitor.DialVendor(poly, uint32(vendorThreshold))
+	if err != nil {
+		return err
+	}
+	if vendorImport {
+		p2sh, p2wsh, err := m.Addresses()
+		if err != nil {
+			return err
+		}
+		for _, addr := range []string{p2sh.EncodeAddress(), p2wsh.EncodeAddress()} {
+			if err = m.BtcCli.ImportAddress(addr); err != nil {
+				return fmt.Errorf("failed to import %s: %v", addr, err)
+			}
+		}
+	}
+	height, err := poly.GetCurrentBlockHeight()
+	if err != nil {
+		return fmt.Errorf("failed to get poly height: %v", err)
+	}
+	if uint64(height) > vendorBlocks {
+		m.ScanFrom(height - uint32(vendorBlocks))
+	}
+	if vendorWatch > 0 {
+		stop := make(chan struct{})
+		sc := make(chan os.Signal, 1)
+		signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM)
+		go func() {
+			<-sc
+			close(stop)
+		}()
+		m.Watch(time.Duration(vendorWatch)*time.Second, stop)
+		return nil
+	}
+	v := m.Check()
+	if err := monitor.PrintVendor(os.Stdout, v, g.Format); err != nil {
+		return err
+	}
+	if v.Status != monitor.StatusOK {
+		return fmt.Errorf("btc vendor is %s", v.Status)
+	}
+	return nil
+}
@@ -43,7 +43,7 @@ type TestConfig struct {
 	BtcRestAddr                  string
 	BtcRestUser                  string
 	BtcRestPwd                   string
-	BtcFee                       int64
+	BtcFee                       int64  // deprecated, see BtcSendFeeRate
 	BtcRedeem                    string // auto set
 	BtcNetType                   string
 	BtcMultiSigNum               int // multi-sig vendor
@@ -57,6 +57,17 @@ type TestConfig struct {
 	BtcSignerPrivateKey          string
 	BtcExistingVendorPrivks      string
 
+	BtcSignerInputType string // p2pkh (default), p2wpkh or p2sh-p2wpkh
+	BtcLockToP2wsh     bool   // always lock to the P2WSH of BtcRedeem, P2SH or P2WSH at random if not set
+	BtcSendFeeRate     uint64 // sat/vbyte for sends, estimatesmartfee of node is used if 0
+	BtcReplaceable     bool   // mark sends replaceable (BIP125)
+	BtcUtxoSelector    string // confs (default), largest, bnb or random
+	BtcUnlockConfs     int64  // confirmations of unlock txs on bitcoin before done, 1 if 0
+	BtcUnlockMaxFee    int64  // max satoshi of fee taken from unlocked btc, not checked if 0
+	BtcUnlockTimeout   uint64 // seconds to wait for unlock txs on bitcoin after cases return, 600 if 0
+
+	BtcAddressCodecs map[uint64]string // codecs of destination addresses by chain-id: evm, ont, bech32[:prefix] or hex
+
 	// eth urls
 	EthURL        string
 	ETHPrivateKey string
@@ -86,6 +97,13 @@ type TestConfig struct {
 	RchainJsonRpcAddress string
 	RCEpoch              uint32
 
+	// consensus wallets of poly sep by ',' for the governance cases, and the
+	// candidate rotated in and out of consensus by PolyEpochChange
+	RCConsensusWallets    string
+	RCConsensusWalletPwds string
+	RCCandidateWallet     string
+	RCCandidateWalletPwd  string
+
 	ReportInterval uint64
 	ReportDir      string
 	BatchTxNum     uint64
@@ -11,10 +11,11 @@ require (
 	github.com/ontio/ontology v1.11.1-0.20200812075204-26cf1fa5dd47
 	github.com/ontio/ontology-crypto v1.0.9
 	github.com/ontio/ontology-go-sdk v1.11.4
+	github.com/polynetwork/btc-vendor-tools v0.0.0-20200813091748-3b19a5fd7666
 	github.com/polynetwork/cosmos-poly-module v0.0.0-20200810030259-95d586518759
+	github.com/polynetwork/eth-contracts v0.0.0-20200814062128-70f58e22b014
 	github.com/polynetwork/poly v0.0.0-20200715030435-4f1d1a0adb44
 	github.com/polynetwork/poly-go-sdk v0.0.0-20200730112529-d9c0c7ddf3d8
-	github.com/polynetwork/btc-vendor-tools v0.0.0-20200813091748-3b19a5fd7666
+	github.com/stretchr/testify v1.6.1
 	github.com/tendermint/tendermint v0.33.7
-	github.com/polynetwork/eth-contracts v0.0.0-20200814062128-70f58e22b014
 )
@@ -0,0 +1,307 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+
+// Package gov passes poly governance transactions among the consensus key
+// holders, so that each of them signs on their own machine:
+// export writes an unsigned payload, sign adds one signature to it, combine
+// merges the signed copies and submit sends them to poly.
+package gov
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/polynetwork/poly-go-sdk"
+	"github.com/polynetwork/poly/common"
+	"github.com/polynetwork/poly/core/types"
+	"io/ioutil"
+	"sort"
+)
+
+const (
+	ActionApproveRegisterSideChain = "approve_register_side_chain"
+	ActionApproveUpdateSideChain   = "approve_update_side_chain"
+	ActionApproveCandidate         = "approve_candidate"
+	ActionApproveRelayer           = "approve_relayer"
+	ActionCommitDpos               = "commit_dpos"
+)
+
+// Actions are all actions supported
+var Actions = []string{ActionApproveRegisterSideChain, ActionApproveUpdateSideChain, ActionApproveCandidate,
+	ActionApproveRelayer, ActionCommitDpos}
+
+// Payload is the file passed among key holders. Approvals are votes, so every
+// key holder sends an own transaction carrying the own address. CommitDpos
+// is one transaction multi-signed by the consensus keys.
+type Payload struct {
+	Action string `json:"action"`
+	// chain id of poly, so we can build transactions offline
+	PolyChainId uint64 `json:"poly_chain_id"`
+	// chain id for side chain approvals or apply id for relayer approval
+	Id         uint64 `json:"id,omitempty"`
+	PeerPubkey string `json:"peer_pubkey,omitempty"`
+	// the unsigned transaction and the multi-sig keys for commit_dpos
+	Unsigned string   `json:"unsigned,omitempty"`
+	PubKeys  []string `json:"pub_keys,omitempty"`
+	M        uint16   `json:"m,omitempty"`
+	// signed transactions by the address of key holder
+	Signed map[string]string `json:"signed"`
+}
+
+// NewPayload exports an unsigned payload. id is the chain id for side chain
+// approvals and the apply id for relayer, peerPubkey is for candidate and
+// pubKeys are the consensus keys for commit_dpos.
+func NewPayload(poly *poly_go_sdk.PolySdk, action string, id uint64, peerPubkey string,
+	pubKeys []keypair.PublicKey) (*Payload, error) {
+	p := &Payload{
+		Action:      action,
+		PolyChainId: poly.ChainId,
+		Signed:      make(map[string]string),
+	}
+	switch action {
+	case ActionApproveRegisterSideChain, ActionApproveUpdateSideChain, ActionApproveRelayer:
+		p.Id = id
+	case ActionApproveCandidate:
+		if peerPubkey == "" {
+			return nil, fmt.Errorf("peer pubkey is required for %s", action)
+		}
+		p.PeerPubkey = peerPubkey
+	case ActionCommitDpos:
+		if len(pubKeys) == 0 {
+			return nil, fmt.Errorf("pubkeys are required for %s", action)
+		}
+		tx, err := poly.Native.Nm.NewCommitDposTransaction()
+		if err != nil {
+			return nil, fmt.Errorf("failed to build commit dpos tx: %v", err)
+		}
+		p.Unsigned = txToHex(tx)
+		for _, pk := range pubKeys {
+			p.PubKeys = append(p.PubKeys, hex.EncodeToString(keypair.SerializePublicKey(pk)))
+		}
+		p.M = uint16((5*len(pubKeys) + 6) / 7)
+	default:
+		return nil, fmt.Errorf("unknown action %s", action)
+	}
+	return p, nil
+}
+
+func LoadPayload(file string) (*Payload, error) {
+	raw, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read payload %s: %v", file, err)
+	}
+	p := &Payload{}
+	if err = json.Unmarshal(raw, p); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal payload %s: %v", file, err)
+	}
+	if p.Signed == nil {
+		p.Signed = make(map[string]string)
+	}
+	return p, nil
+}
+
+func (p *Payload) Save(file string) error {
+	raw, err := json.MarshalIndent(p, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(file, raw, 0644); err != nil {
+		return fmt.Errorf("failed to write payload %s: %v", file, err)
+	}
+	return nil
+}
+
+func txToHex(tx *types.Transaction) string {
+	sink := common.NewZeroCopySink(nil)
+	tx.Serialization(sink)
+	return hex.EncodeToString(sink.Bytes())
+}
+
+func txFromHex(str string) (*types.Transaction, error) {
+	raw, err := hex.DecodeString(str)
+	if err != nil {
+		return nil, err
+	}
+	return types.TransactionFromRawBytes(raw)
+}
+
+func (p *Payload) pubKeys() ([]keypair.PublicKey, error) {
+	pks := make([]keypair.PublicKey, len(p.PubKeys))
+	for i, str := range p.PubKeys {
+		raw, err := hex.DecodeString(str)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode No%d pubkey: %v", i, err)
+		}
+		if pks[i], err = keypair.DeserializePublicKey(raw); err != nil {
+			return nil, fmt.Errorf("failed to deserialize No%d pubkey: %v", i, err)
+		}
+	}
+	return pks, nil
+}
+
+// Sign signs the payload with acc. It only needs the wallet, no connection
+// to poly.
+func (p *Payload) Sign(acc *poly_go_sdk.Account) error {
+	poly := poly_go_sdk.NewPolySdk()
+	poly.SetChainId(p.PolyChainId)
+
+	var (
+		tx  *types.Transaction
+		err error
+	)
+	switch p.Action {
+	case ActionApproveRegisterSideChain:
+		tx, err = poly.Native.Scm.NewApproveRegisterSideChainTransaction(p.Id, acc.Address)
+	case ActionApproveUpdateSideChain:
+		tx, err = poly.Native.Scm.NewApproveUpdateSideChainTransaction(p.Id, acc.Address)
+	case ActionApproveCandidate:
+		tx, err = poly.Native.Nm.NewApproveCandidateTransaction(p.PeerPubkey, acc.Address)
+	case ActionApproveRelayer:
+		tx, err = poly.Native.Rm.NewApproveRegisterRelayerTransaction(p.Id, acc.Address)
+	case ActionCommitDpos:
+		if tx, err = txFromHex(p.Unsigned); err != nil {
+			return fmt.Errorf("failed to decode unsigned tx: %v", err)
+		}
+		pks, err := p.pubKeys()
+		if err != nil {
+			return err
+		}
+		if err = poly.MultiSignToTransaction(tx, p.M, pks, acc); err != nil {
+			return fmt.Errorf("failed to multi-sign: %v", err)
+		}
+		p.Signed[acc.Address.ToBase58()] = txToHex(tx)
+		return nil
+	default:
+		return fmt.Errorf("unknown action %s", p.Action)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to build tx of %s: %v", p.Action, err)
+	}
+	if err = poly.SignToTransaction(tx, acc); err != nil {
+		return fmt.Errorf("failed to sign: %v", err)
+	}
+	p.Signed[acc.Address.ToBase58()] = txToHex(tx)
+	return nil
+}
+
+func (p *Payload) sameAs(o *Payload) bool {
+	if p.Action != o.Action || p.PolyChainId != o.PolyChainId || p.Id != o.Id || p.PeerPubkey != o.PeerPubkey ||
+		p.Unsigned != o.Unsigned || p.M != o.M || len(p.PubKeys) != len(o.PubKeys) {
+		return false
+	}
+	for i := range p.PubKeys {
+		if p.PubKeys[i] != o.PubKeys[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Combine merges the signatures of copies signed separately
+func Combine(ps []*Payload) (*Payload, error) {
+	if len(ps) == 0 {
+		return nil, fmt.Errorf("no payload to combine")
+	}
+	res := *ps[0]
+	res.Signed = make(map[string]string)
+	for i, p := range ps {
+		if !res.sameAs(p) {
+			return nil, fmt.Errorf("No%d payload is not the same transaction as the first one", i)
+		}
+		for addr, tx := range p.Signed {
+			res.Signed[addr] = tx
+		}
+	}
+	return &res, nil
+}
+
+// Signers returns the addresses signed the payload in order
+func (p *Payload) Signers() []string {
+	addrs := make([]string, 0, len(p.Signed))
+	for addr := range p.Signed {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+	return addrs
+}
+
+// Transactions returns the signed transactions ready to send. For
+// commit_dpos, all signatures are merged into one transaction.
+func (p *Payload) Transactions() ([]*types.Transaction, error) {
+	if len(p.Signed) == 0 {
+		return nil, fmt.Errorf("payload is not signed by anyone")
+	}
+	txs := make([]*types.Transaction, 0, len(p.Signed))
+	for _, addr := range p.Signers() {
+		tx, err := txFromHex(p.Signed[addr])
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode tx signed by %s: %v", addr, err)
+		}
+		txs = append(txs, tx)
+	}
+	if p.Action != ActionCommitDpos {
+		return txs, nil
+	}
+
+	tx, err := txFromHex(p.Unsigned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode unsigned tx: %v", err)
+	}
+	pks, err := p.pubKeys()
+	if err != nil {
+		return nil, err
+	}
+	sig := types.Sig{PubKeys: pks, M: p.M}
+	seen := make(map[string]bool)
+	for i, signed := range txs {
+		if signed.Hash() != tx.Hash() {
+			return nil, fmt.Errorf("tx signed by %s is not the exported one", p.Signers()[i])
+		}
+		for _, s := range signed.Sigs {
+			for _, data := range s.SigData {
+				if !seen[string(data)] {
+					seen[string(data)] = true
+					sig.SigData = append(sig.SigData, data)
+				}
+			}
+		}
+	}
+	if len(sig.SigData) < int(p.M) {
+		return nil, fmt.Errorf("only %d signatures, %d required", len(sig.SigData), p.M)
+	}
+	tx.Sigs = []types.Sig{sig}
+	return []*types.Transaction{tx}, nil
+}
+
+// Submit sends the transactions of payload to poly
+func (p *Payload) Submit(poly *poly_go_sdk.PolySdk) ([]common.Uint256, error) {
+	txs, err := p.Transactions()
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]common.Uint256, 0, len(txs))
+	for _, tx := range txs {
+		hash, err := poly.SendTransaction(tx)
+		if err != nil {
+			txHash := tx.Hash()
+			return hashes, fmt.Errorf("failed to send tx %s: %v", txHash.ToHexString(), err)
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
+}
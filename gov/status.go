@@ -0,0 +1,435 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package gov
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/polynetwork/poly-go-sdk"
+	"github.com/polynetwork/poly-io-test/plan"
+	"github.com/polynetwork/poly/common"
+	"github.com/polynetwork/poly/core/types"
+	"github.com/polynetwork/poly/native/service/governance/node_manager"
+	"github.com/polynetwork/poly/native/service/governance/relayer_manager"
+	"github.com/polynetwork/poly/native/service/governance/side_chain_manager"
+	"github.com/polynetwork/poly/native/service/utils"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	ProposalRegisterSideChain = "register_side_chain"
+	ProposalUpdateSideChain   = "update_side_chain"
+	ProposalQuitSideChain     = "quit_side_chain"
+	ProposalRegisterRelayer   = "register_relayer"
+	ProposalRemoveRelayer     = "remove_relayer"
+)
+
+// SideChain is a side chain registered on poly, or the one proposed to
+type SideChain struct {
+	ChainId      uint64 `json:"chain_id"`
+	Name         string `json:"name"`
+	Router       uint64 `json:"router"`
+	BlocksToWait uint64 `json:"blocks_to_wait"`
+	Owner        string `json:"owner"`
+	CCMCAddress  string `json:"ccmc_address"`
+}
+
+// Relayer is an address checked against the relayer list of poly
+type Relayer struct {
+	Address    string `json:"address"`
+	Registered bool   `json:"registered"`
+}
+
+// Proposal is an application waiting for the approvals of consensus peers
+type Proposal struct {
+	Kind string `json:"kind"`
+	// chain id for side chains, apply id or remove id for relayers
+	Id        uint64     `json:"id"`
+	SideChain *SideChain `json:"side_chain,omitempty"`
+	Relayers  []string   `json:"relayers,omitempty"`
+	Approvals int        `json:"approvals"`
+	Required  int        `json:"required"`
+	Signers   []string   `json:"signers"`
+}
+
+// Peer is an item of the poly peer pool
+type Peer struct {
+	Index      uint32 `json:"index"`
+	PeerPubkey string `json:"peer_pubkey"`
+	Address    string `json:"address"`
+	Status     string `json:"status"`
+}
+
+// Status is the governance state of poly
+type Status struct {
+	View       uint32       `json:"view"`
+	SideChains []*SideChain `json:"side_chains"`
+	Relayers   []*Relayer   `json:"relayers"`
+	Proposals  []*Proposal  `json:"proposals"`
+	Peers      []*Peer      `json:"peers"`
+}
+
+// GetPeerPool returns the governance view and the peer pool of it
+func GetPeerPool(poly *poly_go_sdk.PolySdk) (*node_manager.GovernanceView, *node_manager.PeerPoolMap, error) {
+	storeBs, err := poly.GetStorage(utils.NodeManagerContractAddress.ToHexString(), []byte(node_manager.GOVERNANCE_VIEW))
+	if err != nil {
+		return nil, nil, err
+	}
+	source := common.NewZeroCopySource(storeBs)
+	gv := new(node_manager.GovernanceView)
+	if err := gv.Deserialization(source); err != nil {
+		return nil, nil, err
+	}
+
+	raw, err := poly.GetStorage(utils.NodeManagerContractAddress.ToHexString(),
+		append([]byte(node_manager.PEER_POOL), utils.GetUint32Bytes(gv.View)...))
+	if err != nil {
+		return nil, nil, err
+	}
+	m := &node_manager.PeerPoolMap{
+		PeerPoolMap: make(map[string]*node_manager.PeerPoolItem),
+	}
+	if err := m.Deserialization(common.NewZeroCopySource(raw)); err != nil {
+		return nil, nil, err
+	}
+	return gv, m, nil
+}
+
+func statusName(status node_manager.Status) string {
+	switch status {
+	case node_manager.CandidateStatus:
+		return "candidate"
+	case node_manager.ConsensusStatus:
+		return "consensus"
+	case node_manager.QuitingStatus:
+		return "quiting"
+	case node_manager.BlackStatus:
+		return "black"
+	}
+	return fmt.Sprintf("unknown(%d)", status)
+}
+
+type querier struct {
+	poly *poly_go_sdk.PolySdk
+	// addresses of consensus peers, only their signs count
+	consensus map[common.Address]bool
+}
+
+func (q *querier) get(contract common.Address, prefix string, key []byte) ([]byte, error) {
+	raw, err := q.poly.GetStorage(contract.ToHexString(), append([]byte(prefix), key...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get storage %s: %v", prefix, err)
+	}
+	return raw, nil
+}
+
+func (q *querier) sideChain(prefix string, chainId uint64) (*SideChain, error) {
+	raw, err := q.get(utils.SideChainManagerContractAddress, prefix, utils.GetUint64Bytes(chainId))
+	if err != nil || len(raw) == 0 {
+		return nil, err
+	}
+	sc := new(side_chain_manager.SideChain)
+	if err := sc.Deserialization(common.NewZeroCopySource(raw)); err != nil {
+		return nil, fmt.Errorf("failed to deserialize %s of chain %d: %v", prefix, chainId, err)
+	}
+	return &SideChain{
+		ChainId:      sc.ChainId,
+		Name:         sc.Name,
+		Router:       sc.Router,
+		BlocksToWait: sc.BlocksToWait,
+		Owner:        sc.Address.ToBase58(),
+		CCMCAddress:  hex.EncodeToString(sc.CCMCAddress),
+	}, nil
+}
+
+func (q *querier) relayerList(prefix string, id uint64) (*relayer_manager.RelayerListParam, error) {
+	raw, err := q.get(utils.RelayerManagerContractAddress, prefix, utils.GetUint64Bytes(id))
+	if err != nil || len(raw) == 0 {
+		return nil, err
+	}
+	param := new(relayer_manager.RelayerListParam)
+	if err := param.Deserialization(common.NewZeroCopySource(raw)); err != nil {
+		return nil, fmt.Errorf("failed to deserialize %s %d: %v", prefix, id, err)
+	}
+	return param, nil
+}
+
+func (q *querier) counter(prefix string) (uint64, error) {
+	raw, err := q.get(utils.RelayerManagerContractAddress, prefix, nil)
+	if err != nil {
+		return 0, err
+	}
+	return utils.GetBytesUint64(raw), nil
+}
+
+func (q *querier) isRelayer(addr common.Address) (bool, error) {
+	raw, err := q.get(utils.RelayerManagerContractAddress, relayer_manager.RELAYER, addr[:])
+	if err != nil {
+		return false, err
+	}
+	return len(raw) > 0, nil
+}
+
+// approve fills the approvals of p, which are stored under the hash of
+// approving method and input by node manager
+func (q *querier) approve(p *Proposal, method string, input []byte) error {
+	key := sha256.Sum256(append([]byte(method), input...))
+	raw, err := q.get(utils.NodeManagerContractAddress, node_manager.CONSENSUS_SIGNS, key[:])
+	if err != nil {
+		return err
+	}
+	p.Required = (2*len(q.consensus) + 2) / 3
+	p.Signers = make([]string, 0)
+	if len(raw) == 0 {
+		return nil
+	}
+	signs := &node_manager.ConsensusSigns{SignsMap: make(map[common.Address]bool)}
+	if err := signs.Deserialization(common.NewZeroCopySource(raw)); err != nil {
+		return fmt.Errorf("failed to deserialize consensus signs of %s %d: %v", p.Kind, p.Id, err)
+	}
+	for addr := range signs.SignsMap {
+		if q.consensus[addr] {
+			p.Approvals++
+		}
+		p.Signers = append(p.Signers, addr.ToBase58())
+	}
+	sort.Strings(p.Signers)
+	return nil
+}
+
+func addrsToBase58(addrs []common.Address) []string {
+	res := make([]string, len(addrs))
+	for i, addr := range addrs {
+		res[i] = addr.ToBase58()
+	}
+	return res
+}
+
+// QueryStatus reads the governance state from poly storage. Storage can not
+// be iterated, so only side chains in chainIds are looked up, and relayers
+// are the ones in relayers plus those found in the relayer applications.
+func QueryStatus(poly *poly_go_sdk.PolySdk, chainIds []uint64, relayers []common.Address) (*Status, error) {
+	gv, m, err := GetPeerPool(poly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get peer pool: %v", err)
+	}
+	status := &Status{
+		View:       gv.View,
+		SideChains: make([]*SideChain, 0),
+		Relayers:   make([]*Relayer, 0),
+		Proposals:  make([]*Proposal, 0),
+		Peers:      make([]*Peer, 0, len(m.PeerPoolMap)),
+	}
+	q := &querier{poly: poly, consensus: make(map[common.Address]bool)}
+	for _, v := range m.PeerPoolMap {
+		status.Peers = append(status.Peers, &Peer{
+			Index:      v.Index,
+			PeerPubkey: v.PeerPubkey,
+			Address:    v.Address.ToBase58(),
+			Status:     statusName(v.Status),
+		})
+		if v.Status != node_manager.ConsensusStatus {
+			continue
+		}
+		raw, err := hex.DecodeString(v.PeerPubkey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode pubkey %s: %v", v.PeerPubkey, err)
+		}
+		pk, err := keypair.DeserializePublicKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to deserialize pubkey %s: %v", v.PeerPubkey, err)
+		}
+		q.consensus[types.AddressFromPubKey(pk)] = true
+	}
+	sort.Slice(status.Peers, func(i, j int) bool {
+		return status.Peers[i].Index < status.Peers[j].Index
+	})
+
+	seen := make(map[uint64]bool)
+	for _, id := range chainIds {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		sc, err := q.sideChain(side_chain_manager.SIDE_CHAIN, id)
+		if err != nil {
+			return nil, err
+		}
+		if sc != nil {
+			status.SideChains = append(status.SideChains, sc)
+		}
+		apply, err := q.sideChain(side_chain_manager.SIDE_CHAIN_APPLY, id)
+		if err != nil {
+			return nil, err
+		}
+		if apply != nil {
+			p := &Proposal{Kind: ProposalRegisterSideChain, Id: id, SideChain: apply}
+			if err := q.approve(p, side_chain_manager.APPROVE_REGISTER_SIDE_CHAIN, utils.GetUint64Bytes(id)); err != nil {
+				return nil, err
+			}
+			status.Proposals = append(status.Proposals, p)
+		}
+		update, err := q.sideChain(side_chain_manager.UPDATE_SIDE_CHAIN_REQUEST, id)
+		if err != nil {
+			return nil, err
+		}
+		if update != nil {
+			p := &Proposal{Kind: ProposalUpdateSideChain, Id: id, SideChain: update}
+			if err := q.approve(p, side_chain_manager.APPROVE_UPDATE_SIDE_CHAIN, utils.GetUint64Bytes(id)); err != nil {
+				return nil, err
+			}
+			status.Proposals = append(status.Proposals, p)
+		}
+		// the quit request is left in storage after approved, it is only
+		// pending while the side chain is still there
+		quit, err := q.get(utils.SideChainManagerContractAddress, side_chain_manager.QUIT_SIDE_CHAIN_REQUEST,
+			utils.GetUint64Bytes(id))
+		if err != nil {
+			return nil, err
+		}
+		if len(quit) > 0 && sc != nil {
+			p := &Proposal{Kind: ProposalQuitSideChain, Id: id, SideChain: sc}
+			if err := q.approve(p, side_chain_manager.QUIT_SIDE_CHAIN, utils.GetUint64Bytes(id)); err != nil {
+				return nil, err
+			}
+			status.Proposals = append(status.Proposals, p)
+		}
+	}
+	sort.Slice(status.SideChains, func(i, j int) bool {
+		return status.SideChains[i].ChainId < status.SideChains[j].ChainId
+	})
+
+	candidates := make([]common.Address, 0, len(relayers))
+	candidates = append(candidates, relayers...)
+	applyId, err := q.counter(relayer_manager.APPLY_ID)
+	if err != nil {
+		return nil, err
+	}
+	for id := uint64(0); id < applyId; id++ {
+		// approved applications are deleted
+		apply, err := q.relayerList(relayer_manager.RELAYER_APPLY, id)
+		if err != nil {
+			return nil, err
+		}
+		if apply == nil {
+			continue
+		}
+		p := &Proposal{Kind: ProposalRegisterRelayer, Id: id, Relayers: addrsToBase58(apply.AddressList)}
+		if err := q.approve(p, relayer_manager.APPROVE_REGISTER_RELAYER, utils.GetUint64Bytes(id)); err != nil {
+			return nil, err
+		}
+		status.Proposals = append(status.Proposals, p)
+		candidates = append(candidates, apply.AddressList...)
+	}
+	removeId, err := q.counter(relayer_manager.REMOVE_ID)
+	if err != nil {
+		return nil, err
+	}
+	for id := uint64(0); id < removeId; id++ {
+		remove, err := q.relayerList(relayer_manager.RELAYER_REMOVE, id)
+		if err != nil {
+			return nil, err
+		}
+		if remove == nil {
+			continue
+		}
+		// removals are kept after approved, it is pending if any relayer
+		// in it is still registered
+		pending := false
+		for _, addr := range remove.AddressList {
+			ok, err := q.isRelayer(addr)
+			if err != nil {
+				return nil, err
+			}
+			pending = pending || ok
+		}
+		if !pending {
+			continue
+		}
+		p := &Proposal{Kind: ProposalRemoveRelayer, Id: id, Relayers: addrsToBase58(remove.AddressList)}
+		if err := q.approve(p, relayer_manager.APPROVE_REMOVE_RELAYER, utils.GetUint64Bytes(id)); err != nil {
+			return nil, err
+		}
+		status.Proposals = append(status.Proposals, p)
+		candidates = append(candidates, remove.AddressList...)
+	}
+
+	checked := make(map[common.Address]bool)
+	for _, addr := range candidates {
+		if checked[addr] {
+			continue
+		}
+		checked[addr] = true
+		ok, err := q.isRelayer(addr)
+		if err != nil {
+			return nil, err
+		}
+		status.Relayers = append(status.Relayers, &Relayer{Address: addr.ToBase58(), Registered: ok})
+	}
+	return status, nil
+}
+
+// Print writes status as tables or json
+func (status *Status) Print(w io.Writer, format string) error {
+	switch format {
+	case plan.FormatJson:
+		raw, err := json.MarshalIndent(status, "", "\t")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(raw))
+		return err
+	case plan.FormatText, "":
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(tw, "SIDE CHAINS\n")
+		fmt.Fprintln(tw, "ID\tNAME\tROUTER\tBLOCKS_TO_WAIT\tOWNER\tCCMC_ADDRESS")
+		for _, sc := range status.SideChains {
+			fmt.Fprintf(tw, "%d\t%s\t%d\t%d\t%s\t%s\n", sc.ChainId, sc.Name, sc.Router, sc.BlocksToWait, sc.Owner,
+				sc.CCMCAddress)
+		}
+		fmt.Fprintf(tw, "\nRELAYERS\n")
+		fmt.Fprintln(tw, "ADDRESS\tREGISTERED")
+		for _, r := range status.Relayers {
+			fmt.Fprintf(tw, "%s\t%t\n", r.Address, r.Registered)
+		}
+		fmt.Fprintf(tw, "\nPENDING PROPOSALS\n")
+		fmt.Fprintln(tw, "KIND\tID\tSUBJECT\tAPPROVALS\tSIGNERS")
+		for _, p := range status.Proposals {
+			subject := strings.Join(p.Relayers, ",")
+			if p.SideChain != nil {
+				subject = fmt.Sprintf("%s router:%d blocks_to_wait:%d ccmc:%s", p.SideChain.Name, p.SideChain.Router,
+					p.SideChain.BlocksToWait, p.SideChain.CCMCAddress)
+			}
+			fmt.Fprintf(tw, "%s\t%d\t%s\t%d/%d\t%s\n", p.Kind, p.Id, subject, p.Approvals, p.Required,
+				strings.Join(p.Signers, ","))
+		}
+		fmt.Fprintf(tw, "\nPEER POOL (view %d)\n", status.View)
+		fmt.Fprintln(tw, "INDEX\tSTATUS\tADDRESS\tPUBKEY")
+		for _, p := range status.Peers {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", p.Index, p.Status, p.Address, p.PeerPubkey)
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unknown format %s", format)
+	}
+}
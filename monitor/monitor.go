@@ -0,0 +1,387 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+
+// Package monitor checks that headers are synced between poly and the side
+// chains. Relayers that stop syncing headers make cross chain txs time out,
+// so it is better to find them before the cases do.
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/cosmos/cosmos-sdk/codec"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ontio/ontology-go-sdk"
+	ocom "github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/smartcontract/service/native/cross_chain/header_sync"
+	outils "github.com/ontio/ontology/smartcontract/service/native/utils"
+	"github.com/polynetwork/cosmos-poly-module/headersync"
+	"github.com/polynetwork/poly-go-sdk"
+	"github.com/polynetwork/poly-io-test/chains/btc"
+	"github.com/polynetwork/poly-io-test/chains/cosmos"
+	"github.com/polynetwork/poly-io-test/chains/eth/abi/eccd"
+	"github.com/polynetwork/poly-io-test/config"
+	"github.com/polynetwork/poly-io-test/log"
+	"github.com/polynetwork/poly-io-test/plan"
+	pcom "github.com/polynetwork/poly/common"
+	"github.com/polynetwork/poly/native/service/governance/node_manager"
+	hsbtc "github.com/polynetwork/poly/native/service/header_sync/btc"
+	hscom "github.com/polynetwork/poly/native/service/header_sync/common"
+	hscosmos "github.com/polynetwork/poly/native/service/header_sync/cosmos"
+	"github.com/polynetwork/poly/native/service/utils"
+	"github.com/tendermint/tendermint/rpc/client/http"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusLagging     = "lagging"
+	StatusEpochBehind = "epoch_behind"
+	StatusError       = "error"
+)
+
+// Sync is the header sync state between poly and one side chain
+type Sync struct {
+	Chain   string `json:"chain"`
+	ChainId uint64 `json:"chain_id"`
+	// real tip of the side chain and the latest header of it on poly
+	Tip    uint64 `json:"tip"`
+	Synced uint64 `json:"synced"`
+	Lag    int64  `json:"lag"`
+	// poly height now, the start of current poly epoch and the poly epoch
+	// known by the ECCD/ECCM of side chain
+	PolyHeight uint64 `json:"poly_height"`
+	PolyEpoch  uint64 `json:"poly_epoch"`
+	ChainEpoch uint64 `json:"chain_epoch"`
+	Status     string `json:"status"`
+	Note       string `json:"note,omitempty"`
+	Err        string `json:"error,omitempty"`
+}
+
+// Monitor compares the header heights on poly with the tips of side chains.
+// Clients of chains not in the environment can be nil.
+type Monitor struct {
+	BtcCli    btc.BtcClient
+	EthCli    *ethclient.Client
+	OntSdk    *ontology_go_sdk.OntologySdk
+	CMCli     *http.HTTP
+	CMCdc     *codec.Codec
+	Poly      *poly_go_sdk.PolySdk
+	Threshold uint64
+}
+
+func NewMonitor(btcCli btc.BtcClient, ethCli *ethclient.Client, ontSdk *ontology_go_sdk.OntologySdk, cmCli *http.HTTP,
+	cmCdc *codec.Codec, poly *poly_go_sdk.PolySdk, threshold uint64) *Monitor {
+	return &Monitor{
+		BtcCli:    btcCli,
+		EthCli:    ethCli,
+		OntSdk:    ontSdk,
+		CMCli:     cmCli,
+		CMCdc:     cmCdc,
+		Poly:      poly,
+		Threshold: threshold,
+	}
+}
+
+// Dial connects the side chains in config. A chain failed to connect is
+// reported as error by Check, not failing the others.
+func Dial(poly *poly_go_sdk.PolySdk, threshold uint64) *Monitor {
+	m := NewMonitor(nil, nil, nil, nil, cosmos.NewCodec(), poly, threshold)
+	if config.DefConfig.BtcRestAddr != "" {
+		m.BtcCli = btc.NewRestCli(config.DefConfig.BtcRestAddr, config.DefConfig.BtcRestUser,
+			config.DefConfig.BtcRestPwd)
+	}
+	if cli, err := ethclient.Dial(config.DefConfig.EthURL); err != nil {
+		log.Errorf("header monitor: failed to dial ethereum %s: %v", config.DefConfig.EthURL, err)
+	} else {
+		m.EthCli = cli
+	}
+	if config.DefConfig.OntJsonRpcAddress != "" {
+		m.OntSdk = ontology_go_sdk.NewOntologySdk()
+		m.OntSdk.NewRpcClient().SetAddress(config.DefConfig.OntJsonRpcAddress)
+	}
+	if cli, err := http.New(config.DefConfig.CMRpcUrl, "/websocket"); err != nil {
+		log.Errorf("header monitor: failed to new cosmos client: %v", err)
+	} else {
+		m.CMCli = cli
+	}
+	return m
+}
+
+func (m *Monitor) polyStorage(prefix string, key []byte) ([]byte, error) {
+	raw, err := m.Poly.GetStorage(utils.HeaderSyncContractAddress.ToHexString(), append([]byte(prefix), key...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s from poly: %v", prefix, err)
+	}
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("no %s on poly, genesis header not synced?", prefix)
+	}
+	return raw, nil
+}
+
+func (m *Monitor) btcSync(s *Sync) error {
+	if m.BtcCli == nil {
+		return fmt.Errorf("no btc client")
+	}
+	tip, err := m.BtcCli.GetBlockCount()
+	if err != nil {
+		return err
+	}
+	s.Tip = uint64(tip)
+	raw, err := m.polyStorage(hscom.CURRENT_HEADER_HEIGHT, utils.GetUint64Bytes(s.ChainId))
+	if err != nil {
+		return err
+	}
+	best := new(hsbtc.StoredHeader)
+	if err = best.Deserialization(pcom.NewZeroCopySource(raw)); err != nil {
+		return fmt.Errorf("failed to deserialize best btc header: %v", err)
+	}
+	s.Synced = uint64(best.Height)
+	s.Note = "poly is verified by the vendor, no epoch on chain"
+	return nil
+}
+
+func (m *Monitor) ethSync(s *Sync) error {
+	if m.EthCli == nil {
+		return fmt.Errorf("no ethereum client")
+	}
+	hdr, err := m.EthCli.HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to get ethereum tip: %v", err)
+	}
+	s.Tip = hdr.Number.Uint64()
+	raw, err := m.polyStorage(hscom.CURRENT_HEADER_HEIGHT, utils.GetUint64Bytes(s.ChainId))
+	if err != nil {
+		return err
+	}
+	s.Synced = utils.GetBytesUint64(raw)
+
+	data, err := eccd.NewEthCrossChainData(common.HexToAddress(config.DefConfig.Eccd), m.EthCli)
+	if err != nil {
+		return fmt.Errorf("failed to new eccd: %v", err)
+	}
+	epoch, err := data.GetCurEpochStartHeight(&bind.CallOpts{})
+	if err != nil {
+		return fmt.Errorf("failed to get epoch start height from eccd: %v", err)
+	}
+	s.ChainEpoch = uint64(epoch)
+	return nil
+}
+
+func (m *Monitor) ontSync(s *Sync) error {
+	if m.OntSdk == nil {
+		return fmt.Errorf("no ontology sdk")
+	}
+	tip, err := m.OntSdk.GetCurrentBlockHeight()
+	if err != nil {
+		return fmt.Errorf("failed to get ontology tip: %v", err)
+	}
+	s.Tip = uint64(tip)
+	raw, err := m.polyStorage(hscom.CURRENT_HEADER_HEIGHT, utils.GetUint64Bytes(s.ChainId))
+	if err != nil {
+		return err
+	}
+	s.Synced = uint64(utils.GetBytesUint32(raw))
+
+	// ontology keeps the heights of poly key headers in its own header sync
+	key, err := outils.GetUint64Bytes(m.Poly.ChainId)
+	if err != nil {
+		return err
+	}
+	raw, err = m.OntSdk.GetStorage(outils.HeaderSyncContractAddress.ToHexString(),
+		append([]byte(header_sync.KEY_HEIGHTS), key...))
+	if err != nil {
+		return fmt.Errorf("failed to get poly key heights from ontology: %v", err)
+	}
+	heights := new(header_sync.KeyHeights)
+	if err = heights.Deserialization(ocom.NewZeroCopySource(raw)); err != nil {
+		return fmt.Errorf("failed to deserialize poly key heights: %v", err)
+	}
+	for _, h := range heights.HeightList {
+		if uint64(h) > s.ChainEpoch {
+			s.ChainEpoch = uint64(h)
+		}
+	}
+	return nil
+}
+
+func (m *Monitor) cosmosSync(s *Sync) error {
+	if m.CMCli == nil {
+		return fmt.Errorf("no cosmos client")
+	}
+	status, err := m.CMCli.Status()
+	if err != nil {
+		return fmt.Errorf("failed to get cosmos status: %v", err)
+	}
+	s.Tip = uint64(status.SyncInfo.LatestBlockHeight)
+	raw, err := m.polyStorage(hscom.EPOCH_SWITCH, utils.GetUint64Bytes(s.ChainId))
+	if err != nil {
+		return err
+	}
+	info := new(hscosmos.CosmosEpochSwitchInfo)
+	if err = info.Deserialization(pcom.NewZeroCopySource(raw)); err != nil {
+		return fmt.Errorf("failed to deserialize cosmos epoch switch info: %v", err)
+	}
+	s.Synced = uint64(info.Height)
+	s.Note = "poly only keeps the epoch switch of cosmos, lag is not checked"
+
+	param, err := m.CMCdc.MarshalJSON(headersync.NewQueryConsensusPeersParams(m.Poly.ChainId))
+	if err != nil {
+		return err
+	}
+	res, err := m.CMCli.ABCIQuery(fmt.Sprintf("custom/%s/%s", headersync.QuerierRoute, headersync.QueryConsensusPeers),
+		param)
+	if err != nil {
+		return fmt.Errorf("failed to query poly consensus peers on cosmos: %v", err)
+	}
+	if !res.Response.IsOK() {
+		return fmt.Errorf("failed to query poly consensus peers on cosmos: %s", res.Response.Log)
+	}
+	peers := new(headersync.ConsensusPeers)
+	if err = peers.Deserialization(pcom.NewZeroCopySource(res.Response.Value)); err != nil {
+		return fmt.Errorf("failed to deserialize poly consensus peers on cosmos: %v", err)
+	}
+	s.ChainEpoch = uint64(peers.Height)
+	return nil
+}
+
+func (m *Monitor) polyEpoch() (uint64, uint64, error) {
+	height, err := m.Poly.GetCurrentBlockHeight()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get poly height: %v", err)
+	}
+	raw, err := m.Poly.GetStorage(utils.NodeManagerContractAddress.ToHexString(), []byte(node_manager.GOVERNANCE_VIEW))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get governance view: %v", err)
+	}
+	gv := new(node_manager.GovernanceView)
+	if err = gv.Deserialization(pcom.NewZeroCopySource(raw)); err != nil {
+		return 0, 0, fmt.Errorf("failed to deserialize governance view: %v", err)
+	}
+	return uint64(height), uint64(gv.Height), nil
+}
+
+// Check returns the sync state of every side chain in config
+func (m *Monitor) Check() []*Sync {
+	polyHeight, polyEpoch, polyErr := m.polyEpoch()
+	chains := []struct {
+		name string
+		id   uint64
+		get  func(s *Sync) error
+	}{
+		{"bitcoin", config.BTC_CHAIN_ID, m.btcSync},
+		{"ethereum", config.ETH_CHAIN_ID, m.ethSync},
+		{"ontology", config.ONT_CHAIN_ID, m.ontSync},
+		{"cosmos", config.DefConfig.CMCrossChainId, m.cosmosSync},
+	}
+	res := make([]*Sync, 0, len(chains))
+	for _, c := range chains {
+		s := &Sync{Chain: c.name, ChainId: c.id, PolyHeight: polyHeight, PolyEpoch: polyEpoch}
+		err := polyErr
+		if err == nil {
+			err = c.get(s)
+		}
+		res = append(res, m.judge(s, err))
+	}
+	return res
+}
+
+func (m *Monitor) judge(s *Sync, err error) *Sync {
+	if err != nil {
+		s.Status, s.Err = StatusError, err.Error()
+		return s
+	}
+	s.Lag = int64(s.Tip) - int64(s.Synced)
+	switch {
+	case s.Chain != "cosmos" && s.Lag > int64(m.Threshold):
+		s.Status = StatusLagging
+	case s.Chain != "bitcoin" && s.ChainEpoch < s.PolyEpoch:
+		// the key header of the poly epoch is not relayed to the chain
+		s.Status = StatusEpochBehind
+	default:
+		s.Status = StatusOK
+	}
+	return s
+}
+
+// Unhealthy returns the chains not in sync
+func Unhealthy(res []*Sync) []*Sync {
+	bad := make([]*Sync, 0)
+	for _, s := range res {
+		if s.Status != StatusOK {
+			bad = append(bad, s)
+		}
+	}
+	return bad
+}
+
+// Watch checks every interval and logs the chains not in sync until stop
+// is closed
+func (m *Monitor) Watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for _, s := range Unhealthy(m.Check()) {
+			switch s.Status {
+			case StatusError:
+				log.Warnf("header monitor: failed to check %s: %s", s.Chain, s.Err)
+			case StatusLagging:
+				log.Warnf("header monitor: %s headers on poly lag %d blocks behind (tip: %d, synced: %d, "+
+					"threshold: %d), is the relayer alive?", s.Chain, s.Lag, s.Tip, s.Synced, m.Threshold)
+			case StatusEpochBehind:
+				log.Warnf("header monitor: %s only knows poly epoch %d but poly switched at %d", s.Chain,
+					s.ChainEpoch, s.PolyEpoch)
+			}
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func Print(w io.Writer, res []*Sync, format string) error {
+	switch format {
+	case plan.FormatJson:
+		raw, err := json.MarshalIndent(res, "", "\t")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(raw))
+		return err
+	case plan.FormatText, "":
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "CHAIN\tTIP\tSYNCED\tLAG\tPOLY_HEIGHT\tPOLY_EPOCH\tCHAIN_EPOCH\tSTATUS\tNOTE")
+		for _, s := range res {
+			note := s.Note
+			if s.Err != "" {
+				note = s.Err
+			}
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t%s\t%s\n", s.Chain, s.Tip, s.Synced, s.Lag, s.PolyHeight,
+				s.PolyEpoch, s.ChainEpoch, s.Status, note)
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unknown format %s", format)
+	}
+}
@@ -0,0 +1,493 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package monitor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/polynetwork/poly-go-sdk"
+	"github.com/polynetwork/poly-io-test/chains/btc"
+	"github.com/polynetwork/poly-io-test/config"
+	"github.com/polynetwork/poly-io-test/log"
+	"github.com/polynetwork/poly-io-test/plan"
+	pcom "github.com/polynetwork/poly/common"
+	pbtc "github.com/polynetwork/poly/native/service/cross_chain_manager/btc"
+	"github.com/polynetwork/poly/native/service/governance/side_chain_manager"
+	"github.com/polynetwork/poly/native/service/utils"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	StatusNoTxParam = "no_tx_param"
+	StatusUtxoDiff  = "utxo_mismatch"
+	StatusUnsigned  = "waiting_signatures"
+)
+
+// MultisigUtxo is an output locked to the multisig of the vendor
+type MultisigUtxo struct {
+	Outpoint string `json:"outpoint"`
+	Value    int64  `json:"value"`
+	Confs    int64  `json:"confs,omitempty"`
+}
+
+// UnsignedTx is a tx made by poly to unlock btc, waiting for the vendor to sign
+type UnsignedTx struct {
+	Txid       string   `json:"txid"`
+	PolyHeight uint32   `json:"poly_height"`
+	Inputs     int      `json:"inputs"`
+	Value      int64    `json:"value"` // paid to users
+	Signed     int      `json:"signed"`
+	Required   int      `json:"required"`
+	Signers    []string `json:"signers,omitempty"`
+	// outpoints paying back to the multisig, which poly takes as its utxos at once
+	changes []string
+}
+
+// Vendor is the state of the btc multisig vendor on bitcoin and poly
+type Vendor struct {
+	P2sh  string `json:"p2sh"`
+	P2wsh string `json:"p2wsh"`
+	// utxos of the multisig on bitcoin and the ones poly thinks it can spend
+	NodeUtxos []*MultisigUtxo `json:"node_utxos"`
+	PolyUtxos []*MultisigUtxo `json:"poly_utxos"`
+	NodeValue int64           `json:"node_value"`
+	PolyValue int64           `json:"poly_value"`
+	// deposits not relayed to poly yet, and utxos poly may spend but gone on bitcoin
+	OnlyNode []string `json:"only_node,omitempty"`
+	OnlyPoly []string `json:"only_poly,omitempty"`
+	// BtcTxParam of the vendor on poly
+	ParamVersion uint64        `json:"param_version"`
+	FeeRate      uint64        `json:"fee_rate"`
+	MinChange    uint64        `json:"min_change"`
+	Unsigned     []*UnsignedTx `json:"unsigned"`
+	PolyHeight   uint32        `json:"poly_height"`
+	Status       string        `json:"status"`
+	Err          string        `json:"error,omitempty"`
+}
+
+// VendorMonitor compares the utxos of the btc multisig on bitcoin with the view of poly, and
+// tracks the unlock txs poly made from makeBtcTx events until the vendor signs them
+type VendorMonitor struct {
+	Poly     *poly_go_sdk.PolySdk
+	BtcCli   btc.BtcClient
+	Redeem   []byte
+	NetParam *chaincfg.Params
+	// poly blocks an unsigned tx may wait, or the change of a signed one may stay off bitcoin, before reported
+	Threshold uint32
+
+	scanned uint32
+	pending map[string]*UnsignedTx
+	// changes of txs fully signed, to the poly height they are found signed at
+	signed map[string]uint32
+}
+
+func NewVendorMonitor(poly *poly_go_sdk.PolySdk, btcCli btc.BtcClient, redeem []byte, netParam *chaincfg.Params,
+	threshold uint32) *VendorMonitor {
+	return &VendorMonitor{
+		Poly:      poly,
+		BtcCli:    btcCli,
+		Redeem:    redeem,
+		NetParam:  netParam,
+		Threshold: threshold,
+		pending:   make(map[string]*UnsignedTx),
+		signed:    make(map[string]uint32),
+	}
+}
+
+// DialVendor monitors the vendor of BtcRedeem in config
+func DialVendor(poly *poly_go_sdk.PolySdk, threshold uint32) (*VendorMonitor, error) {
+	redeem, err := hex.DecodeString(config.DefConfig.BtcRedeem)
+	if err != nil || len(redeem) == 0 {
+		return nil, fmt.Errorf("wrong BtcRedeem %s: %v", config.DefConfig.BtcRedeem, err)
+	}
+	cli := btc.NewRestCli(config.DefConfig.BtcRestAddr, config.DefConfig.BtcRestUser, config.DefConfig.BtcRestPwd)
+	return NewVendorMonitor(poly, cli, redeem, config.BtcNet, threshold), nil
+}
+
+func (m *VendorMonitor) redeemKey() string {
+	return hex.EncodeToString(btcutil.Hash160(m.Redeem))
+}
+
+// Addresses returns the P2SH and P2WSH addresses of the multisig
+func (m *VendorMonitor) Addresses() (btcutil.Address, btcutil.Address, error) {
+	p2sh, err := btcutil.NewAddressScriptHash(m.Redeem, m.NetParam)
+	if err != nil {
+		return nil, nil, err
+	}
+	hash := chainhash.HashB(m.Redeem)
+	p2wsh, err := btcutil.NewAddressWitnessScriptHash(hash, m.NetParam)
+	if err != nil {
+		return nil, nil, err
+	}
+	return p2sh, p2wsh, nil
+}
+
+// ScanFrom makes the next Check scan makeBtcTx events from poly height h
+func (m *VendorMonitor) ScanFrom(h uint32) {
+	if h > 0 {
+		m.scanned = h - 1
+	}
+}
+
+func (m *VendorMonitor) scan(height uint32) error {
+	rk := m.redeemKey()
+	for m.scanned < height {
+		h := m.scanned + 1
+		events, err := m.Poly.GetSmartContractEventByBlock(h)
+		if err != nil {
+			return fmt.Errorf("failed to get events of poly block %d: %v", h, err)
+		}
+		for _, e := range events {
+			for _, n := range e.Notify {
+				if n.ContractAddress != utils.CrossChainManagerContractAddress.ToHexString() {
+					continue
+				}
+				states, ok := n.States.([]interface{})
+				if !ok || len(states) < 3 {
+					continue
+				}
+				if name, _ := states[0].(string); name != "makeBtcTx" {
+					continue
+				}
+				if key, _ := states[1].(string); key != rk {
+					continue
+				}
+				raw, _ := states[2].(string)
+				if u, err := m.decodeUnsigned(raw); err != nil {
+					log.Warnf("vendor monitor: wrong makeBtcTx in poly tx %s: %v", e.TxHash, err)
+				} else {
+					u.PolyHeight = h
+					m.pending[u.Txid] = u
+				}
+			}
+		}
+		m.scanned = h
+	}
+	return nil
+}
+
+func (m *VendorMonitor) decodeUnsigned(raw string) (*UnsignedTx, error) {
+	rawTx, err := hex.DecodeString(raw)
+	if err != nil {
+		return nil, err
+	}
+	mtx := wire.NewMsgTx(wire.TxVersion)
+	if err = mtx.BtcDecode(bytes.NewBuffer(rawTx), wire.ProtocolVersion, wire.LatestEncoding); err != nil {
+		return nil, err
+	}
+	_, _, required, err := txscript.ExtractPkScriptAddrs(m.Redeem, m.NetParam)
+	if err != nil {
+		return nil, err
+	}
+	p2sh, p2wsh, err := btc.MultisigPkScripts(m.Redeem, m.NetParam)
+	if err != nil {
+		return nil, err
+	}
+	u := &UnsignedTx{Txid: mtx.TxHash().String(), Inputs: len(mtx.TxIn), Required: required}
+	for i, out := range mtx.TxOut {
+		if bytes.Equal(out.PkScript, p2sh) || bytes.Equal(out.PkScript, p2wsh) {
+			u.changes = append(u.changes, fmt.Sprintf("%s:%d", u.Txid, i))
+		} else {
+			u.Value += out.Value
+		}
+	}
+	return u, nil
+}
+
+// signing updates the signatures of pending txs, and drops the ones fully signed at poly height
+func (m *VendorMonitor) signing(height uint32) error {
+	for txid, u := range m.pending {
+		hash, err := chainhash.NewHashFromStr(txid)
+		if err != nil {
+			return err
+		}
+		raw, err := m.Poly.GetStorage(utils.CrossChainManagerContractAddress.ToHexString(),
+			append([]byte(pbtc.MULTI_SIGN_INFO), hash[:]...))
+		if err != nil {
+			return fmt.Errorf("failed to get signatures of %s: %v", txid, err)
+		}
+		info := &pbtc.MultiSignInfo{MultiSignInfo: make(map[string][][]byte)}
+		if len(raw) > 0 {
+			if err = info.Deserialization(pcom.NewZeroCopySource(raw)); err != nil {
+				return fmt.Errorf("failed to deserialize signatures of %s: %v", txid, err)
+			}
+		}
+		if len(info.MultiSignInfo) >= u.Required {
+			for _, op := range u.changes {
+				m.signed[op] = height
+			}
+			delete(m.pending, txid)
+			continue
+		}
+		u.Signed, u.Signers = len(info.MultiSignInfo), make([]string, 0, len(info.MultiSignInfo))
+		for addr := range info.MultiSignInfo {
+			u.Signers = append(u.Signers, addr)
+		}
+		sort.Strings(u.Signers)
+	}
+	return nil
+}
+
+func (m *VendorMonitor) nodeUtxos(v *Vendor) error {
+	for _, addr := range []string{v.P2sh, v.P2wsh} {
+		utxos, err := m.BtcCli.ListUnspent(0, math.MaxInt32, addr)
+		if err != nil {
+			return fmt.Errorf("failed to list utxos of %s, imported into node?: %v", addr, err)
+		}
+		for _, u := range utxos {
+			v.NodeUtxos = append(v.NodeUtxos, &MultisigUtxo{
+				Outpoint: fmt.Sprintf("%s:%d", u.Txid, u.Vout),
+				Value:    u.Amount,
+				Confs:    u.Confs,
+			})
+			v.NodeValue += u.Amount
+		}
+	}
+	return nil
+}
+
+func (m *VendorMonitor) polyView(v *Vendor) error {
+	raw, err := m.Poly.GetStorage(utils.CrossChainManagerContractAddress.ToHexString(),
+		append(append([]byte(pbtc.UTXOS), utils.GetUint64Bytes(config.BTC_CHAIN_ID)...), []byte(m.redeemKey())...))
+	if err != nil {
+		return fmt.Errorf("failed to get utxos on poly: %v", err)
+	}
+	utxos := &pbtc.Utxos{Utxos: make([]*pbtc.Utxo, 0)}
+	if len(raw) > 0 {
+		if err = utxos.Deserialization(pcom.NewZeroCopySource(raw)); err != nil {
+			return fmt.Errorf("failed to deserialize utxos on poly: %v", err)
+		}
+	}
+	for _, u := range utxos.Utxos {
+		hash, err := chainhash.NewHash(u.Op.Hash)
+		if err != nil {
+			return fmt.Errorf("wrong utxo hash on poly: %v", err)
+		}
+		v.PolyUtxos = append(v.PolyUtxos, &MultisigUtxo{
+			Outpoint: fmt.Sprintf("%s:%d", hash.String(), u.Op.Index),
+			Value:    int64(u.Value),
+		})
+		v.PolyValue += int64(u.Value)
+	}
+
+	raw, err = m.Poly.GetStorage(utils.SideChainManagerContractAddress.ToHexString(),
+		append(append([]byte(side_chain_manager.BTC_TX_PARAM), btcutil.Hash160(m.Redeem)...),
+			utils.GetUint64Bytes(config.BTC_CHAIN_ID)...))
+	if err != nil {
+		return fmt.Errorf("failed to get btc tx param on poly: %v", err)
+	}
+	if len(raw) > 0 {
+		detail := &side_chain_manager.BtcTxParamDetial{}
+		if err = detail.Deserialization(pcom.NewZeroCopySource(raw)); err != nil {
+			return fmt.Errorf("failed to deserialize btc tx param: %v", err)
+		}
+		v.ParamVersion, v.FeeRate, v.MinChange = detail.PVersion, detail.FeeRate, detail.MinChange
+	}
+	return nil
+}
+
+// diffUtxos returns the outpoints only in a and only in b
+func diffUtxos(a, b []*MultisigUtxo) ([]string, []string) {
+	inA, inB := make(map[string]bool), make(map[string]bool)
+	for _, u := range a {
+		inA[u.Outpoint] = true
+	}
+	for _, u := range b {
+		inB[u.Outpoint] = true
+	}
+	onlyA, onlyB := make([]string, 0), make([]string, 0)
+	for _, u := range a {
+		if !inB[u.Outpoint] {
+			onlyA = append(onlyA, u.Outpoint)
+		}
+	}
+	for _, u := range b {
+		if !inA[u.Outpoint] {
+			onlyB = append(onlyB, u.Outpoint)
+		}
+	}
+	return onlyA, onlyB
+}
+
+// unbroadcast drops from v.OnlyPoly the changes of unlock txs not expected on bitcoin yet: the ones
+// waiting for signatures and the ones signed within Threshold poly blocks
+func (m *VendorMonitor) unbroadcast(v *Vendor) {
+	expected := make(map[string]bool)
+	for _, u := range m.pending {
+		for _, op := range u.changes {
+			expected[op] = true
+		}
+	}
+	for op, h := range m.signed {
+		if v.PolyHeight-h <= m.Threshold {
+			expected[op] = true
+		}
+	}
+	onlyPoly, missing := make(map[string]bool), make([]string, 0)
+	for _, op := range v.OnlyPoly {
+		onlyPoly[op] = true
+		if !expected[op] {
+			missing = append(missing, op)
+		}
+	}
+	// forget the changes already on bitcoin or spent
+	for op := range m.signed {
+		if !onlyPoly[op] {
+			delete(m.signed, op)
+		}
+	}
+	v.OnlyPoly = missing
+}
+
+// status tells the problem of v, unsigned txs waiting for more than Threshold poly blocks included
+func (m *VendorMonitor) status(v *Vendor) string {
+	switch {
+	case v.FeeRate == 0:
+		// poly refuses to make unlock txs without it
+		return StatusNoTxParam
+	case len(v.OnlyPoly) > 0:
+		return StatusUtxoDiff
+	}
+	for _, u := range v.Unsigned {
+		if v.PolyHeight-u.PolyHeight > m.Threshold {
+			return StatusUnsigned
+		}
+	}
+	return StatusOK
+}
+
+// Check returns the state of the vendor, scanning poly blocks since the last check
+func (m *VendorMonitor) Check() *Vendor {
+	v := &Vendor{}
+	err := func() error {
+		p2sh, p2wsh, err := m.Addresses()
+		if err != nil {
+			return err
+		}
+		v.P2sh, v.P2wsh = p2sh.EncodeAddress(), p2wsh.EncodeAddress()
+		if v.PolyHeight, err = m.Poly.GetCurrentBlockHeight(); err != nil {
+			return fmt.Errorf("failed to get poly height: %v", err)
+		}
+		if err = m.scan(v.PolyHeight); err != nil {
+			return err
+		}
+		if err = m.signing(v.PolyHeight); err != nil {
+			return err
+		}
+		if err = m.nodeUtxos(v); err != nil {
+			return err
+		}
+		return m.polyView(v)
+	}()
+	for _, u := range m.pending {
+		v.Unsigned = append(v.Unsigned, u)
+	}
+	sort.Slice(v.Unsigned, func(i, j int) bool {
+		return v.Unsigned[i].PolyHeight < v.Unsigned[j].PolyHeight
+	})
+	if err != nil {
+		v.Status, v.Err = StatusError, err.Error()
+		return v
+	}
+
+	v.OnlyNode, v.OnlyPoly = diffUtxos(v.NodeUtxos, v.PolyUtxos)
+	m.unbroadcast(v)
+	v.Status = m.status(v)
+	return v
+}
+
+// Watch checks every interval and logs the problems of the vendor until stop is closed
+func (m *VendorMonitor) Watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		switch v := m.Check(); v.Status {
+		case StatusError:
+			log.Warnf("vendor monitor: failed to check: %s", v.Err)
+		case StatusNoTxParam:
+			log.Warnf("vendor monitor: no btc tx param on poly, unlocks to btc will fail")
+		case StatusUtxoDiff:
+			log.Warnf("vendor monitor: poly may spend %d utxos not on bitcoin: %s", len(v.OnlyPoly),
+				strings.Join(v.OnlyPoly, ", "))
+		case StatusUnsigned:
+			for _, u := range v.Unsigned {
+				log.Warnf("vendor monitor: unlock tx %s made at poly height %d has %d/%d signatures, "+
+					"is the vendor signing tool alive?", u.Txid, u.PolyHeight, u.Signed, u.Required)
+			}
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func PrintVendor(w io.Writer, v *Vendor, format string) error {
+	switch format {
+	case plan.FormatJson:
+		raw, err := json.MarshalIndent(v, "", "\t")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(raw))
+		return err
+	case plan.FormatText, "":
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(tw, "multisig:\t%s, %s\n", v.P2sh, v.P2wsh)
+		fmt.Fprintf(tw, "status:\t%s\n", v.Status)
+		if v.Err != "" {
+			fmt.Fprintf(tw, "error:\t%s\n", v.Err)
+		}
+		fmt.Fprintf(tw, "btc tx param:\tversion %d, fee rate %d, min change %d\n", v.ParamVersion, v.FeeRate,
+			v.MinChange)
+		fmt.Fprintf(tw, "utxos on bitcoin:\t%d, %d satoshi\n", len(v.NodeUtxos), v.NodeValue)
+		fmt.Fprintf(tw, "utxos on poly:\t%d, %d satoshi\n", len(v.PolyUtxos), v.PolyValue)
+		for _, op := range v.OnlyNode {
+			fmt.Fprintf(tw, "only on bitcoin:\t%s\n", op)
+		}
+		for _, op := range v.OnlyPoly {
+			fmt.Fprintf(tw, "only on poly:\t%s\n", op)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		if len(v.Unsigned) == 0 {
+			return nil
+		}
+		fmt.Fprintln(tw, "\nUNSIGNED_TX\tPOLY_HEIGHT\tINPUTS\tVALUE\tSIGNED\tSIGNERS")
+		for _, u := range v.Unsigned {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d/%d\t%s\n", u.Txid, u.PolyHeight, u.Inputs, u.Value, u.Signed,
+				u.Required, strings.Join(u.Signers, ","))
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unknown format %s", format)
+	}
+}
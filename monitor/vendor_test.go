@@ -0,0 +1,114 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package monitor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/polynetwork/poly-io-test/chains/btc"
+	"reflect"
+	"testing"
+)
+
+func newTestVendor(t *testing.T) *VendorMonitor {
+	vendor, _ := btc.TestVendor(t, &chaincfg.RegressionNetParams)
+	return NewVendorMonitor(nil, nil, vendor.Redeem, &chaincfg.RegressionNetParams, 10)
+}
+
+func TestDiffUtxos(t *testing.T) {
+	a := []*MultisigUtxo{{Outpoint: "a:0"}, {Outpoint: "b:0"}}
+	b := []*MultisigUtxo{{Outpoint: "b:0"}, {Outpoint: "c:1"}}
+	onlyA, onlyB := diffUtxos(a, b)
+	if !reflect.DeepEqual(onlyA, []string{"a:0"}) || !reflect.DeepEqual(onlyB, []string{"c:1"}) {
+		t.Fatalf("wrong diff: %v, %v", onlyA, onlyB)
+	}
+	if onlyA, onlyB = diffUtxos(a, a); len(onlyA) != 0 || len(onlyB) != 0 {
+		t.Fatalf("same utxos should have no diff: %v, %v", onlyA, onlyB)
+	}
+}
+
+func TestDecodeUnsigned(t *testing.T) {
+	m := newTestVendor(t)
+	_, p2wsh, err := btc.MultisigPkScripts(m.Redeem, m.NetParam)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, _ := btcutil.NewAddressPubKeyHash(btcutil.Hash160([]byte("user")), m.NetParam)
+	userScript, _ := txscript.PayToAddrScript(user)
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{1}, 0), nil, nil))
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{2}, 1), nil, nil))
+	tx.AddTxOut(wire.NewTxOut(30000, userScript))
+	tx.AddTxOut(wire.NewTxOut(20000, p2wsh))
+	var buf bytes.Buffer
+	if err = tx.BtcEncode(&buf, wire.ProtocolVersion, wire.LatestEncoding); err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := m.decodeUnsigned(hex.EncodeToString(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	txid := tx.TxHash().String()
+	if u.Txid != txid || u.Inputs != 2 || u.Value != 30000 || u.Required != 2 {
+		t.Fatalf("wrong unsigned tx: %+v", u)
+	}
+	if !reflect.DeepEqual(u.changes, []string{fmt.Sprintf("%s:1", txid)}) {
+		t.Fatalf("wrong changes: %v", u.changes)
+	}
+	if _, err = m.decodeUnsigned("zz"); err == nil {
+		t.Fatal("should fail with wrong hex")
+	}
+}
+
+func TestUnbroadcast(t *testing.T) {
+	m := newTestVendor(t)
+	m.pending["p"] = &UnsignedTx{Txid: "p", changes: []string{"p:1"}}
+	m.signed["s:1"], m.signed["old:1"], m.signed["done:1"] = 95, 80, 95
+	v := &Vendor{PolyHeight: 100, OnlyPoly: []string{"p:1", "s:1", "old:1", "gone:0"}}
+	m.unbroadcast(v)
+	if !reflect.DeepEqual(v.OnlyPoly, []string{"old:1", "gone:0"}) {
+		t.Fatalf("wrong missing utxos: %v", v.OnlyPoly)
+	}
+	if _, ok := m.signed["done:1"]; ok || len(m.signed) != 2 {
+		t.Fatalf("change on bitcoin should be forgotten: %v", m.signed)
+	}
+}
+
+func TestVendorStatus(t *testing.T) {
+	m := newTestVendor(t)
+	for _, c := range []struct {
+		v      *Vendor
+		status string
+	}{
+		{&Vendor{PolyHeight: 100}, StatusNoTxParam},
+		{&Vendor{PolyHeight: 100, FeeRate: 1, OnlyPoly: []string{"a:0"}}, StatusUtxoDiff},
+		{&Vendor{PolyHeight: 100, FeeRate: 1, Unsigned: []*UnsignedTx{{PolyHeight: 89}}}, StatusUnsigned},
+		{&Vendor{PolyHeight: 100, FeeRate: 1, Unsigned: []*UnsignedTx{{PolyHeight: 90}}}, StatusOK},
+		{&Vendor{PolyHeight: 100, FeeRate: 1, OnlyNode: []string{"a:0"}}, StatusOK},
+	} {
+		if s := m.status(c.v); s != c.status {
+			t.Fatalf("%+v: status %s but expect %s", c.v, s, c.status)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	FormatText = "text"
+	FormatJson = "json"
+)
+
+const (
+	TyDeploy  = "deploy"
+	TyInvoke  = "invoke"
+	TyPolyGov = "poly_governance"
+)
+
+type Arg struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Tx is one transaction that would be sent. Nothing here is broadcasted.
+type Tx struct {
+	Chain    string   `json:"chain"`
+	Type     string   `json:"type"`
+	Contract string   `json:"contract"`
+	Method   string   `json:"method"`
+	Args     []Arg    `json:"args,omitempty"`
+	Signers  []string `json:"signers,omitempty"`
+	Note     string   `json:"note,omitempty"`
+	// send broadcasts the tx for Run and returns its hash, or "" if it is
+	// skipped for already done
+	send func() (string, error)
+}
+
+// Plan is the set of transactions a command is going to send.
+type Plan struct {
+	Name string `json:"name"`
+	Txs  []*Tx  `json:"txs"`
+}
+
+func NewPlan(name string) *Plan {
+	return &Plan{
+		Name: name,
+		Txs:  make([]*Tx, 0),
+	}
+}
+
+func (p *Plan) Add(tx *Tx) *Tx {
+	p.Txs = append(p.Txs, tx)
+	return tx
+}
+
+func (p *Plan) Deploy(chain, contract string, args ...Arg) *Tx {
+	return p.Add(&Tx{Chain: chain, Type: TyDeploy, Contract: contract, Method: "deploy", Args: args})
+}
+
+func (p *Plan) Invoke(chain, contract, method string, args ...Arg) *Tx {
+	return p.Add(&Tx{Chain: chain, Type: TyInvoke, Contract: contract, Method: method, Args: args})
+}
+
+func (p *Plan) PolyGov(contract, method string, signers []string, args ...Arg) *Tx {
+	return p.Add(&Tx{Chain: "poly", Type: TyPolyGov, Contract: contract, Method: method, Args: args, Signers: signers})
+}
+
+func (tx *Tx) WithNote(note string) *Tx {
+	tx.Note = note
+	return tx
+}
+
+func (tx *Tx) SignedBy(signers ...string) *Tx {
+	tx.Signers = append(tx.Signers, signers...)
+	return tx
+}
+
+// SentBy sets how Run broadcasts tx
+func (tx *Tx) SentBy(send func() (string, error)) *Tx {
+	tx.send = send
+	return tx
+}
+
+func NewArg(name string, val interface{}) Arg {
+	switch v := val.(type) {
+	case []byte:
+		return Arg{Name: name, Value: fmt.Sprintf("%x", v)}
+	default:
+		return Arg{Name: name, Value: fmt.Sprintf("%v", v)}
+	}
+}
+
+func (p *Plan) Text() string {
+	sb := &strings.Builder{}
+	sb.WriteString(fmt.Sprintf("=============================plan: %s=============================\n", p.Name))
+	if len(p.Txs) == 0 {
+		sb.WriteString("nothing to do\n")
+	}
+	for i, tx := range p.Txs {
+		sb.WriteString(fmt.Sprintf("No%d: [%s] %s %s.%s\n", i, tx.Chain, tx.Type, tx.Contract, tx.Method))
+		for _, a := range tx.Args {
+			sb.WriteString(fmt.Sprintf("\t%s: %s\n", a.Name, a.Value))
+		}
+		if len(tx.Signers) > 0 {
+			sb.WriteString(fmt.Sprintf("\tsigners: %s\n", strings.Join(tx.Signers, ", ")))
+		}
+		if tx.Note != "" {
+			sb.WriteString(fmt.Sprintf("\tnote: %s\n", tx.Note))
+		}
+	}
+	sb.WriteString("==================================================================")
+	return sb.String()
+}
+
+func (p *Plan) Print(w io.Writer, format string) error {
+	switch format {
+	case FormatJson:
+		raw, err := json.MarshalIndent(p, "", "\t")
+		if err != nil {
+			return fmt.Errorf("failed to marshal plan: %v", err)
+		}
+		_, err = fmt.Fprintln(w, string(raw))
+		return err
+	case FormatText, "":
+		_, err := fmt.Fprintln(w, p.Text())
+		return err
+	default:
+		return fmt.Errorf("unknown plan format %s, should be %s or %s", format, FormatText, FormatJson)
+	}
+}
+
+// Run broadcasts the txs of p in order and prints their hashes to w. It stops
+// at the first failed one.
+func (p *Plan) Run(w io.Writer) error {
+	for i, tx := range p.Txs {
+		if tx.send == nil {
+			return fmt.Errorf("No%d %s.%s of plan %s can not be sent", i, tx.Contract, tx.Method, p.Name)
+		}
+		hash, err := tx.send()
+		if err != nil {
+			return fmt.Errorf("No%d %s.%s failed: %v", i, tx.Contract, tx.Method, err)
+		}
+		desc := fmt.Sprintf("No%d: [%s] %s.%s", i, tx.Chain, tx.Contract, tx.Method)
+		if hash == "" {
+			desc += " skipped"
+		} else {
+			desc += fmt.Sprintf(" ( txhash: %s )", hash)
+		}
+		if tx.Note != "" {
+			desc += ", " + tx.Note
+		}
+		if _, err = fmt.Fprintln(w, desc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
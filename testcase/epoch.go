@@ -0,0 +1,226 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package testcase
+
+import (
+	"fmt"
+	"github.com/polynetwork/poly-go-sdk"
+	"github.com/polynetwork/poly-io-test/chains/btc"
+	"github.com/polynetwork/poly-io-test/config"
+	"github.com/polynetwork/poly-io-test/gov"
+	"github.com/polynetwork/poly-io-test/log"
+	"github.com/polynetwork/poly-io-test/monitor"
+	"github.com/polynetwork/poly-io-test/testframework"
+	"github.com/polynetwork/poly/common"
+	"github.com/polynetwork/poly/consensus/vbft/config"
+	"github.com/polynetwork/poly/native/service/governance/node_manager"
+	"strings"
+	"time"
+)
+
+// EpochRelayTimeout is how long to wait for the key header of a new poly
+// epoch to be relayed to every side chain
+var EpochRelayTimeout = 10 * time.Minute
+
+func getRCConsensusAccounts(poly *poly_go_sdk.PolySdk) ([]*poly_go_sdk.Account, error) {
+	if config.DefConfig.RCConsensusWallets == "" {
+		return nil, fmt.Errorf("RCConsensusWallets is required in config")
+	}
+	wArr := strings.Split(config.DefConfig.RCConsensusWallets, ",")
+	pArr := strings.Split(config.DefConfig.RCConsensusWalletPwds, ",")
+	if len(wArr) != len(pArr) {
+		return nil, fmt.Errorf("%d consensus wallets but %d passwords", len(wArr), len(pArr))
+	}
+	accArr := make([]*poly_go_sdk.Account, len(wArr))
+	for i, w := range wArr {
+		acc, err := btc.GetAccountByPassword(poly, w, []byte(pArr[i]))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode no%d wallet %s: %v", i, w, err)
+		}
+		accArr[i] = acc
+	}
+	return accArr, nil
+}
+
+func getRCCandidateAccount(poly *poly_go_sdk.PolySdk) (*poly_go_sdk.Account, error) {
+	if config.DefConfig.RCCandidateWallet == "" {
+		return nil, fmt.Errorf("RCCandidateWallet is required in config")
+	}
+	acc, err := btc.GetAccountByPassword(poly, config.DefConfig.RCCandidateWallet,
+		[]byte(config.DefConfig.RCCandidateWalletPwd))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode candidate wallet %s: %v", config.DefConfig.RCCandidateWallet, err)
+	}
+	return acc, nil
+}
+
+func getPeerStatus(poly *poly_go_sdk.PolySdk, pubkey string) (uint32, node_manager.Status, error) {
+	gv, m, err := gov.GetPeerPool(poly)
+	if err != nil {
+		return 0, 0, err
+	}
+	item, ok := m.PeerPoolMap[pubkey]
+	if !ok {
+		return gv.Height, 0, nil
+	}
+	return gv.Height, item.Status, nil
+}
+
+// rotatePolyConsensus moves the candidate into consensus, or out of it if it
+// is in, and commits dpos to switch the poly epoch. It returns the height of
+// the new epoch.
+func rotatePolyConsensus(poly *poly_go_sdk.PolySdk, accArr []*poly_go_sdk.Account,
+	cand *poly_go_sdk.Account) (uint32, error) {
+	pubkey := vconfig.PubkeyID(cand.PublicKey)
+	epoch, st, err := getPeerStatus(poly, pubkey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get peer pool: %v", err)
+	}
+	var txhash common.Uint256
+	if st == node_manager.ConsensusStatus {
+		if txhash, err = poly.Native.Nm.QuitNode(pubkey, cand); err != nil {
+			return 0, fmt.Errorf("failed to quit node %s: %v", cand.Address.ToBase58(), err)
+		}
+		WaitPolyTx(txhash, poly)
+		log.Infof("rotatePolyConsensus, node %s quits consensus ( txhash: %s )", cand.Address.ToBase58(),
+			txhash.ToHexString())
+	} else {
+		if st != node_manager.CandidateStatus {
+			if txhash, err = poly.Native.Nm.RegisterCandidate(pubkey, cand); err != nil {
+				return 0, fmt.Errorf("failed to register candidate %s: %v", cand.Address.ToBase58(), err)
+			}
+			WaitPolyTx(txhash, poly)
+			for i, a := range accArr {
+				if txhash, err = poly.Native.Nm.ApproveCandidate(pubkey, a); err != nil {
+					return 0, fmt.Errorf("no%d failed to approve candidate: %v", i, err)
+				}
+			}
+			WaitPolyTx(txhash, poly)
+		}
+		log.Infof("rotatePolyConsensus, node %s joins consensus", cand.Address.ToBase58())
+	}
+	if txhash, err = poly.Native.Nm.CommitDpos(accArr); err != nil {
+		return 0, fmt.Errorf("failed to commit dpos: %v", err)
+	}
+	WaitPolyTx(txhash, poly)
+
+	newEpoch, newSt, err := getPeerStatus(poly, pubkey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get peer pool after commit dpos: %v", err)
+	}
+	if newEpoch <= epoch {
+		return 0, fmt.Errorf("poly epoch not switched after commit dpos, still %d", newEpoch)
+	}
+	if (newSt == node_manager.ConsensusStatus) == (st == node_manager.ConsensusStatus) {
+		return 0, fmt.Errorf("status of node %s is %d after commit dpos, not rotated", cand.Address.ToBase58(),
+			newSt)
+	}
+	log.Infof("rotatePolyConsensus, poly switched to epoch %d ( txhash: %s )", newEpoch, txhash.ToHexString())
+	return newEpoch, nil
+}
+
+// waitEpochRelayed waits until ECCM on ethereum and header sync on ontology
+// and cosmos know the poly epoch at height
+func waitEpochRelayed(ctx *testframework.TestFrameworkContext, height uint32) error {
+	chains := map[string]bool{"ethereum": true, "ontology": ctx.OntInvoker != nil, "cosmos": ctx.CMInvoker != nil}
+	m := monitor.Dial(ctx.RcSdk, 0)
+	deadline := time.Now().Add(EpochRelayTimeout)
+	tick := time.NewTicker(5 * time.Second)
+	defer tick.Stop()
+	for range tick.C {
+		left := make([]string, 0)
+		for _, s := range m.Check() {
+			if !chains[s.Chain] {
+				continue
+			}
+			if s.Err != "" || s.ChainEpoch < uint64(height) {
+				left = append(left, fmt.Sprintf("%s( epoch: %d, err: %s )", s.Chain, s.ChainEpoch, s.Err))
+			}
+		}
+		if len(left) == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("poly epoch %d not relayed in %v: %s", height, EpochRelayTimeout,
+				strings.Join(left, ", "))
+		}
+	}
+	return nil
+}
+
+func sendAcrossEpoch(ctx *testframework.TestFrameworkContext, status *testframework.CaseStatus) error {
+	if err := SendOntCrossEth(ctx, status, GetRandAmount(config.DefConfig.OntValLimit, 1)); err != nil {
+		return fmt.Errorf("SendOntCrossEth error: %v", err)
+	}
+	if err := SendEthCrossOnt(ctx, status, GetRandAmount(config.DefConfig.EthValLimit, 1)); err != nil {
+		return fmt.Errorf("SendEthCrossOnt error: %v", err)
+	}
+	if ctx.CMInvoker != nil {
+		if err := SendOntCrossCosmos(ctx, status, GetRandAmount(config.DefConfig.OntValLimit, 1)); err != nil {
+			return fmt.Errorf("SendOntCrossCosmos error: %v", err)
+		}
+	}
+	return nil
+}
+
+// PolyEpochChange rotates the poly consensus while transfers are in flight,
+// and checks that both these and the ones sent in the new epoch complete
+func PolyEpochChange(ctx *testframework.TestFrameworkContext, status *testframework.CaseStatus) bool {
+	accArr, err := getRCConsensusAccounts(ctx.RcSdk)
+	if err != nil {
+		log.Errorf("PolyEpochChange, %v", err)
+		return false
+	}
+	cand, err := getRCCandidateAccount(ctx.RcSdk)
+	if err != nil {
+		log.Errorf("PolyEpochChange, %v", err)
+		return false
+	}
+	if err = sendAcrossEpoch(ctx, status); err != nil {
+		log.Errorf("PolyEpochChange, in flight: %v", err)
+		return false
+	}
+	log.Infof("PolyEpochChange, transfers in flight ( %d txs ), rotating poly consensus...", status.Len())
+
+	epoch, err := rotatePolyConsensus(ctx.RcSdk, accArr, cand)
+	if err != nil {
+		log.Errorf("PolyEpochChange, %v", err)
+		return false
+	}
+	if err = waitEpochRelayed(ctx, epoch); err != nil {
+		log.Errorf("PolyEpochChange, %v", err)
+		return false
+	}
+	log.Infof("PolyEpochChange, poly epoch %d is relayed to all chains, waiting for transfers in flight...", epoch)
+	if err = WaitUntilCleanIn(status, CleanWait); err != nil {
+		log.Errorf("PolyEpochChange, in old epoch: %v", err)
+		return false
+	}
+
+	if err = sendAcrossEpoch(ctx, status); err != nil {
+		log.Errorf("PolyEpochChange, in new epoch: %v", err)
+		return false
+	}
+	log.Infof("PolyEpochChange, transfers in new epoch %d sent, waiting for confirmation...", epoch)
+	if err = WaitUntilCleanIn(status, CleanWait); err != nil {
+		log.Errorf("PolyEpochChange, in new epoch: %v", err)
+		return false
+	}
+
+	status.SetItSuccess()
+	return true
+}
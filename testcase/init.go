@@ -37,6 +37,7 @@ func init() {
 	testframework.TFramework.RegTestCase("SendBtcoToBtcChain", SendBtcoToBtcChain)
 	testframework.TFramework.RegTestCase("SendBtcToOntChain", SendBtcToOntChain)
 	testframework.TFramework.RegTestCase("SendBtcToEthChain", SendBtcToEthChain)
+	testframework.TFramework.RegTestCase("SendBtcToOntWithRbf", SendBtcToOntWithRbf)
 	testframework.TFramework.RegTestCase("SendBtceToBtcChain", SendBtceToBtcChain)
 	testframework.TFramework.RegTestCase("SendBtcoToEthChain", SendBtcoToEthChain)
 	testframework.TFramework.RegTestCase("SendBtceToOntChain", SendBtceToOntChain)
@@ -71,4 +72,21 @@ func init() {
 	testframework.TFramework.RegTestCase("SendOngToCosmosAndBack", SendOngToCosmosAndBack)
 	testframework.TFramework.RegTestCase("SendOep4ToCosmosAndBack", SendOep4ToCosmosAndBack)
 	testframework.TFramework.RegTestCase("SendZeroOntToEth", SendZeroOntToEth)
+
+	// invalid transfers
+	testframework.TFramework.RegTestCase("TransferOverBalance", TransferOverBalance)
+	testframework.TFramework.RegTestCase("TransferToUnboundChain", TransferToUnboundChain)
+	testframework.TFramework.RegTestCase("TransferUnboundAsset", TransferUnboundAsset)
+	testframework.TFramework.RegTestCase("TransferBtcBelowMinimum", TransferBtcBelowMinimum)
+	testframework.TFramework.RegTestCase("TransferToMalformedAddress", TransferToMalformedAddress)
+	testframework.TFramework.RegTestCase("DoubleUnlockReplay", DoubleUnlockReplay)
+	testframework.TFramework.RegTestCase("EthProofAttack", EthProofAttack)
+	testframework.TFramework.RegTestCase("OntProofAttack", OntProofAttack)
+	testframework.TFramework.RegTestCase("ECCMPPause", ECCMPPause)
+	testframework.TFramework.RegTestCase("ECCMUpgrade", ECCMUpgrade)
+
+	// poly consensus
+	testframework.TFramework.RegTestCase("PolyEpochChange", PolyEpochChange)
+	testframework.TFramework.RegTestCase("SideChainLifecycle", SideChainLifecycle)
+	testframework.TFramework.RegTestCase("RelayerWhitelist", RelayerWhitelist)
 }
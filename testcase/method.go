@@ -353,6 +353,7 @@ func SendBtcoCrossBtc(ctx *testframework.TestFrameworkContext, status *testframe
 		return fmt.Errorf("SendBtcxCrossBtc, ctx.Ont.NeoVM.InvokeNeoVMContract error: %s", err)
 	}
 	status.AddTx(hex.EncodeToString(txHash[:]), &testframework.TxInfo{"BtcoToBtc", time.Now()})
+	status.ExpectBtcUnlock(hex.EncodeToString(txHash[:]), &testframework.BtcUnlock{To: string(to), Amount: int64(amount)})
 	log.Infof("SendBtcxCrossBtc, tx success, txHash is: %s", txHash.ToHexString())
 	return nil
 }
@@ -1418,6 +1419,10 @@ func SendBtceCrossBtc(ctx *testframework.TestFrameworkContext, status *testframe
 		return fmt.Errorf("SendERC20CrossOnt, send transaction error:%s", err.Error())
 	}
 	status.AddTx(signedtx.Hash().String()[2:], &testframework.TxInfo{"BtceToBtc", time.Now()})
+	status.ExpectBtcUnlock(signedtx.Hash().String()[2:], &testframework.BtcUnlock{
+		To:     ctx.BtcInvoker.Signer.Address,
+		Amount: int64(amount),
+	})
 	WaitTransactionConfirm(ctx.EthInvoker.ETHUtil.GetEthClient(), signedtx.Hash())
 	return nil
 }
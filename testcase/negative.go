@@ -0,0 +1,571 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package testcase
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	ontcommon "github.com/ontio/ontology/common"
+	nutils "github.com/ontio/ontology/smartcontract/service/native/utils"
+	"github.com/polynetwork/poly-io-test/chains/eth"
+	btcx_abi "github.com/polynetwork/poly-io-test/chains/eth/abi/btcx"
+	"github.com/polynetwork/poly-io-test/chains/eth/abi/eccm"
+	"github.com/polynetwork/poly-io-test/chains/eth/abi/erc20"
+	lock_proxy_abi "github.com/polynetwork/poly-io-test/chains/eth/abi/lockproxy"
+	"github.com/polynetwork/poly-io-test/config"
+	"github.com/polynetwork/poly-io-test/log"
+	"github.com/polynetwork/poly-io-test/testframework"
+	"math/big"
+	"strings"
+	"time"
+)
+
+var (
+	// UnboundChainId has no asset or lock proxy bound to it on any chain
+	UnboundChainId uint64 = 77777
+	// NonExecutionWait is how long transfers that must not execute on their
+	// destination are watched before they are judged as rejected
+	NonExecutionWait = 5 * time.Minute
+)
+
+// insufficientFundsErrs are what nodes say when the value exceeds the balance
+var insufficientFundsErrs = []string{"insufficient funds", "insufficient balance", "enough funds"}
+
+// expectRejected checks err is for one of the reasons
+func expectRejected(what string, err error, reasons ...string) error {
+	if err == nil {
+		return fmt.Errorf("%s is accepted", what)
+	}
+	for _, r := range reasons {
+		if strings.Contains(err.Error(), r) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s should be rejected for [%s], but got: %v", what, strings.Join(reasons, " | "), err)
+}
+
+// callEth executes data on the contract by eth_call, nothing is sent
+func callEth(ctx *testframework.TestFrameworkContext, contract ethcommon.Address, data []byte, value *big.Int) error {
+	_, err := ctx.EthInvoker.ETHUtil.GetEthClient().CallContract(context.Background(), ethereum.CallMsg{
+		From: ctx.EthInvoker.EthTestSigner.Address, To: &contract, Value: value, Data: data,
+	}, nil)
+	return err
+}
+
+// sendEthTx sends data to the contract from the test signer without waiting
+func sendEthTx(ctx *testframework.TestFrameworkContext, contract ethcommon.Address, data []byte,
+	value *big.Int) (*types.Transaction, error) {
+	client := ctx.EthInvoker.ETHUtil.GetEthClient()
+	gasPrice, err := client.SuggestGasPrice(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get suggest gas price: %v", err)
+	}
+	gasPrice = gasPrice.Mul(gasPrice, big.NewInt(5))
+	gasLimit, err := client.EstimateGas(context.Background(), ethereum.CallMsg{
+		From: ctx.EthInvoker.EthTestSigner.Address, To: &contract, GasPrice: gasPrice, Value: value, Data: data,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate gas: %v", err)
+	}
+	nonce := ctx.EthInvoker.NM.GetAddressNonce(ctx.EthInvoker.EthTestSigner.Address)
+	tx, err := types.SignTx(types.NewTransaction(nonce, contract, value, gasLimit, gasPrice, data),
+		types.HomesteadSigner{}, ctx.EthInvoker.EthTestSigner.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign tx: %v", err)
+	}
+	if err = client.SendTransaction(context.Background(), tx); err != nil {
+		return nil, fmt.Errorf("failed to send tx: %v", err)
+	}
+	return tx, nil
+}
+
+// sendEth sends data to the contract and tracks it in status as ty
+func sendEth(ctx *testframework.TestFrameworkContext, status *testframework.CaseStatus, contract ethcommon.Address,
+	data []byte, value *big.Int, ty string) error {
+	tx, err := sendEthTx(ctx, contract, data, value)
+	if err != nil {
+		return err
+	}
+	status.AddTx(tx.Hash().String()[2:], &testframework.TxInfo{Ty: ty, StartTime: time.Now()})
+	WaitTransactionConfirm(ctx.EthInvoker.ETHUtil.GetEthClient(), tx.Hash())
+	return nil
+}
+
+func packEthLock(asset ethcommon.Address, toChainId uint64, to []byte, amount *big.Int) ([]byte, error) {
+	contractabi, err := abi.JSON(strings.NewReader(lock_proxy_abi.LockProxyABI))
+	if err != nil {
+		return nil, err
+	}
+	return contractabi.Pack("lock", asset, toChainId, to, amount)
+}
+
+func packBtcxLock(toChainId uint64, to []byte, amount uint64) ([]byte, error) {
+	contractabi, err := abi.JSON(strings.NewReader(btcx_abi.BTCXABI))
+	if err != nil {
+		return nil, err
+	}
+	return contractabi.Pack("lock", toChainId, to, amount)
+}
+
+// callEthLock executes lock of the ethereum lock proxy by eth_call
+func callEthLock(ctx *testframework.TestFrameworkContext, asset ethcommon.Address, toChainId uint64, to []byte,
+	amount, value *big.Int) error {
+	data, err := packEthLock(asset, toChainId, to, amount)
+	if err != nil {
+		return err
+	}
+	return callEth(ctx, ethcommon.HexToAddress(config.DefConfig.EthLockProxy), data, value)
+}
+
+// approveEth approves the ethereum lock proxy to take amount of the erc20
+func approveEth(ctx *testframework.TestFrameworkContext, token ethcommon.Address, amount *big.Int) error {
+	contract, err := erc20.NewERC20(token, ctx.EthInvoker.ETHUtil.GetEthClient())
+	if err != nil {
+		return err
+	}
+	gasPrice, err := ctx.EthInvoker.ETHUtil.GetEthClient().SuggestGasPrice(context.Background())
+	if err != nil {
+		return err
+	}
+	nonce := ctx.EthInvoker.NM.GetAddressNonce(ctx.EthInvoker.EthTestSigner.Address)
+	tx, err := contract.Approve(MakeEthAuth(ctx.EthInvoker.EthTestSigner, nonce, gasPrice.Uint64(),
+		uint64(eth.DefaultGasLimit)), ethcommon.HexToAddress(config.DefConfig.EthLockProxy), amount)
+	if err != nil {
+		return fmt.Errorf("failed to approve: %v", err)
+	}
+	ctx.EthInvoker.ETHUtil.WaitTransactionConfirm(tx.Hash())
+	return nil
+}
+
+func erc20BalanceOf(ctx *testframework.TestFrameworkContext, token ethcommon.Address) (*big.Int, error) {
+	contract, err := erc20.NewERC20(token, ctx.EthInvoker.ETHUtil.GetEthClient())
+	if err != nil {
+		return nil, err
+	}
+	return contract.BalanceOf(&bind.CallOpts{}, ctx.EthInvoker.EthTestSigner.Address)
+}
+
+func ontLockParams(asset []byte, toChainId uint64, to []byte, amount uint64, from ontcommon.Address) []interface{} {
+	return []interface{}{"lock", []interface{}{asset, from[:], toChainId, to, amount}}
+}
+
+// preExecOntLock pre-executes lock of the ontology lock proxy signed by the
+// test account, nothing is sent
+func preExecOntLock(ctx *testframework.TestFrameworkContext, asset []byte, toChainId uint64, to []byte,
+	amount uint64) error {
+	proxy, err := ontcommon.AddressFromHexString(config.DefConfig.OntLockProxy)
+	if err != nil {
+		return err
+	}
+	sdk, acc := ctx.OntInvoker.OntSdk, ctx.OntInvoker.OntAcc
+	tx, err := sdk.NeoVM.NewNeoVMInvokeTransaction(config.DefConfig.GasPrice, config.DefConfig.GasLimit, proxy,
+		ontLockParams(asset, toChainId, to, amount, acc.Address))
+	if err != nil {
+		return err
+	}
+	if err = sdk.SignToTransaction(tx, acc); err != nil {
+		return err
+	}
+	res, err := sdk.PreExecTransaction(tx)
+	if err != nil {
+		return err
+	}
+	if res.State == 0 {
+		return fmt.Errorf("failed without message")
+	}
+	return nil
+}
+
+// checkOntLockRejected pre-executes a valid ont lock first, since ontology
+// gives no reason when failing, so that the rejection comes from the args
+func checkOntLockRejected(ctx *testframework.TestFrameworkContext, what string, toChainId uint64, to []byte,
+	amount uint64) error {
+	if err := preExecOntLock(ctx, nutils.OntContractAddress[:], config.ETH_CHAIN_ID,
+		ctx.EthInvoker.EthTestSigner.Address.Bytes(), 1); err != nil {
+		return fmt.Errorf("valid ont lock fails on ontology: %v", err)
+	}
+	if err := preExecOntLock(ctx, nutils.OntContractAddress[:], toChainId, to, amount); err == nil {
+		return fmt.Errorf("%s is accepted on ontology", what)
+	}
+	return nil
+}
+
+func transferOverBalance(ctx *testframework.TestFrameworkContext) error {
+	addr := ctx.EthInvoker.EthTestSigner.Address
+	bal, err := ctx.EthInvoker.ETHUtil.GetEthClient().BalanceAt(context.Background(), addr, nil)
+	if err != nil {
+		return fmt.Errorf("failed to get eth balance: %v", err)
+	}
+	amt := new(big.Int).Add(bal, big.NewInt(1))
+	err = callEthLock(ctx, ethcommon.Address{}, config.ONT_CHAIN_ID, ctx.OntInvoker.OntAcc.Address[:], amt, amt)
+	if err = expectRejected("eth over balance", err, insufficientFundsErrs...); err != nil {
+		return err
+	}
+	log.Infof("TransferOverBalance, lock %s wei over balance is rejected on ethereum", amt.String())
+
+	token := ethcommon.HexToAddress(config.DefConfig.EthErc20)
+	if bal, err = erc20BalanceOf(ctx, token); err != nil {
+		return fmt.Errorf("failed to get erc20 balance: %v", err)
+	}
+	amt = new(big.Int).Add(bal, big.NewInt(1))
+	if err = approveEth(ctx, token, amt); err != nil {
+		return err
+	}
+	err = callEthLock(ctx, token, config.ONT_CHAIN_ID, ctx.OntInvoker.OntAcc.Address[:], amt, big.NewInt(0))
+	if err = expectRejected("erc20 over balance", err, "exceeds balance"); err != nil {
+		return err
+	}
+	log.Infof("TransferOverBalance, lock %s erc20 over balance is rejected on ethereum", amt.String())
+
+	ontBal, err := ctx.OntInvoker.OntSdk.Native.Ont.BalanceOf(ctx.OntInvoker.OntAcc.Address)
+	if err != nil {
+		return fmt.Errorf("failed to get ont balance: %v", err)
+	}
+	if err = checkOntLockRejected(ctx, "ont over balance", config.ETH_CHAIN_ID,
+		ctx.EthInvoker.EthTestSigner.Address.Bytes(), ontBal+1); err != nil {
+		return err
+	}
+	log.Infof("TransferOverBalance, lock %d ont over balance is rejected on ontology", ontBal+1)
+	return nil
+}
+
+func transferToUnboundChain(ctx *testframework.TestFrameworkContext) error {
+	to := ctx.EthInvoker.EthTestSigner.Address.Bytes()
+	err := callEthLock(ctx, ethcommon.Address{}, UnboundChainId, to, big.NewInt(1), big.NewInt(1))
+	if err = expectRejected("eth to unbound chain", err, "empty illegal toAssetHash"); err != nil {
+		return err
+	}
+	log.Infof("TransferToUnboundChain, lock eth to chain %d is rejected on ethereum", UnboundChainId)
+
+	if err = checkOntLockRejected(ctx, "ont to unbound chain", UnboundChainId, to, 1); err != nil {
+		return err
+	}
+	log.Infof("TransferToUnboundChain, lock ont to chain %d is rejected on ontology", UnboundChainId)
+	return nil
+}
+
+func transferUnboundAsset(ctx *testframework.TestFrameworkContext) error {
+	token, _, err := ctx.EthInvoker.DeployERC20()
+	if err != nil {
+		return fmt.Errorf("failed to deploy erc20: %v", err)
+	}
+	if err = approveEth(ctx, token, big.NewInt(1)); err != nil {
+		return err
+	}
+	err = callEthLock(ctx, token, config.ONT_CHAIN_ID, ctx.OntInvoker.OntAcc.Address[:], big.NewInt(1),
+		big.NewInt(0))
+	if err = expectRejected("unbound erc20", err, "empty illegal toAssetHash"); err != nil {
+		return err
+	}
+	log.Infof("TransferUnboundAsset, lock new erc20 %s to ontology is rejected on ethereum", token.Hex())
+	return nil
+}
+
+func transferBtcBelowMinimum(ctx *testframework.TestFrameworkContext) error {
+	if ctx.BtcInvoker == nil {
+		return fmt.Errorf("btc is not configured")
+	}
+	btcx := ethcommon.HexToAddress(config.DefConfig.BtceContractAddress)
+	contract, err := btcx_abi.NewBTCX(btcx, ctx.EthInvoker.ETHUtil.GetEthClient())
+	if err != nil {
+		return err
+	}
+	min, err := contract.MinimumLimit(&bind.CallOpts{})
+	if err != nil {
+		return fmt.Errorf("failed to get minimum limit of btcx: %v", err)
+	}
+	if min != config.DefConfig.BtcMinOutputValFromContract {
+		return fmt.Errorf("minimum limit of btcx is %d, but BtcMinOutputValFromContract is %d", min,
+			config.DefConfig.BtcMinOutputValFromContract)
+	}
+	data, err := packBtcxLock(config.BTC_CHAIN_ID, []byte(ctx.BtcInvoker.Signer.Address), min-1)
+	if err != nil {
+		return err
+	}
+	err = callEth(ctx, btcx, data, big.NewInt(0))
+	if err = expectRejected("btcx below minimum", err, "btcx amount should be greater than"); err != nil {
+		return err
+	}
+	log.Infof("TransferBtcBelowMinimum, lock %d btcx to bitcoin is rejected on ethereum", min-1)
+	return nil
+}
+
+// sendMalformed sends the transfer if its source does not reject it. It
+// returns true if sent.
+func sendMalformed(what string, check func() error, send func() error) (bool, error) {
+	if err := check(); err != nil {
+		log.Infof("TransferToMalformedAddress, %s is rejected on source chain: %v", what, err)
+		return false, nil
+	}
+	if err := send(); err != nil {
+		return false, fmt.Errorf("failed to send %s: %v", what, err)
+	}
+	log.Infof("TransferToMalformedAddress, %s is accepted on source chain, watching destination", what)
+	return true, nil
+}
+
+func transferToMalformedAddress(ctx *testframework.TestFrameworkContext, status *testframework.CaseStatus) error {
+	ethAddr := ctx.EthInvoker.EthTestSigner.Address.Bytes()
+	ontAddr := ctx.OntInvoker.OntAcc.Address[:]
+	lockProxy := ethcommon.HexToAddress(config.DefConfig.EthLockProxy)
+	one := big.NewInt(1)
+	// valid transfers must pass on their sources, so that rejections are for
+	// the addresses
+	if err := preExecOntLock(ctx, nutils.OntContractAddress[:], config.ETH_CHAIN_ID, ethAddr, 1); err != nil {
+		return fmt.Errorf("valid ont lock fails on ontology: %v", err)
+	}
+	if err := callEthLock(ctx, ethcommon.Address{}, config.ONT_CHAIN_ID, ontAddr, one, one); err != nil {
+		return fmt.Errorf("valid eth lock fails on ethereum: %v", err)
+	}
+	sent := 0
+	send := func(what string, check func() error, doSend func() error) error {
+		ok, err := sendMalformed(what, check, doSend)
+		if ok {
+			sent++
+		}
+		return err
+	}
+
+	// ethereum address of 19 bytes from ontology
+	if err := send("ont to short eth address", func() error {
+		return preExecOntLock(ctx, nutils.OntContractAddress[:], config.ETH_CHAIN_ID, ethAddr[1:], 1)
+	}, func() error {
+		proxy, err := ontcommon.AddressFromHexString(config.DefConfig.OntLockProxy)
+		if err != nil {
+			return err
+		}
+		txHash, err := ctx.OntInvoker.OntSdk.NeoVM.InvokeNeoVMContract(config.DefConfig.GasPrice,
+			config.DefConfig.GasLimit, ctx.OntInvoker.OntAcc, ctx.OntInvoker.OntAcc, proxy,
+			ontLockParams(nutils.OntContractAddress[:], config.ETH_CHAIN_ID, ethAddr[1:], 1,
+				ctx.OntInvoker.OntAcc.Address))
+		if err != nil {
+			return err
+		}
+		status.AddTx(hex.EncodeToString(txHash[:]), &testframework.TxInfo{Ty: "OntToMalformedEth", StartTime: time.Now()})
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	// ontology address of 19 bytes from ethereum
+	data, err := packEthLock(ethcommon.Address{}, config.ONT_CHAIN_ID, ontAddr[1:], one)
+	if err != nil {
+		return err
+	}
+	if err = send("eth to short ont address", func() error {
+		return callEth(ctx, lockProxy, data, one)
+	}, func() error {
+		return sendEth(ctx, status, lockProxy, data, one, "EthToMalformedOnt")
+	}); err != nil {
+		return err
+	}
+
+	// cosmos address of 10 bytes from ethereum
+	if ctx.CMInvoker != nil {
+		cmData, err := packEthLock(ethcommon.Address{}, config.DefConfig.CMCrossChainId,
+			ctx.CMInvoker.Acc.Acc.Bytes()[:10], one)
+		if err != nil {
+			return err
+		}
+		if err = send("eth to short cosmos address", func() error {
+			return callEth(ctx, lockProxy, cmData, one)
+		}, func() error {
+			return sendEth(ctx, status, lockProxy, cmData, one, "EthToMalformedCosmos")
+		}); err != nil {
+			return err
+		}
+	}
+
+	// string not decodable as bitcoin address from btcx on ethereum
+	if ctx.BtcInvoker != nil {
+		btcx := ethcommon.HexToAddress(config.DefConfig.BtceContractAddress)
+		contract, err := btcx_abi.NewBTCX(btcx, ctx.EthInvoker.ETHUtil.GetEthClient())
+		if err != nil {
+			return err
+		}
+		bal, err := contract.BalanceOf(&bind.CallOpts{}, ctx.EthInvoker.EthTestSigner.Address)
+		if err != nil {
+			return fmt.Errorf("failed to get btcx balance: %v", err)
+		}
+		if bal.Uint64() < config.DefConfig.BtcMinOutputValFromContract {
+			return fmt.Errorf("btcx balance %d is less than %d, send some btc to ethereum first", bal.Uint64(),
+				config.DefConfig.BtcMinOutputValFromContract)
+		}
+		btcData, err := packBtcxLock(config.BTC_CHAIN_ID, []byte("not_a_bitcoin_address"),
+			config.DefConfig.BtcMinOutputValFromContract)
+		if err != nil {
+			return err
+		}
+		if err = send("btcx to malformed btc address", func() error {
+			return callEth(ctx, btcx, btcData, big.NewInt(0))
+		}, func() error {
+			return sendEth(ctx, status, btcx, btcData, big.NewInt(0), "BtceToMalformedBtc")
+		}); err != nil {
+			return err
+		}
+	}
+
+	if sent == 0 {
+		return nil
+	}
+	log.Infof("TransferToMalformedAddress, %d transfers sent, checking none executes in %v", sent, NonExecutionWait)
+	time.Sleep(NonExecutionWait)
+	// the monitors remove a tx once it executes on its destination
+	pending := status.GetMapCopy()
+	keys := make([]string, 0, len(pending))
+	for k := range pending {
+		keys = append(keys, k)
+	}
+	status.BatchDel(keys)
+	if len(keys) != sent {
+		return fmt.Errorf("%d of %d transfers to malformed addresses executed on destination", sent-len(keys), sent)
+	}
+	return nil
+}
+
+// findEthUnlock returns the tx on ethereum that executed the cross chain tx
+// from fromTx, searching blocks from start, and the poly tx carrying it
+func findEthUnlock(ctx *testframework.TestFrameworkContext, fromTx string,
+	start uint64) (*types.Transaction, []byte, error) {
+	contract, err := eccm.NewEthCrossChainManager(ethcommon.HexToAddress(config.DefConfig.Eccm),
+		ctx.EthInvoker.ETHUtil.GetEthClient())
+	if err != nil {
+		return nil, nil, err
+	}
+	events, err := contract.FilterVerifyHeaderAndExecuteTxEvent(&bind.FilterOpts{Start: start,
+		Context: context.Background()})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to filter eccm events: %v", err)
+	}
+	defer events.Close()
+	for events.Next() {
+		if hex.EncodeToString(events.Event.FromChainTxHash) != fromTx {
+			continue
+		}
+		tx, _, err := ctx.EthInvoker.ETHUtil.GetEthClient().TransactionByHash(context.Background(),
+			events.Event.Raw.TxHash)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get tx %s: %v", events.Event.Raw.TxHash.Hex(), err)
+		}
+		return tx, events.Event.CrossChainTxHash, nil
+	}
+	return nil, nil, fmt.Errorf("no unlock of %s found on ethereum since block %d", fromTx, start)
+}
+
+// sendOntToEthAndFindUnlock sends ont to ethereum, waits for it and returns
+// the tx of the relayer which executed it, and the poly tx carrying it
+func sendOntToEthAndFindUnlock(ctx *testframework.TestFrameworkContext,
+	status *testframework.CaseStatus) (*types.Transaction, []byte, error) {
+	start, err := ctx.EthInvoker.ETHUtil.GetNodeHeight()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get ethereum height: %v", err)
+	}
+	if err = SendOntCrossEth(ctx, status, GetRandAmount(config.DefConfig.OntValLimit, 1)); err != nil {
+		return nil, nil, err
+	}
+	var fromTx string
+	for k := range status.GetMapCopy() {
+		fromTx = k
+	}
+	if err = WaitUntilCleanIn(status, CleanWait); err != nil {
+		return nil, nil, err
+	}
+	return findEthUnlock(ctx, fromTx, start)
+}
+
+func doubleUnlockReplay(ctx *testframework.TestFrameworkContext, status *testframework.CaseStatus) error {
+	tx, _, err := sendOntToEthAndFindUnlock(ctx, status)
+	if err != nil {
+		return err
+	}
+	log.Infof("DoubleUnlockReplay, transfer executed on ethereum ( txhash: %s ), replaying it", tx.Hash().Hex())
+	err = callEth(ctx, *tx.To(), tx.Data(), big.NewInt(0))
+	return expectRejected("replayed unlock", err, "the transaction has been executed!")
+}
+
+// TransferOverBalance checks ETH, ERC20 and ONT locks over the balance are
+// rejected on the source chain
+func TransferOverBalance(ctx *testframework.TestFrameworkContext, status *testframework.CaseStatus) bool {
+	if err := transferOverBalance(ctx); err != nil {
+		log.Errorf("TransferOverBalance, %v", err)
+		return false
+	}
+	status.SetItSuccess()
+	return true
+}
+
+// TransferToUnboundChain checks locks to a chain id nothing is bound to are
+// rejected on the source chain
+func TransferToUnboundChain(ctx *testframework.TestFrameworkContext, status *testframework.CaseStatus) bool {
+	if err := transferToUnboundChain(ctx); err != nil {
+		log.Errorf("TransferToUnboundChain, %v", err)
+		return false
+	}
+	status.SetItSuccess()
+	return true
+}
+
+// TransferUnboundAsset deploys a new ERC20 and checks locking it to a bound
+// chain is rejected on ethereum
+func TransferUnboundAsset(ctx *testframework.TestFrameworkContext, status *testframework.CaseStatus) bool {
+	if err := transferUnboundAsset(ctx); err != nil {
+		log.Errorf("TransferUnboundAsset, %v", err)
+		return false
+	}
+	status.SetItSuccess()
+	return true
+}
+
+// TransferBtcBelowMinimum checks btcx locks to bitcoin below
+// BtcMinOutputValFromContract are rejected on ethereum
+func TransferBtcBelowMinimum(ctx *testframework.TestFrameworkContext, status *testframework.CaseStatus) bool {
+	if err := transferBtcBelowMinimum(ctx); err != nil {
+		log.Errorf("TransferBtcBelowMinimum, %v", err)
+		return false
+	}
+	status.SetItSuccess()
+	return true
+}
+
+// TransferToMalformedAddress sends to malformed addresses of every chain
+// format, and checks each is rejected on its source or never executes on
+// its destination
+func TransferToMalformedAddress(ctx *testframework.TestFrameworkContext, status *testframework.CaseStatus) bool {
+	if err := transferToMalformedAddress(ctx, status); err != nil {
+		log.Errorf("TransferToMalformedAddress, %v", err)
+		return false
+	}
+	status.SetItSuccess()
+	return true
+}
+
+// DoubleUnlockReplay replays the unlock of a finished ont transfer on
+// ethereum and checks ECCM rejects it
+func DoubleUnlockReplay(ctx *testframework.TestFrameworkContext, status *testframework.CaseStatus) bool {
+	if err := doubleUnlockReplay(ctx, status); err != nil {
+		log.Errorf("DoubleUnlockReplay, %v", err)
+		return false
+	}
+	status.SetItSuccess()
+	return true
+}
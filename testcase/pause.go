@@ -0,0 +1,300 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package testcase
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/polynetwork/poly-io-test/chains/btc"
+	"github.com/polynetwork/poly-io-test/chains/poly"
+	"github.com/polynetwork/poly-io-test/config"
+	"github.com/polynetwork/poly-io-test/log"
+	"github.com/polynetwork/poly-io-test/testframework"
+	"math/big"
+	"time"
+)
+
+// PausedHoldTime is how long transfers in flight are checked not to execute
+// on ethereum while ECCMP is paused
+var PausedHoldTime = 2 * time.Minute
+
+// checkPaused checks ECCMP and the ECCM behind it are both paused or not
+func checkPaused(ctx *testframework.TestFrameworkContext, eccm string, paused bool) error {
+	p, err := ctx.EthInvoker.IsECCMPPaused(config.DefConfig.Eccmp)
+	if err != nil {
+		return err
+	}
+	if p != paused {
+		return fmt.Errorf("ECCMP paused: %v, but %v expected", p, paused)
+	}
+	if p, err = ctx.EthInvoker.IsECCMPaused(eccm); err != nil {
+		return err
+	}
+	if p != paused {
+		return fmt.Errorf("ECCM %s paused: %v, but %v expected", eccm, p, paused)
+	}
+	return nil
+}
+
+func pauseECCMP(ctx *testframework.TestFrameworkContext, eccm string) error {
+	tx, err := ctx.EthInvoker.PauseECCMP(config.DefConfig.Eccmp)
+	if err != nil {
+		return err
+	}
+	if err = checkPaused(ctx, eccm, true); err != nil {
+		return err
+	}
+	log.Infof("ECCMP is paused ( txhash: %s )", tx.Hash().Hex())
+	return nil
+}
+
+// unpauseECCMP unpauses ECCMP and checks eccm is the one behind it
+func unpauseECCMP(ctx *testframework.TestFrameworkContext, eccm string) error {
+	tx, err := ctx.EthInvoker.UnpauseECCMP(config.DefConfig.Eccmp)
+	if err != nil {
+		return err
+	}
+	if err = checkPaused(ctx, eccm, false); err != nil {
+		return err
+	}
+	cur, err := ctx.EthInvoker.GetECCMOfECCMP(config.DefConfig.Eccmp)
+	if err != nil {
+		return err
+	}
+	if cur != ethcommon.HexToAddress(eccm) {
+		return fmt.Errorf("ECCM behind ECCMP is %s, but %s expected", cur.Hex(), eccm)
+	}
+	log.Infof("ECCMP is unpaused with ECCM %s ( txhash: %s )", eccm, tx.Hash().Hex())
+	return nil
+}
+
+// upgradeECCM points the paused ECCMP to eccm and checks ECCD is handed to it
+func upgradeECCM(ctx *testframework.TestFrameworkContext, eccm string) error {
+	tx, err := ctx.EthInvoker.UpgradeECCM(config.DefConfig.Eccmp, eccm)
+	if err != nil {
+		return err
+	}
+	owner, err := ctx.EthInvoker.GetOwnerOfECCD(config.DefConfig.Eccd)
+	if err != nil {
+		return err
+	}
+	if owner != ethcommon.HexToAddress(eccm) {
+		return fmt.Errorf("ECCD is owned by %s after upgraded, but %s expected", owner.Hex(), eccm)
+	}
+	log.Infof("ECCM behind ECCMP is upgraded to %s ( txhash: %s )", eccm, tx.Hash().Hex())
+	return nil
+}
+
+// checkRefused checks both a lock through the lock proxy and the unlock tx
+// are refused for paused
+func checkRefused(ctx *testframework.TestFrameworkContext, unlock *types.Transaction) error {
+	one := big.NewInt(1)
+	err := callEthLock(ctx, ethcommon.Address{}, config.ONT_CHAIN_ID, ctx.OntInvoker.OntAcc.Address[:], one, one)
+	if err = expectRejected("lock while paused", err, "paused"); err != nil {
+		return err
+	}
+	err = callEth(ctx, *unlock.To(), unlock.Data(), big.NewInt(0))
+	return expectRejected("unlock while paused", err, "paused")
+}
+
+// checkLockThrough locks 1 wei of ether through the lock proxy and checks the
+// cross chain event comes from eccm. Relayers do not watch eccm if it is not
+// the configured one, so the lock is relayed to poly here and tracked in status
+// until it executes on ontology.
+func checkLockThrough(ctx *testframework.TestFrameworkContext, status *testframework.CaseStatus, eccm string) error {
+	one := big.NewInt(1)
+	data, err := packEthLock(ethcommon.Address{}, config.ONT_CHAIN_ID, ctx.OntInvoker.OntAcc.Address[:], one)
+	if err != nil {
+		return err
+	}
+	tx, err := sendEthTx(ctx, ethcommon.HexToAddress(config.DefConfig.EthLockProxy), data, one)
+	if err != nil {
+		return fmt.Errorf("failed to lock through ECCM %s: %v", eccm, err)
+	}
+	client := ctx.EthInvoker.ETHUtil.GetEthClient()
+	WaitTransactionConfirm(client, tx.Hash())
+	receipt, err := client.TransactionReceipt(context.Background(), tx.Hash())
+	if err != nil {
+		return fmt.Errorf("failed to get receipt of %s: %v", tx.Hash().String(), err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return fmt.Errorf("lock %s through ECCM %s failed", tx.Hash().String(), eccm)
+	}
+	acc, err := btc.GetAccountByPassword(ctx.RcSdk, config.DefConfig.RCWallet, []byte(config.DefConfig.RCWalletPwd))
+	if err != nil {
+		return fmt.Errorf("failed to get poly account: %v", err)
+	}
+	lock, err := poly.ImportEth(ctx.EthInvoker, ctx.RcSdk, acc, eccm, tx.Hash(), CleanWait)
+	if err != nil {
+		return fmt.Errorf("failed to relay lock %s through ECCM %s: %v", tx.Hash().String(), eccm, err)
+	}
+	// keyed by the cross chain tx id like the locks through the configured ECCM
+	status.AddTx(hex.EncodeToString(ethcommon.LeftPadBytes(lock.Txid, 32)),
+		&testframework.TxInfo{Ty: "EthToOnt", StartTime: time.Now()})
+	return nil
+}
+
+// holdInFlight checks transfers in flight do not execute while paused
+func holdInFlight(status *testframework.CaseStatus) error {
+	n := status.Len()
+	if n == 0 {
+		log.Warnf("transfer in flight executed before paused, nothing to hold")
+		return nil
+	}
+	log.Infof("checking %d transfers in flight are held for %v", n, PausedHoldTime)
+	time.Sleep(PausedHoldTime)
+	if left := status.Len(); left != n {
+		return fmt.Errorf("%d transfers executed on ethereum while paused", n-left)
+	}
+	return nil
+}
+
+// sendAfterResumed sends transfers both ways and waits for them
+func sendAfterResumed(ctx *testframework.TestFrameworkContext, status *testframework.CaseStatus) error {
+	if err := SendOntCrossEth(ctx, status, GetRandAmount(config.DefConfig.OntValLimit, 1)); err != nil {
+		return fmt.Errorf("SendOntCrossEth error: %v", err)
+	}
+	if err := SendEthCrossOnt(ctx, status, GetRandAmount(config.DefConfig.EthValLimit, 1)); err != nil {
+		return fmt.Errorf("SendEthCrossOnt error: %v", err)
+	}
+	return WaitUntilCleanIn(status, CleanWait)
+}
+
+func eccmpPause(ctx *testframework.TestFrameworkContext, status *testframework.CaseStatus) (err error) {
+	eccm := config.DefConfig.Eccm
+	unlock, _, err := sendOntToEthAndFindUnlock(ctx, status)
+	if err != nil {
+		return err
+	}
+	if err = SendOntCrossEth(ctx, status, GetRandAmount(config.DefConfig.OntValLimit, 1)); err != nil {
+		return fmt.Errorf("SendOntCrossEth error: %v", err)
+	}
+	if err = pauseECCMP(ctx, eccm); err != nil {
+		return err
+	}
+	paused := true
+	defer func() {
+		if paused {
+			if e := unpauseECCMP(ctx, eccm); e != nil {
+				log.Errorf("ECCMPPause, failed to unpause after failure: %v", e)
+			}
+		}
+	}()
+	if err = checkRefused(ctx, unlock); err != nil {
+		return err
+	}
+	log.Infof("ECCMPPause, lock and unlock are refused while paused")
+	if err = holdInFlight(status); err != nil {
+		return err
+	}
+
+	paused = false
+	if err = unpauseECCMP(ctx, eccm); err != nil {
+		return err
+	}
+	log.Infof("ECCMPPause, waiting for transfers in flight...")
+	if err = WaitUntilCleanIn(status, CleanWait); err != nil {
+		return err
+	}
+	return sendAfterResumed(ctx, status)
+}
+
+func eccmUpgrade(ctx *testframework.TestFrameworkContext, status *testframework.CaseStatus) (err error) {
+	old := config.DefConfig.Eccm
+	if err = SendOntCrossEth(ctx, status, GetRandAmount(config.DefConfig.OntValLimit, 1)); err != nil {
+		return fmt.Errorf("SendOntCrossEth error: %v", err)
+	}
+	if err = pauseECCMP(ctx, old); err != nil {
+		return err
+	}
+	cur, paused := old, true
+	defer func() {
+		if paused {
+			if e := unpauseECCMP(ctx, cur); e != nil {
+				log.Errorf("ECCMUpgrade, failed to unpause after failure: %v", e)
+			}
+		}
+	}()
+
+	addr, _, err := ctx.EthInvoker.DeployECCMContract(config.DefConfig.Eccd)
+	if err != nil {
+		return err
+	}
+	eccm := addr.Hex()
+	if _, err = ctx.EthInvoker.PauseECCM(eccm); err != nil {
+		return err
+	}
+	if _, err = ctx.EthInvoker.TransferOwnershipForECCM(eccm, config.DefConfig.Eccmp); err != nil {
+		return err
+	}
+	if err = upgradeECCM(ctx, eccm); err != nil {
+		return err
+	}
+	cur = eccm
+	if err = unpauseECCMP(ctx, eccm); err != nil {
+		return err
+	}
+	paused = false
+	if err = checkLockThrough(ctx, status, eccm); err != nil {
+		return err
+	}
+	log.Infof("ECCMUpgrade, ECCMP works with new ECCM %s, switching back to %s", eccm, old)
+
+	// relayers and the monitor know the old one only
+	if err = pauseECCMP(ctx, eccm); err != nil {
+		return err
+	}
+	paused = true
+	if err = upgradeECCM(ctx, old); err != nil {
+		return err
+	}
+	cur = old
+	paused = false
+	if err = unpauseECCMP(ctx, old); err != nil {
+		return err
+	}
+	log.Infof("ECCMUpgrade, waiting for transfers in flight...")
+	if err = WaitUntilCleanIn(status, CleanWait); err != nil {
+		return err
+	}
+	return sendAfterResumed(ctx, status)
+}
+
+// ECCMPPause pauses ECCMP with a transfer in flight, checks lock and unlock
+// are refused and the transfer is held, then unpauses and checks it completes
+func ECCMPPause(ctx *testframework.TestFrameworkContext, status *testframework.CaseStatus) bool {
+	if err := eccmpPause(ctx, status); err != nil {
+		log.Errorf("ECCMPPause, %v", err)
+		return false
+	}
+	status.SetItSuccess()
+	return true
+}
+
+// ECCMUpgrade upgrades the ECCM behind ECCMP to a new one and back with a
+// transfer in flight, and checks it completes
+func ECCMUpgrade(ctx *testframework.TestFrameworkContext, status *testframework.CaseStatus) bool {
+	if err := eccmUpgrade(ctx, status); err != nil {
+		log.Errorf("ECCMUpgrade, %v", err)
+		return false
+	}
+	status.SetItSuccess()
+	return true
+}
@@ -0,0 +1,405 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package testcase
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ontio/ontology/core/payload"
+	"github.com/ontio/ontology/smartcontract/service/native/cross_chain/cross_chain_manager"
+	nutils "github.com/ontio/ontology/smartcontract/service/native/utils"
+	"github.com/polynetwork/poly-io-test/chains/eth/abi/eccd"
+	"github.com/polynetwork/poly-io-test/chains/eth/abi/eccm"
+	"github.com/polynetwork/poly-io-test/config"
+	"github.com/polynetwork/poly-io-test/log"
+	"github.com/polynetwork/poly-io-test/testframework"
+	pcommon "github.com/polynetwork/poly/common"
+	ccmcom "github.com/polynetwork/poly/native/service/cross_chain_manager/common"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// ontCCMAddress is the cross chain manager native contract on ontology
+const ontCCMAddress = "0900000000000000000000000000000000000000"
+
+func reverseBytes(b []byte) []byte {
+	res := make([]byte, len(b))
+	for i, v := range b {
+		res[len(b)-1-i] = v
+	}
+	return res
+}
+
+// leadingHex decodes the hex chars at the start of b
+func leadingHex(b []byte) []byte {
+	n := 0
+	for n < len(b) && strings.IndexByte("0123456789abcdefABCDEF", b[n]) >= 0 {
+		n++
+	}
+	raw, _ := hex.DecodeString(string(b[:n&^1]))
+	return raw
+}
+
+// tamperArgs returns a copy of the lock proxy args with the target address
+// or the amount changed
+func tamperArgs(args []byte, target bool) ([]byte, error) {
+	src := pcommon.NewZeroCopySource(args)
+	if _, eof := src.NextVarBytes(); eof {
+		return nil, fmt.Errorf("no asset in args")
+	}
+	to, eof := src.NextVarBytes()
+	if eof || len(to) == 0 {
+		return nil, fmt.Errorf("no target address in args")
+	}
+	pos := int(src.Pos())
+	res := append([]byte{}, args...)
+	if target {
+		res[pos-1] ^= 0xff
+		return res, nil
+	}
+	if pos >= len(res) {
+		return nil, fmt.Errorf("no amount in args")
+	}
+	// the lowest byte of the little endian amount
+	res[pos]++
+	return res, nil
+}
+
+// tamperMerkleValue finds the merkle value of the poly tx in raw, plain or
+// hex encoded, and returns a copy of raw with its args tampered
+func tamperMerkleValue(raw, polyTx []byte, target bool) ([]byte, error) {
+	for _, h := range [][]byte{polyTx, reverseBytes(polyTx)} {
+		sink := pcommon.NewZeroCopySink(nil)
+		sink.WriteVarBytes(h)
+		for _, encoded := range []bool{false, true} {
+			prefix := sink.Bytes()
+			if encoded {
+				prefix = []byte(hex.EncodeToString(prefix))
+			}
+			idx := bytes.Index(raw, prefix)
+			if idx < 0 {
+				continue
+			}
+			body := raw[idx:]
+			if encoded {
+				body = leadingHex(body)
+			}
+			src := pcommon.NewZeroCopySource(body)
+			value := new(ccmcom.ToMerkleValue)
+			if err := value.Deserialization(src); err != nil {
+				continue
+			}
+			args, err := tamperArgs(value.MakeTxParam.Args, target)
+			if err != nil {
+				return nil, err
+			}
+			value.MakeTxParam.Args = args
+			out := pcommon.NewZeroCopySink(nil)
+			value.Serialization(out)
+			tampered := out.Bytes()
+			if uint64(len(tampered)) != src.Pos() {
+				return nil, fmt.Errorf("length of merkle value changed after tampered")
+			}
+			if encoded {
+				tampered = []byte(hex.EncodeToString(tampered))
+			}
+			res := append([]byte{}, raw...)
+			copy(res[idx:], tampered)
+			return res, nil
+		}
+	}
+	return nil, fmt.Errorf("merkle value of poly tx %x not found", polyTx)
+}
+
+func tamperedWhat(target bool) string {
+	if target {
+		return "target"
+	}
+	return "amount"
+}
+
+// ethKeepersNum returns the number of poly keepers known by ECCM
+func ethKeepersNum(ctx *testframework.TestFrameworkContext) (int, error) {
+	data, err := eccd.NewEthCrossChainData(ethcommon.HexToAddress(config.DefConfig.Eccd),
+		ctx.EthInvoker.ETHUtil.GetEthClient())
+	if err != nil {
+		return 0, err
+	}
+	raw, err := data.GetCurEpochConPubKeyBytes(&bind.CallOpts{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get keepers from eccd: %v", err)
+	}
+	if len(raw) < 8 {
+		return 0, fmt.Errorf("no keepers in eccd")
+	}
+	return int(binary.LittleEndian.Uint64(raw[:8])), nil
+}
+
+func ethProofAttack(ctx *testframework.TestFrameworkContext, status *testframework.CaseStatus) error {
+	tx, polyTx, err := sendOntToEthAndFindUnlock(ctx, status)
+	if err != nil {
+		return err
+	}
+	log.Infof("EthProofAttack, transfer executed on ethereum ( txhash: %s )", tx.Hash().Hex())
+	contractabi, err := abi.JSON(strings.NewReader(eccm.EthCrossChainManagerABI))
+	if err != nil {
+		return err
+	}
+	if len(tx.Data()) < 4 {
+		return fmt.Errorf("tx %s is not a call", tx.Hash().Hex())
+	}
+	args, err := contractabi.Methods["verifyHeaderAndExecuteTx"].Inputs.UnpackValues(tx.Data()[4:])
+	if err != nil || len(args) != 5 {
+		return fmt.Errorf("tx %s is not verifyHeaderAndExecuteTx: %v", tx.Hash().Hex(), err)
+	}
+	submit := func(what string, args []interface{}, reasons ...string) error {
+		data, err := contractabi.Pack("verifyHeaderAndExecuteTx", args...)
+		if err != nil {
+			return err
+		}
+		if err = expectRejected(what, callEth(ctx, *tx.To(), data, big.NewInt(0)), reasons...); err != nil {
+			return err
+		}
+		log.Infof("EthProofAttack, %s is rejected by ECCM", what)
+		return nil
+	}
+
+	if err = submit("replayed proof", args, "the transaction has been executed!"); err != nil {
+		return err
+	}
+	for _, target := range []bool{false, true} {
+		proof, err := tamperMerkleValue(args[0].([]byte), polyTx, target)
+		if err != nil {
+			return err
+		}
+		if err = submit(fmt.Sprintf("proof with %s tampered", tamperedWhat(target)),
+			append([]interface{}{proof}, args[1:]...), "merkleProve, expect root is not equal actual root"); err != nil {
+			return err
+		}
+	}
+
+	n, err := ethKeepersNum(ctx)
+	if err != nil {
+		return err
+	}
+	sigs, keep := args[4].([]byte), n-(n-1)/3-1
+	if len(sigs) < keep*65 {
+		return fmt.Errorf("only %d bytes of signatures in tx %s", len(sigs), tx.Hash().Hex())
+	}
+	return submit(fmt.Sprintf("header signed by %d of %d keepers", keep, n),
+		append(append([]interface{}{}, args[:4]...), sigs[:keep*65]), "signature failed")
+}
+
+// findOntUnlock returns the invoke code of the tx on ontology that executed
+// the cross chain tx from fromTx, searching blocks from start, and the poly tx
+// carrying it
+func findOntUnlock(ctx *testframework.TestFrameworkContext, fromTx string, start uint32) ([]byte, []byte, error) {
+	sdk := ctx.OntInvoker.OntSdk
+	end, err := sdk.GetCurrentBlockHeight()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get ontology height: %v", err)
+	}
+	for h := start; h <= end; h++ {
+		events, err := sdk.GetSmartContractEventByBlock(h)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get events of ontology block %d: %v", h, err)
+		}
+		for _, e := range events {
+			for _, n := range e.Notify {
+				states, ok := n.States.([]interface{})
+				if n.ContractAddress != ontCCMAddress || !ok || len(states) < 5 {
+					continue
+				}
+				if name, _ := states[0].(string); name != "verifyToOntProof" {
+					continue
+				}
+				if raw, _ := states[2].(string); raw != fromTx {
+					continue
+				}
+				polyTx, _ := states[1].(string)
+				rawPolyTx, err := hex.DecodeString(polyTx)
+				if err != nil {
+					return nil, nil, fmt.Errorf("failed to decode poly tx %s: %v", polyTx, err)
+				}
+				tx, err := sdk.GetTransaction(e.TxHash)
+				if err != nil {
+					return nil, nil, fmt.Errorf("failed to get tx %s: %v", e.TxHash, err)
+				}
+				code, ok := tx.Payload.(*payload.InvokeCode)
+				if !ok {
+					return nil, nil, fmt.Errorf("tx %s is not an invoke", e.TxHash)
+				}
+				return code.Code, rawPolyTx, nil
+			}
+		}
+	}
+	return nil, nil, fmt.Errorf("no unlock of %s found on ontology since block %d", fromTx, start)
+}
+
+// sendEthToOntAndFindUnlock sends eth to ontology, waits for it and returns
+// the invoke code of the relayer which executed it, and the poly tx carrying it
+func sendEthToOntAndFindUnlock(ctx *testframework.TestFrameworkContext,
+	status *testframework.CaseStatus) ([]byte, []byte, error) {
+	start, err := ctx.OntInvoker.OntSdk.GetCurrentBlockHeight()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get ontology height: %v", err)
+	}
+	if err = SendEthCrossOnt(ctx, status, GetRandAmount(config.DefConfig.EthValLimit, 1)); err != nil {
+		return nil, nil, err
+	}
+	// the monitor replaces the ethereum tx with its cross chain tx id
+	var fromTx string
+	deadline := time.Now().Add(CleanWait)
+	for fromTx == "" {
+		if time.Now().After(deadline) {
+			return nil, nil, fmt.Errorf("cross chain tx id not caught in %v", CleanWait)
+		}
+		m := status.GetMapCopy()
+		if len(m) == 0 {
+			return nil, nil, fmt.Errorf("transfer finished before its cross chain tx id is caught")
+		}
+		for k, v := range m {
+			if v.Ty != "EthToOnt" {
+				fromTx = k
+			}
+		}
+		time.Sleep(time.Second)
+	}
+	if err = WaitUntilCleanIn(status, CleanWait); err != nil {
+		return nil, nil, err
+	}
+	return findOntUnlock(ctx, fromTx, start)
+}
+
+// preExecOntCode pre-executes the invoke code signed by the test account
+func preExecOntCode(ctx *testframework.TestFrameworkContext, code []byte) error {
+	sdk := ctx.OntInvoker.OntSdk
+	tx := sdk.NewInvokeTransaction(config.DefConfig.GasPrice, config.DefConfig.GasLimit, code)
+	if err := sdk.SignToTransaction(tx, ctx.OntInvoker.OntAcc); err != nil {
+		return err
+	}
+	res, err := sdk.PreExecTransaction(tx)
+	if err != nil {
+		return err
+	}
+	if res.State == 0 {
+		return fmt.Errorf("failed without message")
+	}
+	return nil
+}
+
+// preExecOntHeaderProof pre-executes processCrossChainTx on ontology with the
+// poly header raw and an empty proof at a height ontology never syncs, so that
+// the header is always verified before the proof
+func preExecOntHeaderProof(ctx *testframework.TestFrameworkContext, polyChainId uint64, raw []byte) error {
+	sdk := ctx.OntInvoker.OntSdk
+	tx, err := sdk.Native.NewNativeInvokeTransaction(config.DefConfig.GasPrice, config.DefConfig.GasLimit, 0,
+		nutils.CrossChainContractAddress, cross_chain_manager.PROCESS_CROSS_CHAIN_TX, []interface{}{
+			&cross_chain_manager.ProcessCrossChainTxParam{Address: ctx.OntInvoker.OntAcc.Address,
+				FromChainID: polyChainId, Height: math.MaxUint32, Header: raw}})
+	if err != nil {
+		return err
+	}
+	return preExecOntCode(ctx, tx.Payload.(*payload.InvokeCode).Code)
+}
+
+func ontProofAttack(ctx *testframework.TestFrameworkContext, status *testframework.CaseStatus) error {
+	code, polyTx, err := sendEthToOntAndFindUnlock(ctx, status)
+	if err != nil {
+		return err
+	}
+	log.Infof("OntProofAttack, transfer executed on ontology ( poly txhash: %x )", polyTx)
+	submit := func(what string, code []byte, reason string) error {
+		if err := expectRejected(what, preExecOntCode(ctx, code), reason); err != nil {
+			return err
+		}
+		log.Infof("OntProofAttack, %s is rejected by verifyToOntProof", what)
+		return nil
+	}
+
+	if err = submit("replayed proof", code, "checkDoneTx"); err != nil {
+		return err
+	}
+	for _, target := range []bool{false, true} {
+		tampered, err := tamperMerkleValue(code, polyTx, target)
+		if err != nil {
+			return err
+		}
+		if err = submit(fmt.Sprintf("proof with %s tampered", tamperedWhat(target)), tampered,
+			"MerkleProve"); err != nil {
+			return err
+		}
+	}
+
+	// ontology only verifies the header of a cross chain tx when it has no
+	// header at the height of the tx, so claim a height never synced
+	height, err := ctx.RcSdk.GetCurrentBlockHeight()
+	if err != nil {
+		return fmt.Errorf("failed to get poly height: %v", err)
+	}
+	hdr, err := ctx.RcSdk.GetHeaderByHeight(height)
+	if err != nil {
+		return fmt.Errorf("failed to get poly header %d: %v", height, err)
+	}
+	// a valid header passes and the empty proof fails after it
+	err = expectRejected(fmt.Sprintf("empty proof with poly header %d", height),
+		preExecOntHeaderProof(ctx, hdr.ChainID, hdr.ToArray()), "MerkleProve")
+	if err != nil {
+		return fmt.Errorf("valid poly header %d is rejected by ontology: %v", height, err)
+	}
+	n := len(hdr.Bookkeepers)
+	keep := (2*n - 1) / 3
+	hdr.Bookkeepers, hdr.SigData = hdr.Bookkeepers[:keep], hdr.SigData[:keep]
+	err = expectRejected(fmt.Sprintf("poly header %d signed by %d of %d keepers", height, keep, n),
+		preExecOntHeaderProof(ctx, hdr.ChainID, hdr.ToArray()), "2/3")
+	if err != nil {
+		return err
+	}
+	log.Infof("OntProofAttack, poly header signed by %d of %d keepers is rejected by ontology", keep, n)
+	return nil
+}
+
+// EthProofAttack replays the poly proof of a finished transfer to ECCM, then
+// submits it with the amount or target tampered, and with a header signed by
+// too few poly keepers, all of which must be rejected
+func EthProofAttack(ctx *testframework.TestFrameworkContext, status *testframework.CaseStatus) bool {
+	if err := ethProofAttack(ctx, status); err != nil {
+		log.Errorf("EthProofAttack, %v", err)
+		return false
+	}
+	status.SetItSuccess()
+	return true
+}
+
+// OntProofAttack does the same as EthProofAttack against verifyToOntProof
+// on ontology, and relays a poly header signed by too few keepers with a
+// cross chain tx to it
+func OntProofAttack(ctx *testframework.TestFrameworkContext, status *testframework.CaseStatus) bool {
+	if err := ontProofAttack(ctx, status); err != nil {
+		log.Errorf("OntProofAttack, %v", err)
+		return false
+	}
+	status.SetItSuccess()
+	return true
+}
@@ -0,0 +1,203 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package testcase
+
+import (
+	"fmt"
+	"github.com/polynetwork/poly-go-sdk"
+	"github.com/polynetwork/poly-io-test/config"
+	"github.com/polynetwork/poly-io-test/log"
+	"github.com/polynetwork/poly-io-test/testframework"
+	"github.com/polynetwork/poly/common"
+	"github.com/polynetwork/poly/native/service/governance/relayer_manager"
+	hscom "github.com/polynetwork/poly/native/service/header_sync/common"
+	"github.com/polynetwork/poly/native/service/utils"
+	"strings"
+)
+
+// notRelayerErr is returned by poly for txs signed by neither a relayer nor
+// a consensus peer
+const notRelayerErr = "address is not registered"
+
+func isRelayer(poly *poly_go_sdk.PolySdk, addr common.Address) (bool, error) {
+	raw, err := poly.GetStorage(utils.RelayerManagerContractAddress.ToHexString(),
+		append([]byte(relayer_manager.RELAYER), addr[:]...))
+	if err != nil {
+		return false, err
+	}
+	return len(raw) > 0, nil
+}
+
+// notifiedId returns the apply or remove id notified as name by tx
+func notifiedId(poly *poly_go_sdk.PolySdk, txhash common.Uint256, name string) (uint64, error) {
+	event, err := poly.GetSmartContractEvent(txhash.ToHexString())
+	if err != nil {
+		return 0, err
+	}
+	if event == nil {
+		return 0, fmt.Errorf("no event of tx %s", txhash.ToHexString())
+	}
+	for _, e := range event.Notify {
+		states, ok := e.States.([]interface{})
+		if !ok || len(states) < 2 {
+			continue
+		}
+		if s, _ := states[0].(string); s == name {
+			if id, ok := states[1].(float64); ok {
+				return uint64(id), nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("no %s notified by tx %s", name, txhash.ToHexString())
+}
+
+// setRelayer registers or removes the relayer with the approvals of
+// consensus, and checks the relayer list on poly
+func setRelayer(poly *poly_go_sdk.PolySdk, accArr []*poly_go_sdk.Account, relayer common.Address, add bool) error {
+	var (
+		txhash common.Uint256
+		err    error
+		name   = "putRelayerRemove"
+	)
+	if add {
+		name = "putRelayerApply"
+		txhash, err = poly.Native.Rm.RegisterRelayer([]common.Address{relayer}, accArr[0])
+	} else {
+		txhash, err = poly.Native.Rm.RemoveRelayer([]common.Address{relayer}, accArr[0])
+	}
+	if err != nil {
+		return err
+	}
+	WaitPolyTx(txhash, poly)
+	id, err := notifiedId(poly, txhash, name)
+	if err != nil {
+		return err
+	}
+	if err = approveAll(poly, accArr, func(acc *poly_go_sdk.Account) (common.Uint256, error) {
+		if add {
+			return poly.Native.Rm.ApproveRegisterRelayer(id, acc)
+		}
+		return poly.Native.Rm.ApproveRemoveRelayer(id, acc)
+	}); err != nil {
+		return err
+	}
+	ok, err := isRelayer(poly, relayer)
+	if err != nil {
+		return err
+	}
+	if ok != add {
+		return fmt.Errorf("relayer %s registered: %v after approved, but %v expected", relayer.ToBase58(), ok, add)
+	}
+	return nil
+}
+
+// syncSyncedEthHeader submits the ethereum header at the height already
+// synced on poly, so it is a no-op if the signer is allowed to submit
+func syncSyncedEthHeader(ctx *testframework.TestFrameworkContext, signer *poly_go_sdk.Account) (common.Uint256, error) {
+	raw, err := ctx.RcSdk.GetStorage(utils.HeaderSyncContractAddress.ToHexString(),
+		append([]byte(hscom.CURRENT_HEADER_HEIGHT), utils.GetUint64Bytes(config.ETH_CHAIN_ID)...))
+	if err != nil {
+		return common.UINT256_EMPTY, err
+	}
+	if len(raw) == 0 {
+		return common.UINT256_EMPTY, fmt.Errorf("no ethereum header synced on poly")
+	}
+	hdr, err := ctx.EthInvoker.ETHUtil.GetBlockHeader(utils.GetBytesUint64(raw))
+	if err != nil {
+		return common.UINT256_EMPTY, err
+	}
+	rawHdr, err := hdr.MarshalJSON()
+	if err != nil {
+		return common.UINT256_EMPTY, err
+	}
+	return ctx.RcSdk.Native.Hs.SyncBlockHeader(config.ETH_CHAIN_ID, signer.Address, [][]byte{rawHdr}, signer)
+}
+
+// checkSubmit submits a header from the relayer and checks poly accepts it
+// or rejects it for not registered
+func checkSubmit(ctx *testframework.TestFrameworkContext, relayer *poly_go_sdk.Account, accepted bool) error {
+	txhash, err := syncSyncedEthHeader(ctx, relayer)
+	if err != nil {
+		if !accepted && strings.Contains(err.Error(), notRelayerErr) {
+			return nil
+		}
+		return fmt.Errorf("header from %s should be accepted: %v, but got: %v", relayer.Address.ToBase58(),
+			accepted, err)
+	}
+	if !accepted {
+		return fmt.Errorf("header from %s is accepted ( txhash: %s ) but it is not a relayer",
+			relayer.Address.ToBase58(), txhash.ToHexString())
+	}
+	WaitPolyTx(txhash, ctx.RcSdk)
+	return nil
+}
+
+func relayerWhitelist(ctx *testframework.TestFrameworkContext, accArr []*poly_go_sdk.Account) error {
+	poly := ctx.RcSdk
+	relayer := poly_go_sdk.NewAccount()
+	addr := relayer.Address.ToBase58()
+	if err := checkSubmit(ctx, relayer, false); err != nil {
+		return fmt.Errorf("new account: %v", err)
+	}
+	log.Infof("RelayerWhitelist, header from new account %s is rejected", addr)
+
+	if err := setRelayer(poly, accArr, relayer.Address, true); err != nil {
+		return fmt.Errorf("register: %v", err)
+	}
+	if err := checkSubmit(ctx, relayer, true); err != nil {
+		return fmt.Errorf("registered: %v", err)
+	}
+	log.Infof("RelayerWhitelist, header from relayer %s is accepted", addr)
+
+	if err := setRelayer(poly, accArr, relayer.Address, false); err != nil {
+		return fmt.Errorf("remove: %v", err)
+	}
+	if err := checkSubmit(ctx, relayer, false); err != nil {
+		return fmt.Errorf("removed: %v", err)
+	}
+	log.Infof("RelayerWhitelist, header from removed relayer %s is rejected", addr)
+
+	if err := setRelayer(poly, accArr, relayer.Address, true); err != nil {
+		return fmt.Errorf("re-register: %v", err)
+	}
+	if err := checkSubmit(ctx, relayer, true); err != nil {
+		return fmt.Errorf("re-registered: %v", err)
+	}
+	log.Infof("RelayerWhitelist, header from re-added relayer %s is accepted", addr)
+
+	// the account is thrown away, do not leave it in the list
+	if err := setRelayer(poly, accArr, relayer.Address, false); err != nil {
+		return fmt.Errorf("cleanup: %v", err)
+	}
+	return nil
+}
+
+// RelayerWhitelist checks poly rejects headers from an account removed from
+// relayers and accepts them again after it is re-added
+func RelayerWhitelist(ctx *testframework.TestFrameworkContext, status *testframework.CaseStatus) bool {
+	accArr, err := getRCConsensusAccounts(ctx.RcSdk)
+	if err != nil {
+		log.Errorf("RelayerWhitelist, %v", err)
+		return false
+	}
+	if err = relayerWhitelist(ctx, accArr); err != nil {
+		log.Errorf("RelayerWhitelist, %v", err)
+		return false
+	}
+	status.SetItSuccess()
+	return true
+}
@@ -0,0 +1,335 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package testcase
+
+import (
+	"encoding/hex"
+	"fmt"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/polynetwork/poly-go-sdk"
+	"github.com/polynetwork/poly-io-test/chains/eth"
+	"github.com/polynetwork/poly-io-test/chains/poly"
+	"github.com/polynetwork/poly-io-test/config"
+	"github.com/polynetwork/poly-io-test/gov"
+	"github.com/polynetwork/poly-io-test/log"
+	"github.com/polynetwork/poly-io-test/testframework"
+	"github.com/polynetwork/poly/common"
+	"github.com/polynetwork/poly/native/service/utils"
+	"math/big"
+	"strings"
+)
+
+// LifecycleChainId is the dummy side chain walked through its lifecycle,
+// it must not be used by any real chain
+var LifecycleChainId uint64 = 88888
+
+// approveAll sends the approval of every consensus account and waits for
+// the last one
+func approveAll(poly *poly_go_sdk.PolySdk, accArr []*poly_go_sdk.Account,
+	approve func(acc *poly_go_sdk.Account) (common.Uint256, error)) error {
+	var txhash common.Uint256
+	for i, acc := range accArr {
+		var err error
+		if txhash, err = approve(acc); err != nil {
+			return fmt.Errorf("no%d failed to approve: %v", i, err)
+		}
+	}
+	WaitPolyTx(txhash, poly)
+	return nil
+}
+
+// checkSideChain compares the side chain on poly with expect, nil for not
+// registered, and checks that the only pending proposal of it is of kind
+// pending, none if empty
+func checkSideChain(poly *poly_go_sdk.PolySdk, id uint64, expect *gov.SideChain, pending string) error {
+	status, err := gov.QueryStatus(poly, []uint64{id}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to query status: %v", err)
+	}
+	var actual *gov.SideChain
+	if len(status.SideChains) > 0 {
+		actual = status.SideChains[0]
+	}
+	switch {
+	case expect == nil && actual != nil:
+		return fmt.Errorf("side chain %d should not be registered, but found %s", id, actual.Name)
+	case expect != nil && actual == nil:
+		return fmt.Errorf("side chain %d should be registered, but not found", id)
+	case expect != nil && *expect != *actual:
+		return fmt.Errorf("side chain %d on poly is %+v, but %+v is expected", id, *actual, *expect)
+	}
+	kinds := make([]string, 0)
+	for _, p := range status.Proposals {
+		if p.Id == id {
+			kinds = append(kinds, p.Kind)
+		}
+	}
+	if pending == "" && len(kinds) > 0 || pending != "" && (len(kinds) != 1 || kinds[0] != pending) {
+		return fmt.Errorf("proposals of side chain %d are [%s], but [%s] is expected", id,
+			strings.Join(kinds, ", "), pending)
+	}
+	return nil
+}
+
+// checkRejected checks poly rejects cross chain txs of side chain id for
+// not registered or not
+func checkRejected(sdk *poly_go_sdk.PolySdk, id uint64, signer *poly_go_sdk.Account, rejected bool) error {
+	err := poly.PreExecImport(sdk, signer, id, []byte{}, 0, []byte{})
+	if err == nil {
+		return fmt.Errorf("fake cross chain tx from side chain %d is accepted", id)
+	}
+	notRegistered := strings.Contains(err.Error(), fmt.Sprintf("side chain %d is not registered", id))
+	if notRegistered != rejected {
+		return fmt.Errorf("fake cross chain tx from side chain %d should be rejected for not registered: %v, "+
+			"but got: %v", id, rejected, err)
+	}
+	return nil
+}
+
+// bindEthProxyTo binds a dummy lock proxy and ether of side chain id on the
+// ethereum lock proxy if not yet, so that locks to the chain pass ethereum
+func bindEthProxyTo(ei *eth.EInvoker, id uint64) error {
+	auth, proxy, err := ei.MakeLockProxy(config.DefConfig.EthLockProxy)
+	if err != nil {
+		return err
+	}
+	hash, err := proxy.ProxyHashMap(nil, id)
+	if err != nil {
+		return fmt.Errorf("failed to get proxy of chain %d: %v", id, err)
+	}
+	if len(hash) == 0 {
+		tx, err := proxy.BindProxyHash(auth, id, []byte("lifecycle_proxy"))
+		if err != nil {
+			return fmt.Errorf("failed to bind proxy of chain %d: %v", id, err)
+		}
+		ei.ETHUtil.WaitTransactionConfirm(tx.Hash())
+	}
+	if hash, err = proxy.AssetHashMap(nil, ethcommon.Address{}, id); err != nil {
+		return fmt.Errorf("failed to get ether of chain %d: %v", id, err)
+	}
+	if len(hash) == 0 {
+		auth, _ = ei.MakeSmartContractAuth()
+		tx, err := proxy.BindAssetHash(auth, ethcommon.Address{}, id, []byte("lifecycle_ether"))
+		if err != nil {
+			return fmt.Errorf("failed to bind ether of chain %d: %v", id, err)
+		}
+		ei.ETHUtil.WaitTransactionConfirm(tx.Hash())
+	}
+	return nil
+}
+
+// checkLockToRejected locks ether to side chain id on ethereum and checks
+// poly rejects importing it for the target chain not registered
+func checkLockToRejected(ctx *testframework.TestFrameworkContext, id uint64, signer *poly_go_sdk.Account) error {
+	if err := bindEthProxyTo(ctx.EthInvoker, id); err != nil {
+		return err
+	}
+	one := big.NewInt(1)
+	data, err := packEthLock(ethcommon.Address{}, id, []byte("lifecycle_user"), one)
+	if err != nil {
+		return err
+	}
+	tx, err := sendEthTx(ctx, ethcommon.HexToAddress(config.DefConfig.EthLockProxy), data, one)
+	if err != nil {
+		return fmt.Errorf("failed to lock ether to side chain %d: %v", id, err)
+	}
+	WaitTransactionConfirm(ctx.EthInvoker.ETHUtil.GetEthClient(), tx.Hash())
+	err = poly.PreExecEthImport(ctx.EthInvoker, ctx.RcSdk, signer, tx.Hash(), NonExecutionWait)
+	if err == nil {
+		return fmt.Errorf("lock %s to side chain %d is accepted", tx.Hash().String(), id)
+	}
+	if !strings.Contains(err.Error(), fmt.Sprintf("side chain %d is not registered", id)) {
+		return fmt.Errorf("lock %s to side chain %d should be rejected for not registered, but got: %v",
+			tx.Hash().String(), id, err)
+	}
+	return nil
+}
+
+// registerLifecycleChain registers sc with the approvals of consensus, checking
+// poly storage after each step
+func registerLifecycleChain(poly *poly_go_sdk.PolySdk, accArr []*poly_go_sdk.Account, sc *gov.SideChain,
+	ccmc []byte) error {
+	owner := accArr[0]
+	txhash, err := poly.Native.Scm.RegisterSideChain(owner.Address, sc.ChainId, sc.Router, sc.Name,
+		sc.BlocksToWait, ccmc, owner)
+	if err != nil {
+		return fmt.Errorf("register: %v", err)
+	}
+	WaitPolyTx(txhash, poly)
+	if err = checkSideChain(poly, sc.ChainId, nil, gov.ProposalRegisterSideChain); err != nil {
+		return fmt.Errorf("register: %v", err)
+	}
+	log.Infof("SideChainLifecycle, side chain %d is applied ( txhash: %s )", sc.ChainId, txhash.ToHexString())
+
+	if err = approveAll(poly, accArr, func(acc *poly_go_sdk.Account) (common.Uint256, error) {
+		return poly.Native.Scm.ApproveRegisterSideChain(sc.ChainId, acc)
+	}); err != nil {
+		return fmt.Errorf("approve register: %v", err)
+	}
+	if err = checkSideChain(poly, sc.ChainId, sc, ""); err != nil {
+		return fmt.Errorf("approve register: %v", err)
+	}
+	if err = checkRejected(poly, sc.ChainId, owner, false); err != nil {
+		return fmt.Errorf("approve register: %v", err)
+	}
+	log.Infof("SideChainLifecycle, side chain %d is registered", sc.ChainId)
+	return nil
+}
+
+// quitRegisteredChain quits the registered side chain sc with the approvals of
+// consensus, checking poly storage after each step
+func quitRegisteredChain(poly *poly_go_sdk.PolySdk, accArr []*poly_go_sdk.Account, sc *gov.SideChain) error {
+	owner := accArr[0]
+	txhash, err := poly.Native.Scm.QuitSideChain(sc.ChainId, owner)
+	if err != nil {
+		return fmt.Errorf("quit: %v", err)
+	}
+	WaitPolyTx(txhash, poly)
+	if err = checkSideChain(poly, sc.ChainId, sc, gov.ProposalQuitSideChain); err != nil {
+		return fmt.Errorf("quit: %v", err)
+	}
+	if err = approveAll(poly, accArr, func(acc *poly_go_sdk.Account) (common.Uint256, error) {
+		return poly.Native.Scm.ApproveQuitSideChain(sc.ChainId, acc)
+	}); err != nil {
+		return fmt.Errorf("approve quit: %v", err)
+	}
+	if err = checkSideChain(poly, sc.ChainId, nil, ""); err != nil {
+		return fmt.Errorf("approve quit: %v", err)
+	}
+	if err = checkRejected(poly, sc.ChainId, owner, true); err != nil {
+		return fmt.Errorf("approve quit: %v", err)
+	}
+	return nil
+}
+
+// quitLifecycleChain removes what the last run left on poly
+func quitLifecycleChain(poly *poly_go_sdk.PolySdk, accArr []*poly_go_sdk.Account, owner *poly_go_sdk.Account) error {
+	id := LifecycleChainId
+	status, err := gov.QueryStatus(poly, []uint64{id}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to query status: %v", err)
+	}
+	for _, p := range status.Proposals {
+		if p.Id == id && p.Kind == gov.ProposalRegisterSideChain {
+			log.Infof("SideChainLifecycle, approve the register of %d left by last run", id)
+			if err = approveAll(poly, accArr, func(acc *poly_go_sdk.Account) (common.Uint256, error) {
+				return poly.Native.Scm.ApproveRegisterSideChain(id, acc)
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	if err = checkSideChain(poly, id, nil, ""); err == nil {
+		return nil
+	}
+	log.Infof("SideChainLifecycle, quit side chain %d left by last run", id)
+	txhash, err := poly.Native.Scm.QuitSideChain(id, owner)
+	if err != nil {
+		return fmt.Errorf("failed to quit side chain %d: %v", id, err)
+	}
+	WaitPolyTx(txhash, poly)
+	return approveAll(poly, accArr, func(acc *poly_go_sdk.Account) (common.Uint256, error) {
+		return poly.Native.Scm.ApproveQuitSideChain(id, acc)
+	})
+}
+
+func sideChainLifecycle(ctx *testframework.TestFrameworkContext, accArr []*poly_go_sdk.Account) error {
+	poly, id, owner := ctx.RcSdk, LifecycleChainId, accArr[0]
+	if err := quitLifecycleChain(poly, accArr, owner); err != nil {
+		return fmt.Errorf("cleanup: %v", err)
+	}
+	if err := checkSideChain(poly, id, nil, ""); err != nil {
+		return fmt.Errorf("cleanup: %v", err)
+	}
+	if err := checkRejected(poly, id, owner, true); err != nil {
+		return fmt.Errorf("cleanup: %v", err)
+	}
+
+	ccmc := []byte("lifecycle_ccmc")
+	sc := &gov.SideChain{
+		ChainId:      id,
+		Name:         "lifecycle",
+		Router:       utils.ETH_ROUTER,
+		BlocksToWait: 1,
+		Owner:        owner.Address.ToBase58(),
+		CCMCAddress:  hex.EncodeToString(ccmc),
+	}
+	if err := registerLifecycleChain(poly, accArr, sc, ccmc); err != nil {
+		return err
+	}
+
+	updated := *sc
+	updated.Name, updated.Router, updated.BlocksToWait = "lifecycle_updated", utils.ONT_ROUTER, 3
+	txhash, err := poly.Native.Scm.UpdateSideChain(owner.Address, id, updated.Router, updated.Name,
+		updated.BlocksToWait, ccmc, owner)
+	if err != nil {
+		return fmt.Errorf("update: %v", err)
+	}
+	WaitPolyTx(txhash, poly)
+	if err = checkSideChain(poly, id, sc, gov.ProposalUpdateSideChain); err != nil {
+		return fmt.Errorf("update: %v", err)
+	}
+	if err = approveAll(poly, accArr, func(acc *poly_go_sdk.Account) (common.Uint256, error) {
+		return poly.Native.Scm.ApproveUpdateSideChain(id, acc)
+	}); err != nil {
+		return fmt.Errorf("approve update: %v", err)
+	}
+	if err = checkSideChain(poly, id, &updated, ""); err != nil {
+		return fmt.Errorf("approve update: %v", err)
+	}
+	log.Infof("SideChainLifecycle, side chain %d is updated: ( router: %d, blocks_to_wait: %d )", id,
+		updated.Router, updated.BlocksToWait)
+
+	if err = quitRegisteredChain(poly, accArr, &updated); err != nil {
+		return err
+	}
+	if err = checkLockToRejected(ctx, id, owner); err != nil {
+		return fmt.Errorf("approve quit: %v", err)
+	}
+	log.Infof("SideChainLifecycle, side chain %d quit and cross chain txs from and to it are rejected", id)
+
+	// a quit chain can come back by registering again
+	if err = registerLifecycleChain(poly, accArr, sc, ccmc); err != nil {
+		return fmt.Errorf("again, %v", err)
+	}
+	if err = quitRegisteredChain(poly, accArr, sc); err != nil {
+		return fmt.Errorf("again, %v", err)
+	}
+	log.Infof("SideChainLifecycle, side chain %d registered again and quit", id)
+	return nil
+}
+
+// SideChainLifecycle walks a dummy side chain through register, update, quit
+// and register again with the approvals of consensus, checking poly storage
+// after each step
+func SideChainLifecycle(ctx *testframework.TestFrameworkContext, status *testframework.CaseStatus) bool {
+	if ctx.EthInvoker == nil {
+		log.Errorf("SideChainLifecycle, ethereum is needed to lock to the quit chain")
+		return false
+	}
+	accArr, err := getRCConsensusAccounts(ctx.RcSdk)
+	if err != nil {
+		log.Errorf("SideChainLifecycle, %v", err)
+		return false
+	}
+	if err = sideChainLifecycle(ctx, accArr); err != nil {
+		log.Errorf("SideChainLifecycle, %v", err)
+		return false
+	}
+	status.SetItSuccess()
+	return true
+}
@@ -17,6 +17,7 @@
 package testcase
 
 import (
+	"github.com/polynetwork/poly-io-test/chains/btc"
 	"github.com/polynetwork/poly-io-test/config"
 	"github.com/polynetwork/poly-io-test/log"
 	"github.com/polynetwork/poly-io-test/testframework"
@@ -86,6 +87,35 @@ func SendBtcToOntChain(ctx *testframework.TestFrameworkContext, status *testfram
 	return true
 }
 
+// SendBtcToOntWithRbf sends a replaceable BTC at the min relay fee rate to ontology,
+// then bumps its fee and checks the replacement goes through
+func SendBtcToOntWithRbf(ctx *testframework.TestFrameworkContext, status *testframework.CaseStatus) bool {
+	amt := int64(GetRandAmount(config.DefConfig.BtcValLimit, config.DefConfig.BtcMinOutputValFromContract))
+	k, err := sendBtcCrossAt(ctx, config.ONT_CHAIN_ID, ctx.BtcInvoker.Signer, ctx.OntInvoker.OntAcc.Address.ToBase58(),
+		amt, btc.MinRelayFeeRate, true)
+	if err != nil {
+		log.Errorf("SendBtcToOntWithRbf, sendBtcCrossAt error: %v", err)
+		return false
+	}
+	status.AddTx(k, &testframework.TxInfo{Ty: "BtcToOnt", StartTime: time.Now()})
+	rate := btcFeeRate(ctx)
+	if rate <= btc.MinRelayFeeRate {
+		rate = btc.MinRelayFeeRate + 1
+	}
+	if _, err = BumpBtcCross(ctx, status, k, rate); err == btc.ErrConfirmed {
+		log.Warnf("SendBtcToOntWithRbf, %s is confirmed before bumped, nothing to replace", k)
+	} else if err != nil {
+		log.Errorf("SendBtcToOntWithRbf, %v", err)
+		return false
+	}
+	if err = WaitUntilCleanIn(status, CleanWait); err != nil {
+		log.Errorf("SendBtcToOntWithRbf, %v", err)
+		return false
+	}
+	status.SetItSuccess()
+	return true
+}
+
 func SendBtcToEthChain(ctx *testframework.TestFrameworkContext, status *testframework.CaseStatus) bool {
 	if err := SendBtcCrossEth(ctx, status, int64(GetRandAmount(config.DefConfig.BtcValLimit, config.DefConfig.BtcMinOutputValFromContract))); err != nil {
 		log.Errorf("SendBtcToEthChain, SendBtcCrossEth error: %s", err)
@@ -22,20 +22,19 @@ import (
 	"encoding/hex"
 	"fmt"
 	"github.com/btcsuite/btcd/btcjson"
-	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcutil"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/polynetwork/poly-go-sdk"
 	"github.com/polynetwork/poly-io-test/chains/btc"
 	"github.com/polynetwork/poly-io-test/chains/eth"
+	"github.com/polynetwork/poly-io-test/chains/poly"
 	"github.com/polynetwork/poly-io-test/config"
 	"github.com/polynetwork/poly-io-test/log"
 	"github.com/polynetwork/poly-io-test/testframework"
 	"github.com/polynetwork/poly/common"
 	"math/big"
 	"math/rand"
-	"strconv"
 	"strings"
 	"time"
 
@@ -61,22 +60,8 @@ func WaitTransactionConfirm(ethclient *ethclient.Client, hash ethcommon.Hash) {
 	}
 }
 
-func WaitPolyTx(txhash common.Uint256, poly *poly_go_sdk.PolySdk) {
-	tick := time.NewTicker(100 * time.Millisecond)
-	var h uint32
-	startTime := time.Now()
-	for range tick.C {
-		h, _ = poly.GetBlockHeightByTxHash(txhash.ToHexString())
-		curr, _ := poly.GetCurrentBlockHeight()
-		if h > 0 && curr > h {
-			break
-		}
-
-		if startTime.Add(100 * time.Millisecond); startTime.Second() > 300 {
-			panic(fmt.Errorf("tx( %s ) is not confirm for a long time ( over %d sec )",
-				txhash.ToHexString(), 300))
-		}
-	}
+func WaitPolyTx(txhash common.Uint256, sdk *poly_go_sdk.PolySdk) {
+	poly.WaitTx(txhash, sdk)
 }
 
 func GetRandAmount(high, low uint64) uint64 {
@@ -92,6 +77,26 @@ func WaitUntilClean(status *testframework.CaseStatus) {
 	}
 }
 
+// CleanWait bounds how long a case waits for its transfers to finish
+var CleanWait = 30 * time.Minute
+
+// WaitUntilCleanIn waits until all txs of status finish, and fails if any is
+// still pending after timeout
+func WaitUntilCleanIn(status *testframework.CaseStatus, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+	for range tick.C {
+		if status.Len() == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%d txs not finished in %v:\n%s", status.Len(), timeout, status.Info())
+		}
+	}
+	return nil
+}
+
 func MakeEthAuth(signer *eth.EthSigner, nonce, gasPrice, gasLimit uint64) *bind.TransactOpts {
 	auth := bind.NewKeyedTransactor(signer.PrivateKey)
 	auth.Nonce = big.NewInt(int64(nonce))
@@ -102,95 +107,132 @@ func MakeEthAuth(signer *eth.EthSigner, nonce, gasPrice, gasLimit uint64) *bind.
 	return auth
 }
 
-func sendBtcCross(ctx *testframework.TestFrameworkContext, chainID uint64, btcSigner *btc.BtcSigner,
-	targetAddress string, amount int64) (string, error) {
-	value := float64(amount) / btcutil.SatoshiPerBitcoin
-	fee := float64(config.DefConfig.BtcFee) / btcutil.SatoshiPerBitcoin
-
-	addrPubk, err := btcutil.NewAddressPubKey(btcSigner.WIF.PrivKey.PubKey().SerializeCompressed(), config.BtcNet)
-	if err != nil {
-		return "", fmt.Errorf("sendBtcCross, Failed to new an address pubkey: %v", err)
-	}
-	pubkScript, err := txscript.PayToAddrScript(addrPubk.AddressPubKeyHash())
-	if err != nil {
-		return "", fmt.Errorf("sendBtcCross, Failed to build pubk script: %v", err)
-	}
-	data, err := btc.BuildData(chainID, 0, targetAddress)
-	if err != nil {
-		return "", fmt.Errorf("sendBtcCross, Failed to ge data: %v", err)
-	}
-
-	addr := addrPubk.EncodeAddress()
-	//err = ra.Cli.ImportAddress(addr)
-	//if err != nil {
-	//	log.Errorf("rpc failed: %v", err)
-	//	return nil
-	//}
-HERE:
-	cnt, err := ctx.BtcInvoker.BtcCli.GetBlockCount()
-	if err != nil {
-		return "", fmt.Errorf("sendBtcCross, rpc failed: %v", err)
-	}
-	utxos, err := ctx.BtcInvoker.BtcCli.ListUnspent(1, cnt, addr)
-	if err != nil {
-		return "", fmt.Errorf("sendBtcCross, rpc failed: %v", err)
-	}
-	total, err := btcutil.NewAmount(value + fee)
-	if err != nil {
-		return "", fmt.Errorf("sendBtcCross, failed to new amount: %v", err)
-	}
-	selected, sumVal, err := btc.SelectUtxos(utxos, int64(total))
-	if err != nil {
-		return "", fmt.Errorf("sendBtcCross, failed to select utxo when build btc tx: %v", err)
-	}
+// btcFeeRate returns the fee rate in satoshi per vbyte for sends
+func btcFeeRate(ctx *testframework.TestFrameworkContext) uint64 {
+	return btc.FeeRate(ctx.BtcInvoker.BtcCli, config.DefConfig.BtcSendFeeRate)
+}
 
-	//var prevPkScripts [][]byte
+// signBtcCross builds and signs the cross chain tx spending selected utxos of from with fee
+func signBtcCross(from btcutil.Address, btcSigner *btc.BtcSigner, selected []*btc.Utxo, sumVal int64,
+	data []byte, amount, fee int64, replaceable bool) (*wire.MsgTx, error) {
+	var prevPkScripts [][]byte
+	var prevAmounts []int64
 	var ipts []btcjson.TransactionInput
 	for _, v := range selected {
 		ipts = append(ipts, btcjson.TransactionInput{
 			Txid: v.Txid,
 			Vout: v.Vout,
 		})
+		pkScript, err := hex.DecodeString(v.ScriptPubKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode pk script of utxo %s:%d: %v", v.Txid, v.Vout, err)
+		}
+		prevPkScripts = append(prevPkScripts, pkScript)
+		prevAmounts = append(prevAmounts, v.Amount)
+	}
+	changes := map[string]float64{}
+	if change := sumVal - amount - fee; change >= btc.DustLimit {
+		changes[from.EncodeAddress()] = float64(change) / btcutil.SatoshiPerBitcoin
 	}
 
+	// lock to P2SH or P2WSH at random so that both are covered, unless P2WSH is forced
+	toP2wsh := config.DefConfig.BtcLockToP2wsh || rand.Intn(2) == 1
 	b, err := btc.NewBuilder(&btc.BuildCrossChainTxParam{
-		Redeem:       config.DefConfig.BtcRedeem,
-		Data:         data,
-		Inputs:       ipts,
-		NetParam:     config.BtcNet,
-		PrevPkScript: pubkScript,
-		Privk:        btcSigner.WIF.PrivKey,
-		Locktime:     nil,
-		ToMultiValue: value,
-		Changes: func() map[string]float64 {
-			if changeVal := float64(sumVal)/btcutil.SatoshiPerBitcoin - value - fee; changeVal > 0 {
-				return map[string]float64{addrPubk.EncodeAddress(): changeVal}
-			} else {
-				return map[string]float64{}
-			}
-		}(),
+		Redeem:        config.DefConfig.BtcRedeem,
+		Data:          data,
+		Inputs:        ipts,
+		NetParam:      config.BtcNet,
+		PrevPkScripts: prevPkScripts,
+		PrevAmounts:   prevAmounts,
+		ToP2wsh:       toP2wsh,
+		Replaceable:   replaceable,
+		Privk:         btcSigner.WIF.PrivKey,
+		Locktime:      nil,
+		ToMultiValue:  float64(amount) / btcutil.SatoshiPerBitcoin,
+		Changes:       changes,
 	})
 	if err != nil {
-		return "", fmt.Errorf("sendBtcCross, Failed to new an instance of Builder: %v", err)
+		return nil, fmt.Errorf("Failed to new an instance of Builder: %v", err)
 	}
-
-	var buf bytes.Buffer
 	err = b.BuildSignedTx()
 	if err != nil || !b.IsSigned {
-		return "", fmt.Errorf("sendBtcCross, Failed to build signed transaction: %v", err)
+		return nil, fmt.Errorf("Failed to build signed transaction: %v", err)
 	}
-	err = b.Tx.BtcEncode(&buf, wire.ProtocolVersion, wire.LatestEncoding)
-	if err != nil {
-		return "", fmt.Errorf("sendBtcCross, Failed to encode transaction: %v", err)
+	return b.Tx, nil
+}
+
+// buildBtcCross builds the cross chain tx spending utxos reserved from mgr, and raises the fee
+// until it covers the vsize of signed tx at feeRate. The utxos spent are kept reserved.
+func buildBtcCross(from btcutil.Address, btcSigner *btc.BtcSigner, mgr *btc.UtxoManager, data []byte,
+	amount int64, feeRate uint64, replaceable bool) (*wire.MsgTx, []*btc.Utxo, error) {
+	fee := int64(0)
+	for i := 0; i < 5; i++ {
+		selected, sumVal, err := mgr.Reserve(amount + fee)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to select utxo when build btc tx: %v", err)
+		}
+		mtx, err := signBtcCross(from, btcSigner, selected, sumVal, data, amount, fee, replaceable)
+		if err != nil {
+			mgr.Release(selected)
+			return nil, nil, err
+		}
+		need := btc.VSize(mtx) * int64(feeRate)
+		if fee >= need {
+			return mtx, selected, nil
+		}
+		mgr.Release(selected)
+		fee = need
 	}
+	return nil, nil, fmt.Errorf("fee not settled at %d sat/vbyte", feeRate)
+}
 
-	txid, err := ctx.BtcInvoker.BtcCli.SendRawTx(hex.EncodeToString(buf.Bytes()))
+func sendRawBtcTx(ctx *testframework.TestFrameworkContext, mtx *wire.MsgTx) (string, error) {
+	var buf bytes.Buffer
+	if err := mtx.BtcEncode(&buf, wire.ProtocolVersion, wire.LatestEncoding); err != nil {
+		return "", fmt.Errorf("Failed to encode transaction: %v", err)
+	}
+	return ctx.BtcInvoker.BtcCli.SendRawTx(hex.EncodeToString(buf.Bytes()))
+}
+
+func sendBtcCross(ctx *testframework.TestFrameworkContext, chainID uint64, btcSigner *btc.BtcSigner,
+	targetAddress string, amount int64) (string, error) {
+	return sendBtcCrossAt(ctx, chainID, btcSigner, targetAddress, amount, btcFeeRate(ctx),
+		config.DefConfig.BtcReplaceable)
+}
+
+// sendBtcCrossAt sends the cross chain tx paying fee at feeRate, and returns the txid in
+// the byte order tracked by status
+func sendBtcCrossAt(ctx *testframework.TestFrameworkContext, chainID uint64, btcSigner *btc.BtcSigner,
+	targetAddress string, amount int64, feeRate uint64, replaceable bool) (string, error) {
+	from, err := btcSigner.AddressOf(config.DefConfig.BtcSignerInputType, config.BtcNet)
+	if err != nil {
+		return "", fmt.Errorf("sendBtcCross, Failed to get address of signer: %v", err)
+	}
+	data, err := btc.BuildData(chainID, 0, targetAddress)
 	if err != nil {
-		if strings.Contains(err.Error(), "min relay fee not met") {
-			arr := strings.Split(err.Error(), " ")
-			newFee, _ := strconv.ParseUint(arr[len(arr)-1], 10, 64)
-			fee = float64(newFee) / btcutil.SatoshiPerBitcoin
-			goto HERE
+		return "", fmt.Errorf("sendBtcCross, Failed to ge data: %v", err)
+	}
+
+	mgr := ctx.BtcInvoker.Utxos
+	var txid string
+	for retry := 0; ; retry++ {
+		mtx, selected, err := buildBtcCross(from, btcSigner, mgr, data, amount, feeRate, replaceable)
+		if err != nil {
+			return "", fmt.Errorf("sendBtcCross, %v", err)
+		}
+		txid, err = sendRawBtcTx(ctx, mtx)
+		if err == nil {
+			mgr.Spent(mtx)
+			break
+		}
+		mgr.Release(selected)
+		// the node may ask for more than the estimated fee
+		if retry < 3 && strings.Contains(err.Error(), "min relay fee not met") {
+			if feeRate *= 2; feeRate < btc.MinRelayFeeRate {
+				feeRate = btc.MinRelayFeeRate
+			}
+			log.Warnf("sendBtcCross, retry with fee rate %d sat/vbyte: %v", feeRate, err)
+			continue
 		}
 		return "", fmt.Errorf("sendBtcCross, failed to send tx: %v", err)
 	}
@@ -198,6 +240,41 @@ HERE:
 	if err != nil {
 		return "", fmt.Errorf("sendBtcCross, hex.DecodeString error: %v", err)
 	}
-	log.Infof("sendBtcCross, send tx %s(%f btc) to regression net(%s)", txid, value, ctx.BtcInvoker.BtcCli.Addr)
+	log.Infof("sendBtcCross, send tx %s(%d sat, %d sat/vbyte) to %s", txid, amount, feeRate,
+		config.DefConfig.BtcNetType)
 	return hex.EncodeToString(ToArrayReverse(txidBytes)), nil
 }
+
+// BumpBtcCross replaces the stuck cross chain tx tracked as k in status by one paying fee
+// at feeRate, and tracks the replacement instead. It returns btc.ErrConfirmed if the tx is
+// already in a block.
+func BumpBtcCross(ctx *testframework.TestFrameworkContext, status *testframework.CaseStatus, k string,
+	feeRate uint64) (string, error) {
+	raw, err := hex.DecodeString(k)
+	if err != nil {
+		return "", fmt.Errorf("BumpBtcCross, wrong txid %s: %v", k, err)
+	}
+	txid := hex.EncodeToString(ToArrayReverse(raw))
+	mtx, err := btc.BumpFee(ctx.BtcInvoker.BtcCli, ctx.BtcInvoker.Signer.WIF.PrivKey, config.BtcNet, txid, feeRate)
+	if err == btc.ErrConfirmed {
+		return "", err
+	}
+	if err != nil {
+		return "", fmt.Errorf("BumpBtcCross, failed to bump fee of %s: %v", txid, err)
+	}
+	nid, err := sendRawBtcTx(ctx, mtx)
+	if err != nil {
+		return "", fmt.Errorf("BumpBtcCross, failed to send replacement of %s: %v", txid, err)
+	}
+	ctx.BtcInvoker.Utxos.Replaced(txid, mtx)
+	raw, err = hex.DecodeString(nid)
+	if err != nil {
+		return "", fmt.Errorf("BumpBtcCross, hex.DecodeString error: %v", err)
+	}
+	nk := hex.EncodeToString(ToArrayReverse(raw))
+	if !status.Replace(k, nk) {
+		log.Warnf("BumpBtcCross, %s is not tracked", k)
+	}
+	log.Infof("BumpBtcCross, tx %s replaced by %s at %d sat/vbyte", txid, nid, feeRate)
+	return nk, nil
+}
@@ -0,0 +1,141 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package testcase
+
+import (
+	"encoding/hex"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+	"github.com/polynetwork/poly-io-test/chains/btc"
+	"github.com/polynetwork/poly-io-test/config"
+	"github.com/polynetwork/poly-io-test/testframework"
+	"testing"
+	"time"
+)
+
+const testEthAddr = "0x344cFc3B8635f72F14200aAf2168d9f75df86FD3"
+
+// newBtcTestCtx returns a ctx whose btc invoker talks to a fake node, with the signer funded
+func newBtcTestCtx(t *testing.T, inputType string, amounts ...int64) (*testframework.TestFrameworkContext,
+	*btc.FakeCli) {
+	btc.KeepConfig(t)
+	config.BtcNet = &chaincfg.RegressionNetParams
+	config.DefConfig.BtcNetType = "regtest"
+	config.DefConfig.BtcSignerInputType = inputType
+	config.DefConfig.BtcLockToP2wsh = false
+
+	vendor, _ := btc.TestVendor(t, config.BtcNet)
+	config.DefConfig.BtcRedeem = hex.EncodeToString(vendor.Redeem)
+
+	privk, _ := btcec.PrivKeyFromBytes(btcec.S256(), chainhash.HashB([]byte("signer")))
+	wif, _ := btcutil.NewWIF(privk, config.BtcNet, true)
+	signer, err := btc.NewBtcSigner(wif.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, err := signer.AddressOf(inputType, config.BtcNet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli := btc.NewFakeCli(config.BtcNet)
+	if _, err = cli.Fund(addr.EncodeAddress(), amounts...); err != nil {
+		t.Fatal(err)
+	}
+	mgr, err := btc.NewUtxoManager(cli, addr, btc.SelectLargestFirst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	invoker := &btc.BtcInvoker{BtcCli: cli, Signer: signer, Utxos: mgr}
+	return testframework.NewTestFrameworkContext(nil, nil, nil, nil, invoker, nil, nil), cli
+}
+
+func TestSendBtcCross(t *testing.T) {
+	for _, ty := range []string{btc.InputP2pkh, btc.InputP2wpkh, btc.InputP2shP2wpkh} {
+		ctx, cli := newBtcTestCtx(t, ty, 300000, 200000)
+		for i := 0; i < 3; i++ {
+			if _, err := sendBtcCrossAt(ctx, config.ETH_CHAIN_ID, ctx.BtcInvoker.Signer, testEthAddr, 100000,
+				5, false); err != nil {
+				t.Fatalf("%s: No.%d send failed: %v", ty, i, err)
+			}
+		}
+		if n, _ := cli.GetMempoolInfo(); n != 3 {
+			t.Fatalf("%s: %d txs in mempool, expected 3", ty, n)
+		}
+		if _, err := sendBtcCrossAt(ctx, config.ETH_CHAIN_ID, ctx.BtcInvoker.Signer, testEthAddr, 300000,
+			5, false); err == nil {
+			t.Fatalf("%s: send should fail for not enough utxos", ty)
+		}
+	}
+}
+
+func TestSendBtcCrossRaiseFee(t *testing.T) {
+	ctx, cli := newBtcTestCtx(t, btc.InputP2wpkh, 300000)
+	// zero fee rate is raised after rejected by node
+	k, err := sendBtcCrossAt(ctx, config.ETH_CHAIN_ID, ctx.BtcInvoker.Signer, testEthAddr, 100000, 0, false)
+	if err != nil {
+		t.Fatalf("fee rate should be raised: %v", err)
+	}
+	raw, _ := hex.DecodeString(k)
+	if _, err = cli.GetTx(hex.EncodeToString(ToArrayReverse(raw))); err != nil {
+		t.Fatalf("tx %s not sent: %v", k, err)
+	}
+}
+
+func TestBumpBtcCross(t *testing.T) {
+	ctx, cli := newBtcTestCtx(t, btc.InputP2shP2wpkh, 300000)
+	status := ctx.Status.AddCase(0)
+	k, err := sendBtcCrossAt(ctx, config.ETH_CHAIN_ID, ctx.BtcInvoker.Signer, testEthAddr, 100000, 1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status.AddTx(k, &testframework.TxInfo{Ty: "BtcToEth", StartTime: time.Now()})
+
+	nk, err := BumpBtcCross(ctx, status, k, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Has(k) || !status.Has(nk) {
+		t.Fatalf("status should track %s instead of %s", nk, k)
+	}
+	mempool := cli.Mempool()
+	raw, _ := hex.DecodeString(nk)
+	if len(mempool) != 1 || mempool[0].TxHash().String() != hex.EncodeToString(ToArrayReverse(raw)) {
+		t.Fatal("only the replacement should be in mempool")
+	}
+	// change of the replacement is spendable
+	if _, err = sendBtcCrossAt(ctx, config.ETH_CHAIN_ID, ctx.BtcInvoker.Signer, testEthAddr, 100000, 1,
+		false); err != nil {
+		t.Fatalf("failed to spend change of replacement: %v", err)
+	}
+}
+
+func TestWaitUntilCleanIn(t *testing.T) {
+	status := testframework.NewCaseStatus(1)
+	status.AddTx("pending", &testframework.TxInfo{Ty: "OntToEth", StartTime: time.Now()})
+	if err := WaitUntilCleanIn(status, time.Second); err == nil {
+		t.Fatal("pending tx should fail the wait")
+	}
+	go func() {
+		time.Sleep(time.Second)
+		status.Del("pending")
+	}()
+	if err := WaitUntilCleanIn(status, 5*time.Second); err != nil {
+		t.Fatalf("finished tx should end the wait: %v", err)
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"github.com/btcsuite/btcd/wire"
+	"github.com/polynetwork/poly-io-test/chains/btc"
 	"github.com/polynetwork/poly-io-test/config"
 	"github.com/polynetwork/poly-io-test/log"
 	"io/ioutil"
@@ -146,18 +147,7 @@ func parseEthChainBlock(ctx *TestFrameworkContext, height uint32) error {
 			continue
 		}
 
-		var ethTxIdByte []byte
-		indexInt := big.NewInt(0)
-		indexInt.SetBytes(event.Txid)
-		for i := len(indexInt.Bytes()); i < 32; i++ {
-			ethTxIdByte = append(ethTxIdByte, 0)
-		}
-		ethTxIdByte = append(ethTxIdByte, indexInt.Bytes()...)
-		ethTxIdStr := hex.EncodeToString(ethTxIdByte)
-		log.Infof("send cross chain tx on eth, tx hash: %s, tx id: %s", ethTxHash, ethTxIdStr)
-		caseStatus := ctx.Status.GetCaseStatus(idx)
-		caseStatus.AddTx(ethTxIdStr, &TxInfo{ethTxHash, time.Now()})
-		caseStatus.Del(ethTxHash)
+		trackEthLock(ctx, idx, ethTxHash, event.Txid)
 	}
 
 	for _, event := range unlockevents {
@@ -173,6 +163,23 @@ func parseEthChainBlock(ctx *TestFrameworkContext, height uint32) error {
 	return nil
 }
 
+// trackEthLock tracks the lock tx ethTxHash of case idx by its cross chain tx id on poly
+func trackEthLock(ctx *TestFrameworkContext, idx int, ethTxHash string, txId []byte) {
+	var ethTxIdByte []byte
+	indexInt := big.NewInt(0)
+	indexInt.SetBytes(txId)
+	for i := len(indexInt.Bytes()); i < 32; i++ {
+		ethTxIdByte = append(ethTxIdByte, 0)
+	}
+	ethTxIdByte = append(ethTxIdByte, indexInt.Bytes()...)
+	ethTxIdStr := hex.EncodeToString(ethTxIdByte)
+	log.Infof("send cross chain tx on eth, tx hash: %s, tx id: %s", ethTxHash, ethTxIdStr)
+	caseStatus := ctx.Status.GetCaseStatus(idx)
+	caseStatus.AddTx(ethTxIdStr, &TxInfo{ethTxHash, time.Now()})
+	caseStatus.MoveBtcUnlock(ethTxHash, ethTxIdStr)
+	caseStatus.Del(ethTxHash)
+}
+
 func MonitorRChain(ctx *TestFrameworkContext) {
 	currentHeight, err := ctx.RcSdk.GetCurrentBlockHeight()
 	if err != nil {
@@ -225,12 +232,7 @@ func parseRelayChainBlock(ctx *TestFrameworkContext, height uint32) error {
 				if ok, idx := ctx.Status.IsTxPending(txHash); ok {
 					log.Infof("receive cross chain tx on relay chain, tx hash: %s, raw tx hash: %s", event.TxHash, txHash)
 					raw, _ := hex.DecodeString(states[3].(string))
-					mtx := wire.NewMsgTx(wire.TxVersion)
-					_ = mtx.BtcDecode(bytes.NewBuffer(raw), wire.ProtocolVersion, wire.LatestEncoding)
-					txid := mtx.TxHash()
-					caseStatus := ctx.Status.GetCaseStatus(idx)
-					caseStatus.AddTx(txid.String(), &TxInfo{"RCToBtc", time.Now()})
-					caseStatus.Del(txHash)
+					trackBtcTxToRelay(ctx, idx, txHash, raw)
 				}
 			}
 		}
@@ -238,27 +240,71 @@ func parseRelayChainBlock(ctx *TestFrameworkContext, height uint32) error {
 	return nil
 }
 
+// trackBtcTxToRelay tracks the cross chain tx txHash of case idx by the signed tx raw to btc
+func trackBtcTxToRelay(ctx *TestFrameworkContext, idx int, txHash string, raw []byte) {
+	mtx := wire.NewMsgTx(wire.TxVersion)
+	_ = mtx.BtcDecode(bytes.NewBuffer(raw), wire.ProtocolVersion, wire.LatestEncoding)
+	txid := mtx.TxHash()
+	caseStatus := ctx.Status.GetCaseStatus(idx)
+	caseStatus.AddTx(txid.String(), &TxInfo{"RCToBtc", time.Now()})
+	caseStatus.MoveBtcUnlock(txHash, txid.String())
+	caseStatus.Del(txHash)
+}
+
 func MonitorBtc(ctx *TestFrameworkContext) {
 	updateTicker := time.NewTicker(time.Second * 1)
 	for {
 		select {
 		case <-updateTicker.C:
-			for idx, v := range ctx.Status.caseMap {
-				for a, b := range v.GetMapCopy() {
-					if b.Ty != "RCToBtc" {
-						continue
-					}
-					raw, err := ctx.BtcInvoker.BtcCli.GetRawTransaction(a)
-					if raw != "" && err == nil {
-						log.Infof("receive cross chain tx on btc chain, tx hash: %s, info: %s", a, b)
-						ctx.Status.DelWithIndex(a, idx)
-					}
-				}
+			checkBtc(ctx)
+		}
+	}
+}
+
+// checkBtc removes the txs to btc with BtcUnlockConfs confirmations, and fails the case if the
+// tx does not unlock from the multisig as expected
+func checkBtc(ctx *TestFrameworkContext) {
+	confs := config.DefConfig.BtcUnlockConfs
+	if confs <= 0 {
+		confs = 1
+	}
+	for idx, v := range ctx.Status.GetCaseMap() {
+		for a, b := range v.GetMapCopy() {
+			if b.Ty != "RCToBtc" {
+				continue
+			}
+			n, err := ctx.BtcInvoker.BtcCli.GetTxConfs(a)
+			if err != nil || n < confs {
+				continue
+			}
+			if err = verifyBtcUnlock(ctx, v, a); err != nil {
+				log.Errorf("wrong cross chain tx on btc chain, tx hash: %s, err: %v", a, err)
+				v.Fail(fmt.Errorf("unlock tx %s: %v", a, err))
+			} else {
+				log.Infof("receive cross chain tx on btc chain, tx hash: %s, confirmations: %d, info: %s", a, n, b)
 			}
+			ctx.Status.DelWithIndex(a, idx)
 		}
 	}
 }
 
+func verifyBtcUnlock(ctx *TestFrameworkContext, cs *CaseStatus, txid string) error {
+	mtx, err := ctx.BtcInvoker.BtcCli.GetTx(txid)
+	if err != nil {
+		return err
+	}
+	redeem, err := hex.DecodeString(config.DefConfig.BtcRedeem)
+	if err != nil || len(redeem) == 0 {
+		return fmt.Errorf("wrong BtcRedeem %s: %v", config.DefConfig.BtcRedeem, err)
+	}
+	to, amount := "", int64(0)
+	if u := cs.TakeBtcUnlock(txid); u != nil {
+		to, amount = u.To, u.Amount
+	}
+	return btc.VerifyUnlock(ctx.BtcInvoker.BtcCli, mtx, redeem, to, amount, config.DefConfig.BtcUnlockMaxFee,
+		config.BtcNet)
+}
+
 func MonitorCosmos(ctx *TestFrameworkContext) {
 	updateTicker := time.NewTicker(time.Second * 1)
 	for {
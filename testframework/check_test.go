@@ -0,0 +1,202 @@
+/*
+* Copyright (C) 2020 The poly network Authors
+* This file is part of The poly network library.
+*
+* The poly network is free software: you can redistribute it and/or modify
+* it under the terms of the GNU Lesser General Public License as published by
+* the Free Software Foundation, either version 3 of the License, or
+* (at your option) any later version.
+*
+* The poly network is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU Lesser General Public License for more details.
+* You should have received a copy of the GNU Lesser General Public License
+* along with The poly network . If not, see <http://www.gnu.org/licenses/>.
+ */
+package testframework
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/polynetwork/poly-io-test/chains/btc"
+	"github.com/polynetwork/poly-io-test/config"
+	"testing"
+	"time"
+)
+
+type unlockTest struct {
+	t        *testing.T
+	cli      *btc.FakeCli
+	vendor   *btc.Vendor
+	multisig btcutil.Address
+	user     btcutil.Address
+}
+
+func newUnlockTest(t *testing.T) *unlockTest {
+	btc.KeepConfig(t)
+	config.BtcNet = &chaincfg.RegressionNetParams
+	vendor, _ := btc.TestVendor(t, config.BtcNet)
+	config.DefConfig.BtcRedeem = hex.EncodeToString(vendor.Redeem)
+	user, _ := btcutil.NewAddressPubKeyHash(btcutil.Hash160([]byte("user")), config.BtcNet)
+	return &unlockTest{
+		t:        t,
+		cli:      btc.NewFakeCli(config.BtcNet),
+		vendor:   vendor,
+		multisig: vendor.P2shAddr,
+		user:     user,
+	}
+}
+
+// unlock sends the tx spending a new utxo of multisig to outputs, and returns its txid
+func (ut *unlockTest) unlock(outs map[btcutil.Address]int64) string {
+	fund, err := ut.cli.Fund(ut.multisig.EncodeAddress(), 100000)
+	if err != nil {
+		ut.t.Fatal(err)
+	}
+	fundHash := fund.TxHash()
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&fundHash, 0), nil, nil))
+	for addr, val := range outs {
+		pkScript, _ := txscript.PayToAddrScript(addr)
+		tx.AddTxOut(wire.NewTxOut(val, pkScript))
+	}
+	sig, err := txscript.SignTxOutput(config.BtcNet, tx, 0, fund.TxOut[0].PkScript, txscript.SigHashAll,
+		txscript.KeyClosure(func(addr btcutil.Address) (*btcec.PrivateKey, bool, error) {
+			for i, pk := range ut.vendor.AddressSet {
+				if pk.EncodeAddress() == addr.EncodeAddress() {
+					return ut.vendor.PrivateKeys[i].PrivKey, true, nil
+				}
+			}
+			return nil, false, fmt.Errorf("no key of %s", addr.EncodeAddress())
+		}), txscript.ScriptClosure(func(btcutil.Address) ([]byte, error) {
+			return ut.vendor.Redeem, nil
+		}), nil)
+	if err != nil {
+		ut.t.Fatal(err)
+	}
+	tx.TxIn[0].SignatureScript = sig
+	var buf bytes.Buffer
+	_ = tx.BtcEncode(&buf, wire.ProtocolVersion, wire.LatestEncoding)
+	txid, err := ut.cli.SendRawTx(hex.EncodeToString(buf.Bytes()))
+	if err != nil {
+		ut.t.Fatal(err)
+	}
+	return txid
+}
+
+func TestCheckBtc(t *testing.T) {
+	ut := newUnlockTest(t)
+	other, _ := btcutil.NewAddressPubKeyHash(btcutil.Hash160([]byte("other")), config.BtcNet)
+	good := ut.unlock(map[btcutil.Address]int64{ut.user: 29000, ut.multisig: 70000})
+	wrongAddr := ut.unlock(map[btcutil.Address]int64{other: 29000, ut.multisig: 70000})
+	wrongAmount := ut.unlock(map[btcutil.Address]int64{ut.user: 50000, ut.multisig: 49000})
+	noChange := ut.unlock(map[btcutil.Address]int64{ut.user: 29000, other: 70000})
+
+	ctx := NewTestFrameworkContext(nil, nil, nil, nil, &btc.BtcInvoker{BtcCli: ut.cli}, nil, nil)
+	cases := make([]*CaseStatus, 0)
+	for i, txid := range []string{good, wrongAddr, wrongAmount, noChange} {
+		cs := ctx.Status.AddCase(i)
+		cs.AddTx(txid, &TxInfo{Ty: "RCToBtc", StartTime: time.Now()})
+		cs.ExpectBtcUnlock(txid, &BtcUnlock{To: ut.user.EncodeAddress(), Amount: 30000})
+		cases = append(cases, cs)
+	}
+	missing := chainhash.HashH([]byte("missing")).String()
+	cases[0].AddTx(missing, &TxInfo{Ty: "RCToBtc", StartTime: time.Now()})
+	toEth := chainhash.HashH([]byte("to eth")).String()
+	cases[0].AddTx(toEth, &TxInfo{Ty: "BtcToEth", StartTime: time.Now()})
+
+	// all unlock txs are in mempool but not confirmed
+	checkBtc(ctx)
+	for i, cs := range cases {
+		if cs.Len() == 0 || cs.Err() != nil {
+			t.Fatalf("case %d should wait for confirmation: %v", i, cs.Err())
+		}
+	}
+
+	if _, err := ut.cli.GenerateToAddr(1, ut.user.EncodeAddress()); err != nil {
+		t.Fatal(err)
+	}
+	checkBtc(ctx)
+	if cases[0].Has(good) || cases[0].Err() != nil {
+		t.Fatalf("good unlock should be removed without failure: %v", cases[0].Err())
+	}
+	if !cases[0].Has(missing) {
+		t.Fatal("tx not found on btc should be kept")
+	}
+	if !cases[0].Has(toEth) {
+		t.Fatal("BtcToEth tx should be left to its own monitor")
+	}
+	for i, cs := range cases[1:] {
+		if cs.Len() != 0 || cs.Err() == nil {
+			t.Fatalf("wrong unlock %d should be removed with failure", i+1)
+		}
+	}
+}
+
+func TestTrackBtceToBtc(t *testing.T) {
+	ut := newUnlockTest(t)
+	ctx := NewTestFrameworkContext(nil, nil, nil, nil, &btc.BtcInvoker{BtcCli: ut.cli}, nil, nil)
+	cs := ctx.Status.AddCase(1)
+	ethTxHash := chainhash.HashH([]byte("eth lock")).String()
+	cs.AddTx(ethTxHash, &TxInfo{Ty: "BtceToBtc", StartTime: time.Now()})
+	cs.ExpectBtcUnlock(ethTxHash, &BtcUnlock{To: ut.user.EncodeAddress(), Amount: 30000})
+
+	trackEthLock(ctx, 1, ethTxHash, []byte{1})
+	txId := hex.EncodeToString(append(make([]byte, 31), 1))
+	if !cs.Has(txId) || cs.Has(ethTxHash) || cs.GetBtcUnlock(txId) == nil {
+		t.Fatalf("eth lock should be tracked with its unlock by tx id %s", txId)
+	}
+
+	txid := ut.unlock(map[btcutil.Address]int64{ut.user: 29000, ut.multisig: 70000})
+	raw, err := ut.cli.GetRawTransaction(txid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawBytes, _ := hex.DecodeString(raw)
+	trackBtcTxToRelay(ctx, 1, txId, rawBytes)
+	if !cs.Has(txid) || cs.Has(txId) || cs.GetBtcUnlock(txid) == nil {
+		t.Fatalf("tx to relay should be tracked with its unlock by btc txid %s", txid)
+	}
+
+	if _, err := ut.cli.GenerateToAddr(1, ut.user.EncodeAddress()); err != nil {
+		t.Fatal(err)
+	}
+	checkBtc(ctx)
+	if cs.Len() != 0 || cs.Err() != nil || cs.PendingBtcUnlocks() != 0 {
+		t.Fatalf("unlock should be checked without failure: %v", cs.Err())
+	}
+}
+
+func TestResolveCases(t *testing.T) {
+	ut := newUnlockTest(t)
+	fw := NewTestFramework()
+	ctx := NewTestFrameworkContext(fw, []TestCase{
+		func(*TestFrameworkContext, *CaseStatus) bool { return true },
+		func(*TestFrameworkContext, *CaseStatus) bool { return true },
+		func(*TestFrameworkContext, *CaseStatus) bool { return true },
+	}, nil, nil,
+		&btc.BtcInvoker{BtcCli: ut.cli}, nil, nil)
+	for i, c := range ctx.Cases {
+		ctx.Status.AddCase(i + 1)
+		fw.testCaseRes[fw.getTestCaseId(c)] = true
+	}
+	ctx.Status.GetCaseStatus(2).Fail(fmt.Errorf("wrong unlock"))
+	ctx.Status.GetCaseStatus(3).ExpectBtcUnlock("txid", &BtcUnlock{To: ut.user.EncodeAddress(), Amount: 1})
+
+	defer func(timeout uint64) { config.DefConfig.BtcUnlockTimeout = timeout }(config.DefConfig.BtcUnlockTimeout)
+	config.DefConfig.BtcUnlockTimeout = 1
+	fw.resolveCases(ctx)
+	for i, want := range []bool{true, false, false} {
+		if got := fw.testCaseRes[fw.getTestCaseId(ctx.Cases[i])]; got != want {
+			t.Fatalf("case %d: result %v, want %v", i+1, got, want)
+		}
+	}
+}
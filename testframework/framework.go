@@ -136,6 +136,33 @@ func (this *TestFramework) runTestList(testCaseList []TestCase, loopNumber int)
 	}
 
 	wg.Wait()
+	if this.btcInvoker != nil {
+		this.resolveCases(ctx)
+	}
+}
+
+// resolveCases waits for the unlocks to btc that monitors check after cases return,
+// and fails the cases whose unlocks are wrong or not confirmed in BtcUnlockTimeout
+func (this *TestFramework) resolveCases(ctx *TestFrameworkContext) {
+	timeout := config.DefConfig.BtcUnlockTimeout
+	if timeout == 0 {
+		timeout = 600
+	}
+	deadline := time.Now().Add(time.Duration(timeout) * time.Second)
+	for time.Now().Before(deadline) && ctx.Status.PendingBtcUnlocks() > 0 {
+		time.Sleep(time.Second)
+	}
+	for idx, cs := range ctx.Status.GetCaseMap() {
+		err := cs.Err()
+		if n := cs.PendingBtcUnlocks(); err == nil && n > 0 {
+			err = fmt.Errorf("%d unlocks to btc not confirmed in %d seconds", n, timeout)
+		}
+		id := this.getTestCaseId(ctx.Cases[idx-1])
+		if err != nil && this.testCaseRes[id] {
+			log.Errorf("case %s failed on check: %v", this.getTestCaseName(ctx.Cases[idx-1]), err)
+			this.testCaseRes[id] = false
+		}
+	}
 }
 
 //Run a single test case
@@ -144,6 +171,10 @@ func (this *TestFramework) runTest(index int, ctx *TestFrameworkContext, testCas
 		this.onBeforeTestCaseStart(index, loopNum, testCase)
 		status := ctx.Status.AddCase(index)
 		ok := testCase(ctx, status)
+		if err := status.Err(); ok && err != nil {
+			log.Errorf("case %s failed on check: %v", this.getTestCaseName(testCase), err)
+			ok = false
+		}
 		this.onAfterTestCaseFinish(index, loopNum, testCase, ok)
 		this.testCaseRes[this.getTestCaseId(testCase)] = ok
 		if !ok {
@@ -23,6 +23,7 @@ import (
 	"github.com/polynetwork/poly-io-test/chains/cosmos"
 	"github.com/polynetwork/poly-io-test/chains/eth"
 	"github.com/polynetwork/poly-io-test/chains/ont"
+	"strings"
 	"sync"
 	"time"
 )
@@ -121,6 +122,17 @@ func (status *CtxStatus) GetCaseStatus(idx int) *CaseStatus {
 	return status.caseMap[idx]
 }
 
+// PendingBtcUnlocks returns the number of unlocks to btc not checked yet in all cases
+func (status *CtxStatus) PendingBtcUnlocks() int {
+	status.lock.Lock()
+	defer status.lock.Unlock()
+	n := 0
+	for _, cs := range status.caseMap {
+		n += cs.PendingBtcUnlocks()
+	}
+	return n
+}
+
 func (status *CtxStatus) Info() map[int]string {
 	status.lock.Lock()
 	defer status.lock.Unlock()
@@ -147,19 +159,29 @@ type TxInfo struct {
 	StartTime time.Time
 }
 
+// BtcUnlock is what the unlock tx on bitcoin should pay for a tx locking btcx
+type BtcUnlock struct {
+	To     string // address of user
+	Amount int64  // satoshi locked
+}
+
 type CaseStatus struct {
 	lock      *sync.Mutex
 	CaseIdx   int
 	txMap     map[string]*TxInfo
 	isSuccess bool
+
+	btcUnlocks map[string]*BtcUnlock
+	failures   []string
 }
 
 func NewCaseStatus(idx int) *CaseStatus {
 	return &CaseStatus{
-		lock:      &sync.Mutex{},
-		CaseIdx:   idx,
-		txMap:     make(map[string]*TxInfo),
-		isSuccess: false,
+		lock:       &sync.Mutex{},
+		CaseIdx:    idx,
+		txMap:      make(map[string]*TxInfo),
+		isSuccess:  false,
+		btcUnlocks: make(map[string]*BtcUnlock),
 	}
 }
 
@@ -191,11 +213,88 @@ func (cs *CaseStatus) AddTx(k string, v *TxInfo) {
 	cs.txMap[k] = v
 }
 
+// Replace tracks tx nk instead of k which is replaced on chain, and returns false if k not tracked
+func (cs *CaseStatus) Replace(k, nk string) bool {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+	v, ok := cs.txMap[k]
+	if !ok {
+		return false
+	}
+	delete(cs.txMap, k)
+	cs.txMap[nk] = v
+	cs.moveBtcUnlock(k, nk)
+	return true
+}
+
+// ExpectBtcUnlock records what the unlock tx for tx k should pay, checked by MonitorBtc
+func (cs *CaseStatus) ExpectBtcUnlock(k string, u *BtcUnlock) {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+	cs.btcUnlocks[k] = u
+}
+
+func (cs *CaseStatus) GetBtcUnlock(k string) *BtcUnlock {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+	return cs.btcUnlocks[k]
+}
+
+// TakeBtcUnlock returns the unlock expected for tx k and stops expecting it
+func (cs *CaseStatus) TakeBtcUnlock(k string) *BtcUnlock {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+	u := cs.btcUnlocks[k]
+	delete(cs.btcUnlocks, k)
+	return u
+}
+
+// PendingBtcUnlocks returns the number of unlocks to btc not checked yet
+func (cs *CaseStatus) PendingBtcUnlocks() int {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+	return len(cs.btcUnlocks)
+}
+
+// MoveBtcUnlock moves the unlock expected for tx k to its next tx nk
+func (cs *CaseStatus) MoveBtcUnlock(k, nk string) {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+	cs.moveBtcUnlock(k, nk)
+}
+
+func (cs *CaseStatus) moveBtcUnlock(k, nk string) {
+	if u, ok := cs.btcUnlocks[k]; ok {
+		delete(cs.btcUnlocks, k)
+		cs.btcUnlocks[nk] = u
+	}
+}
+
+// Fail records a failure of the case found by monitors
+func (cs *CaseStatus) Fail(err error) {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+	cs.failures = append(cs.failures, err.Error())
+}
+
+// Err returns the failures recorded by Fail, nil if none
+func (cs *CaseStatus) Err() error {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+	if len(cs.failures) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s", strings.Join(cs.failures, "; "))
+}
+
 func (cs *CaseStatus) Info() string {
 	cs.lock.Lock()
 	defer cs.lock.Unlock()
 	info := ""
-	if cs.isSuccess {
+	for _, f := range cs.failures {
+		info += fmt.Sprintf("\t[ failed: %s ]\n", f)
+	}
+	if cs.isSuccess && info == "" {
 		info = "success!"
 	} else {
 		for k, v := range cs.txMap {